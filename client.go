@@ -2,19 +2,26 @@ package multitenant
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/resilience"
 	"github.com/victorximenis/multitenant/core/service"
 	"github.com/victorximenis/multitenant/infra/connection"
+	"github.com/victorximenis/multitenant/infra/file"
+	"github.com/victorximenis/multitenant/infra/memory"
 	"github.com/victorximenis/multitenant/infra/mongodb"
 	"github.com/victorximenis/multitenant/infra/postgres"
 	"github.com/victorximenis/multitenant/infra/redis"
+	"github.com/victorximenis/multitenant/infra/tiered"
 	"github.com/victorximenis/multitenant/interfaces/cli"
 	httpMiddleware "github.com/victorximenis/multitenant/interfaces/http"
 )
@@ -22,9 +29,40 @@ import (
 // MultitenantClient is the main client for the multitenant library
 type MultitenantClient struct {
 	config            *Config
+	repository        core.TenantRepository
+	cache             core.TenantCache
 	tenantService     core.TenantService
+	provisioner       core.TenantProvisioner
+	templates         core.TemplateRepository
 	connectionManager *connection.ConnectionManager
 	tenantResolver    *cli.TenantResolver
+	logger            *slog.Logger
+	// tieredCache is set when the in-process L1 cache is enabled
+	tieredCache *tiered.Cache
+	// changeBus is set when changes are published over Redis pub/sub
+	changeBus *redis.ChangeBus
+
+	// stopWatching cancels the change watcher and watchDone is closed once it returned
+	stopWatching context.CancelFunc
+	watchDone    chan struct{}
+
+	// stopWarmUp cancels the startup warm-up and warmUpDone is closed once it returned
+	stopWarmUp     context.CancelFunc
+	warmUpDone     chan struct{}
+	warmUpMu       sync.Mutex
+	warmUpProgress WarmUpProgress
+}
+
+// changeWatcher delivers tenant changes reported by the registry or published by other
+// instances until ctx is done
+type changeWatcher interface {
+	Run(ctx context.Context, handler func(context.Context, core.TenantChange)) error
+}
+
+// changeFeed pairs a change watcher with the cache invalidation its changes need
+type changeFeed struct {
+	watcher    changeWatcher
+	invalidate func(context.Context, core.TenantChange) error
 }
 
 // NewMultitenantClient creates a new multitenant client
@@ -32,36 +70,44 @@ func NewMultitenantClient(ctx context.Context, config *Config) (*MultitenantClie
 	if err := config.Validate(); err != nil {
 		return nil, err
 	}
+	logger := config.logger()
 
 	// Create repository based on database type
-	var repository core.TenantRepository
-	var err error
-
-	if config.DatabaseType == PostgreSQL {
-		repository, err = postgres.NewTenantRepository(ctx, config.DatabaseDSN)
-	} else {
-		repository, err = mongodb.NewTenantRepository(ctx, config.DatabaseDSN)
-	}
-
+	repository, err := NewTenantRepository(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tenant repository: %w", err)
 	}
 
 	// Create cache
-	cache, err := redis.NewTenantCache(ctx, redis.Config{
-		RedisURL: config.RedisURL,
-		TTL:      config.CacheTTL,
-	})
+	cache, err := newTenantCache(ctx, config)
 	if err != nil {
+		core.CloseResource(ctx, repository)
 		return nil, fmt.Errorf("failed to create tenant cache: %w", err)
 	}
 
+	// Templates live in the same registry when the backend supports them
+	templates, _ := repository.(core.TemplateRepository)
+
+	// Broadcast changes to the other instances over Redis pub/sub
+	var changeBus *redis.ChangeBus
+	var publisher core.ChangePublisher
+	if config.PublishChanges {
+		changeBus, err = redis.NewChangeBus(ctx, redis.ChangeBusConfig{
+			RedisURL:  config.RedisURL,
+			Client:    config.Redis,
+			KeyPrefix: config.CacheKeyPrefix,
+			Logger:    logger,
+		})
+		if err != nil {
+			core.CloseResource(ctx, cache)
+			core.CloseResource(ctx, repository)
+			return nil, fmt.Errorf("failed to create change bus: %w", err)
+		}
+		publisher = changeBus
+	}
+
 	// Create tenant service
-	tenantService := service.NewTenantService(service.Config{
-		Repository: repository,
-		Cache:      cache,
-		CacheTTL:   config.CacheTTL,
-	})
+	tenantService := newTenantService(config, repository, cache, publisher, logger)
 
 	// Create connection manager
 	connectionManager := connection.NewConnectionManager(tenantService, connection.ConnectionConfig{
@@ -70,17 +116,213 @@ func NewMultitenantClient(ctx context.Context, config *Config) (*MultitenantClie
 		MaxIdleTime: 5 * time.Minute,
 		MaxLifetime: 1 * time.Hour,
 		HealthCheck: 1 * time.Minute,
+		Logger:      logger,
 	})
 
 	// Create tenant resolver
 	tenantResolver := cli.NewTenantResolver(tenantService, "TENANT_NAME")
 
-	return &MultitenantClient{
+	client := &MultitenantClient{
 		config:            config,
+		repository:        repository,
+		cache:             cache,
 		tenantService:     tenantService,
+		provisioner:       tenantService,
+		templates:         templates,
 		connectionManager: connectionManager,
 		tenantResolver:    tenantResolver,
-	}, nil
+		changeBus:         changeBus,
+		logger:            core.ComponentLogger(logger, "client"),
+	}
+	client.tieredCache, _ = cache.(*tiered.Cache)
+
+	var feeds []changeFeed
+	if config.WatchChanges {
+		watcher, err := newChangeWatcher(config, repository)
+		if err != nil {
+			if changeBus != nil {
+				changeBus.Close()
+			}
+			core.CloseResource(ctx, cache)
+			core.CloseResource(ctx, repository)
+			return nil, fmt.Errorf("failed to create change watcher: %w", err)
+		}
+		// Registry changes may bypass every TenantService, so shared tiers are cleared too
+		feeds = append(feeds, changeFeed{watcher: watcher, invalidate: tenantService.InvalidateRegistryChange})
+	}
+	if changeBus != nil {
+		feeds = append(feeds, changeFeed{watcher: changeBus, invalidate: tenantService.InvalidateTenant})
+	}
+	if len(feeds) > 0 {
+		client.watch(feeds...)
+	}
+
+	if config.WarmUp {
+		client.warmUp(ctx, tenantService)
+	}
+
+	return client, nil
+}
+
+// newTenantService creates the tenant service, retrying transient registry failures.
+// Cache operations are not retried: they sit on the request path, and a failing cache
+// falls back to the registry at once.
+func newTenantService(
+	config *Config,
+	repository core.TenantRepository,
+	cache core.TenantCache,
+	publisher core.ChangePublisher,
+	logger *slog.Logger,
+) *service.TenantService {
+	templates, _ := repository.(core.TemplateRepository)
+
+	return service.NewTenantService(service.Config{
+		Repository: resilience.NewRepository(repository, resilience.Policy{
+			MaxRetries:  config.MaxRetries,
+			Delay:       config.RetryDelay,
+			RetryWrites: config.RetryWrites,
+		}),
+		Cache:        cache,
+		CacheTTL:     config.CacheTTL,
+		Templates:    templates,
+		NotFoundTTL:  config.NotFoundCacheTTL,
+		Publisher:    publisher,
+		RefreshAhead: config.CacheRefreshAhead,
+		Logger:       logger,
+	})
+}
+
+// newChangeWatcher creates the change feed of the configured registry
+func newChangeWatcher(config *Config, repository core.TenantRepository) (changeWatcher, error) {
+	switch config.DatabaseType {
+	case PostgreSQL:
+		return postgres.NewListener(postgres.ListenerConfig{
+			DSN:     config.DatabaseDSN,
+			Options: config.postgresOptions(),
+		})
+	case MongoDB:
+		repo, ok := repository.(*mongodb.TenantRepository)
+		if !ok {
+			return nil, core.ErrConfigInvalid("WatchChanges", "change streams require the MongoDB tenant repository")
+		}
+		return mongodb.NewWatcher(repo, mongodb.WatcherConfig{Name: config.watcherName()})
+	default:
+		return nil, core.ErrConfigInvalid("WatchChanges",
+			fmt.Sprintf("watching changes is not supported for database type: %s", config.DatabaseType))
+	}
+}
+
+// watch runs the feeds in the background, invalidating cached tenants and refreshing
+// their pools for every change until the client is closed
+func (c *MultitenantClient) watch(feeds ...changeFeed) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.stopWatching = cancel
+	c.watchDone = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func(feed changeFeed) {
+			defer wg.Done()
+			feed.watcher.Run(ctx, c.applyChange(feed.invalidate))
+		}(feed)
+	}
+
+	go func() {
+		wg.Wait()
+		close(c.watchDone)
+	}()
+}
+
+// applyChange returns the handler invalidating the cache entries and pools of a change
+func (c *MultitenantClient) applyChange(invalidate func(context.Context, core.TenantChange) error) func(context.Context, core.TenantChange) {
+	return func(ctx context.Context, change core.TenantChange) {
+		// Invalidation is best effort; entries missed here still expire with the cache TTL
+		if err := invalidate(ctx, change); err != nil {
+			c.logger.WarnContext(ctx, "failed to invalidate changed tenant",
+				core.LogKeyTenantName, change.Name, core.LogKeyError, err)
+		}
+
+		if change.Op == core.ChangeResync {
+			c.connectionManager.RefreshAll(ctx)
+			return
+		}
+		for _, name := range []string{change.Name, change.OldName} {
+			if name != "" {
+				c.connectionManager.RefreshTenant(ctx, name)
+			}
+		}
+	}
+}
+
+// NewTenantRepository creates the tenant repository for the configured database type
+func NewTenantRepository(ctx context.Context, config *Config) (core.TenantRepository, error) {
+	switch config.DatabaseType {
+	case PostgreSQL:
+		return postgres.NewTenantRepositoryWithOptions(ctx, config.DatabaseDSN, config.postgresOptions())
+	case MongoDB:
+		return mongodb.NewTenantRepositoryWithOptions(ctx, config.DatabaseDSN, config.mongoOptions())
+	case Memory:
+		return memory.NewTenantRepository(), nil
+	case File:
+		fileConfig, err := file.ParseDSN(config.DatabaseDSN)
+		if err != nil {
+			return nil, err
+		}
+		fileConfig.Logger = config.logger()
+		return file.NewTenantRepository(ctx, fileConfig)
+	default:
+		return nil, core.ErrConfigInvalid("DatabaseType",
+			fmt.Sprintf("unsupported database type: %s", config.DatabaseType))
+	}
+}
+
+// newTenantCache creates the cache selected by the cache type: Redis, optionally behind
+// an in-process L1 cache, an in-memory cache, or no cache at all
+func newTenantCache(ctx context.Context, config *Config) (core.TenantCache, error) {
+	switch config.cacheType() {
+	case MemoryCache:
+		return memory.NewTenantCache(memory.CacheConfig{
+			TTL:        config.CacheTTL,
+			MaxEntries: config.memoryCacheSize(),
+			StaleTTL:   config.StaleCacheTTL,
+		}), nil
+	case NoCache:
+		return memory.NewNoopCache(), nil
+	}
+
+	codec := redis.JSONCodec
+	if config.CacheCodec != "" {
+		selected, err := redis.CodecByName(config.CacheCodec)
+		if err != nil {
+			return nil, err
+		}
+		codec = selected
+	}
+
+	cache, err := redis.NewTenantCache(ctx, redis.Config{
+		RedisURL:  config.RedisURL,
+		Client:    config.Redis,
+		TTL:       config.CacheTTL,
+		StaleTTL:  config.StaleCacheTTL,
+		Codec:     codec,
+		Compress:  config.CacheCompression,
+		KeyPrefix: config.CacheKeyPrefix,
+		Logger:    config.logger(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config.L1CacheSize == 0 {
+		return cache, nil
+	}
+
+	return tiered.NewCache(tiered.Config{
+		Remote:      cache,
+		MaxEntries:  config.L1CacheSize,
+		TTL:         config.L1CacheTTL,
+		NegativeTTL: config.NotFoundCacheTTL,
+	})
 }
 
 // GetTenantService returns the tenant service
@@ -88,11 +330,40 @@ func (c *MultitenantClient) GetTenantService() core.TenantService {
 	return c.tenantService
 }
 
+// GetTenantProvisioner returns the service used to create tenants from templates or clones
+func (c *MultitenantClient) GetTenantProvisioner() core.TenantProvisioner {
+	return c.provisioner
+}
+
+// GetTemplateRepository returns the tenant template repository, or nil if the backend has none
+func (c *MultitenantClient) GetTemplateRepository() core.TemplateRepository {
+	return c.templates
+}
+
 // GetConnectionManager returns the connection manager
 func (c *MultitenantClient) GetConnectionManager() *connection.ConnectionManager {
 	return c.connectionManager
 }
 
+// GetCacheStats returns the hit and miss counters of both cache tiers, or false when the
+// L1 cache is disabled
+func (c *MultitenantClient) GetCacheStats() (tiered.Stats, bool) {
+	if c.tieredCache == nil {
+		return tiered.Stats{}, false
+	}
+	return c.tieredCache.Stats(), true
+}
+
+// GetLookupStats returns how many tenant lookups by name were cache hits, cached
+// "not found" results and cache misses
+func (c *MultitenantClient) GetLookupStats() service.LookupStats {
+	stats, ok := c.tenantService.(interface{ LookupStats() service.LookupStats })
+	if !ok {
+		return service.LookupStats{}
+	}
+	return stats.LookupStats()
+}
+
 // GetTenantResolver returns the tenant resolver
 func (c *MultitenantClient) GetTenantResolver() *cli.TenantResolver {
 	return c.tenantResolver
@@ -104,6 +375,7 @@ func (c *MultitenantClient) GinMiddleware() gin.HandlerFunc {
 		TenantService:    c.tenantService,
 		HeaderName:       c.config.HeaderName,
 		IgnoredEndpoints: c.config.IgnoredEndpoints,
+		Logger:           c.config.logger(),
 	})
 }
 
@@ -113,6 +385,7 @@ func (c *MultitenantClient) FiberMiddleware() fiber.Handler {
 		TenantService:    c.tenantService,
 		HeaderName:       c.config.HeaderName,
 		IgnoredEndpoints: c.config.IgnoredEndpoints,
+		Logger:           c.config.logger(),
 	})
 }
 
@@ -122,11 +395,31 @@ func (c *MultitenantClient) ChiMiddleware() func(http.Handler) http.Handler {
 		TenantService:    c.tenantService,
 		HeaderName:       c.config.HeaderName,
 		IgnoredEndpoints: c.config.IgnoredEndpoints,
+		Logger:           c.config.logger(),
 	})
 }
 
-// Close closes all connections and resources
+// Close stops the background work and closes the tenant connections, the cache and the
+// repository
 func (c *MultitenantClient) Close(ctx context.Context) error {
+	if c.stopWarmUp != nil {
+		c.stopWarmUp()
+		<-c.warmUpDone
+	}
+
+	if c.stopWatching != nil {
+		c.stopWatching()
+		<-c.watchDone
+	}
+
+	if c.changeBus != nil {
+		c.changeBus.Close()
+	}
+
 	c.connectionManager.CloseAll(ctx)
-	return nil
+
+	return errors.Join(
+		core.CloseResource(ctx, c.cache),
+		core.CloseResource(ctx, c.repository),
+	)
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/victorximenis/multitenant"
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/reconcile"
+)
+
+// runApply reconciles the registry with a desired-state file
+func runApply(ctx context.Context, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+
+	file := flags.String("f", "", "path to the desired-state file (.yaml, .yml or .json)")
+	prune := flags.Bool("prune", false, "delete tenants that are missing from the desired state")
+	dryRun := flags.Bool("dry-run", false, "print the plan without applying it")
+	databaseType := flags.String("database-type", envOrDefault("MULTITENANT_DATABASE_TYPE", string(multitenant.PostgreSQL)),
+		"registry database type (postgres or mongodb)")
+	databaseDSN := flags.String("database-dsn", os.Getenv("MULTITENANT_DATABASE_DSN"), "registry database DSN")
+	var registry registryFlags
+	registry.addPostgresFlags(flags)
+	registry.addMongoFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return errors.New("the -f flag is required")
+	}
+
+	if *databaseDSN == "" {
+		return core.ErrConfigInvalid("DatabaseDSN", "database DSN is required (use --database-dsn or MULTITENANT_DATABASE_DSN)")
+	}
+
+	key, err := encryptionKeyFromEnv()
+	if err != nil {
+		return err
+	}
+
+	repo, err := multitenant.NewTenantRepository(ctx, &multitenant.Config{
+		DatabaseType: multitenant.DatabaseType(*databaseType),
+		DatabaseDSN:  *databaseDSN,
+		Postgres:     registry.postgresOptions(),
+		Mongo:        registry.mongoOptions(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tenant repository: %w", err)
+	}
+	defer core.CloseResource(context.Background(), repo)
+
+	_, err = reconcile.ReconcileFile(ctx, repo, *file, reconcile.Options{
+		Prune:         *prune,
+		DryRun:        *dryRun,
+		EncryptionKey: key,
+	}, stdout)
+	return err
+}
+
+// encryptionKeyFromEnv reads the base64 encoded DSN encryption key, if any
+func encryptionKeyFromEnv() ([]byte, error) {
+	encoded := os.Getenv("MULTITENANT_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, core.ErrConfigInvalid("MULTITENANT_ENCRYPTION_KEY", "encryption key must be base64 encoded").WithCause(err)
+	}
+	return key, nil
+}
+
+func envOrDefault(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func TestRunApply_UsesRegistryOptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("PostgreSQL options from the environment", func(t *testing.T) {
+		t.Setenv("MULTITENANT_POSTGRES_SCHEMA", "bad-schema")
+
+		// The options are validated before connecting, so an invalid schema proves they
+		// reached the repository
+		err := runApply(ctx, []string{"-f", "tenants.yaml", "--database-dsn", "postgres://localhost:1/registry"}, io.Discard)
+		require.Error(t, err)
+		assert.True(t, core.IsErrorCode(err, core.ErrCodeConfigInvalid))
+		assert.Contains(t, err.Error(), "bad-schema")
+	})
+
+	t.Run("PostgreSQL options from flags", func(t *testing.T) {
+		err := runApply(ctx, []string{"-f", "tenants.yaml", "--database-dsn", "postgres://localhost:1/registry",
+			"--table-prefix", "bad-prefix"}, io.Discard)
+		require.Error(t, err)
+		assert.True(t, core.IsErrorCode(err, core.ErrCodeConfigInvalid))
+		assert.Contains(t, err.Error(), "bad-prefix")
+	})
+
+	t.Run("MongoDB options", func(t *testing.T) {
+		t.Setenv("MULTITENANT_MONGO_DATABASE", "bad.database")
+
+		err := runApply(ctx, []string{"-f", "tenants.yaml", "--database-type", "mongodb",
+			"--database-dsn", "mongodb://localhost:1"}, io.Discard)
+		require.Error(t, err)
+		assert.True(t, core.IsErrorCode(err, core.ErrCodeConfigInvalid))
+		assert.Contains(t, err.Error(), "bad.database")
+	})
+}
+
+func TestRegistryFlags(t *testing.T) {
+	t.Setenv("MULTITENANT_POSTGRES_SCHEMA", "registry")
+	t.Setenv("MULTITENANT_MONGO_COLLECTION", "platform_tenants")
+
+	var registry registryFlags
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	registry.addPostgresFlags(flags)
+	registry.addMongoFlags(flags)
+	require.NoError(t, flags.Parse([]string{"--table-prefix", "mt_", "--skip-indexes"}))
+
+	pg := registry.postgresOptions()
+	assert.Equal(t, "registry", pg.Schema)
+	assert.Equal(t, "mt_", pg.TablePrefix)
+	assert.True(t, pg.SkipIndexes)
+
+	mongo := registry.mongoOptions()
+	assert.Equal(t, "platform_tenants", mongo.Collection)
+	assert.True(t, mongo.SkipIndexes)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/infra/postgres"
+)
+
+// runMigrate applies, reverts or reports PostgreSQL registry schema migrations
+func runMigrate(ctx context.Context, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.Usage = func() {
+		fmt.Fprintln(stdout, "Usage: multitenant migrate [flags] up|down|status")
+		flags.PrintDefaults()
+	}
+
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	databaseDSN := flags.String("database-dsn", os.Getenv("MULTITENANT_DATABASE_DSN"), "PostgreSQL registry DSN")
+	var registry registryFlags
+	registry.addPostgresFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one of up, down or status")
+	}
+
+	if *databaseDSN == "" {
+		return core.ErrConfigInvalid("DatabaseDSN", "database DSN is required (use --database-dsn or MULTITENANT_DATABASE_DSN)")
+	}
+
+	migrator, err := postgres.NewMigrator(registry.postgresOptions())
+	if err != nil {
+		return err
+	}
+
+	conn, err := pgx.Connect(ctx, *databaseDSN)
+	if err != nil {
+		return core.ErrDatabaseConnection("postgres", err)
+	}
+	defer conn.Close(context.Background())
+
+	switch action := flags.Arg(0); action {
+	case "up":
+		if err := migrator.Up(ctx, conn); err != nil {
+			return err
+		}
+	case "down":
+		if err := migrator.Down(ctx, conn, *steps); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate action: %s", action)
+	}
+
+	version, err := migrator.Version(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "schema version: %d (latest %d)\n", version, postgres.LatestSchemaVersion())
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/victorximenis/multitenant/infra/mongodb"
+	"github.com/victorximenis/multitenant/infra/postgres"
+)
+
+// registryFlags locate the registry tables and collections. Every command opening the
+// registry registers them, so all of them work against the same configured registry.
+type registryFlags struct {
+	schema      string
+	tablePrefix string
+	skipIndexes bool
+	database    string
+	collection  string
+}
+
+// addPostgresFlags registers the PostgreSQL registry flags, defaulting to the same
+// environment variables as the client
+func (r *registryFlags) addPostgresFlags(flags *flag.FlagSet) {
+	flags.StringVar(&r.schema, "schema", os.Getenv("MULTITENANT_POSTGRES_SCHEMA"), "schema holding the registry tables")
+	flags.StringVar(&r.tablePrefix, "table-prefix", os.Getenv("MULTITENANT_POSTGRES_TABLE_PREFIX"), "prefix of the registry tables")
+	flags.BoolVar(&r.skipIndexes, "skip-indexes", false, "do not create secondary indexes")
+}
+
+// addMongoFlags registers the MongoDB registry flags, defaulting to the same environment
+// variables as the client
+func (r *registryFlags) addMongoFlags(flags *flag.FlagSet) {
+	flags.StringVar(&r.database, "mongo-database", os.Getenv("MULTITENANT_MONGO_DATABASE"), "MongoDB registry database")
+	flags.StringVar(&r.collection, "mongo-collection", os.Getenv("MULTITENANT_MONGO_COLLECTION"), "MongoDB tenants collection")
+}
+
+func (r *registryFlags) postgresOptions() postgres.Options {
+	return postgres.Options{
+		Schema:      r.schema,
+		TablePrefix: r.tablePrefix,
+		SkipIndexes: r.skipIndexes,
+	}
+}
+
+func (r *registryFlags) mongoOptions() mongodb.Options {
+	return mongodb.Options{
+		Database:    r.database,
+		Collection:  r.collection,
+		SkipIndexes: r.skipIndexes,
+	}
+}
@@ -2,6 +2,7 @@ package multitenant
 
 import (
 	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
 	"strconv"
@@ -9,6 +10,10 @@ import (
 	"time"
 
 	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/infra/file"
+	"github.com/victorximenis/multitenant/infra/mongodb"
+	"github.com/victorximenis/multitenant/infra/postgres"
+	"github.com/victorximenis/multitenant/infra/redis"
 )
 
 type DatabaseType string
@@ -16,16 +21,90 @@ type DatabaseType string
 const (
 	PostgreSQL DatabaseType = "postgres"
 	MongoDB    DatabaseType = "mongodb"
+	// Memory keeps tenants in process memory; intended for tests and local development
+	Memory DatabaseType = "memory"
+	// File reads tenants from a YAML/JSON document or directory; the DSN is the path
+	File DatabaseType = "file"
 )
 
+// CacheType selects where tenants are cached between registry lookups
+type CacheType string
+
+const (
+	// RedisCache shares the cache between instances; the default when Redis is configured
+	RedisCache CacheType = "redis"
+	// MemoryCache keeps tenants in process memory; the default for local registries without Redis
+	MemoryCache CacheType = "memory"
+	// NoCache reads every tenant from the registry
+	NoCache CacheType = "none"
+)
+
+// DefaultMemoryCacheSize bounds the in-memory cache when MemoryCacheSize is not set, so
+// lookups of unknown names cannot grow it without limit
+const DefaultMemoryCacheSize = 10000
+
 type Config struct {
 	// Database configuration
 	DatabaseType DatabaseType `json:"database_type"`
 	DatabaseDSN  string       `json:"database_dsn"`
 
+	// Backend specific registry options (schema, table and collection names, indexes, pool sizes)
+	Postgres postgres.Options `json:"postgres"`
+	Mongo    mongodb.Options  `json:"mongo"`
+
 	// Redis configuration
-	RedisURL string        `json:"redis_url"`
-	CacheTTL time.Duration `json:"cache_ttl"`
+	RedisURL string `json:"redis_url"`
+	// Redis selects Redis Cluster or Sentinel through its addresses, and sets credentials
+	// and TLS; without addresses RedisURL is used
+	Redis redis.ClientOptions `json:"redis"`
+	// CacheType selects the tenant cache; empty picks Redis when it is configured and the
+	// in-memory cache otherwise for local registries
+	CacheType CacheType     `json:"cache_type"`
+	CacheTTL  time.Duration `json:"cache_ttl"`
+	// MemoryCacheSize bounds the in-memory cache, evicting the least recently used
+	// tenants and unknown names when it is full (default DefaultMemoryCacheSize)
+	MemoryCacheSize int `json:"memory_cache_size"`
+	// WatchChanges invalidates caches and pools when the registry reports tenant changes
+	WatchChanges bool `json:"watch_changes"`
+	// WatcherName identifies this instance's MongoDB change stream in the resume token
+	// store, so replicas do not overwrite each other's position (default the hostname)
+	WatcherName string `json:"watcher_name"`
+	// PublishChanges broadcasts the changes made by this client over Redis pub/sub and
+	// invalidates caches and pools for the changes broadcast by every instance
+	PublishChanges bool `json:"publish_changes"`
+	// L1CacheSize enables an in-process LRU of up to this many tenants in front of Redis;
+	// 0 disables it
+	L1CacheSize int `json:"l1_cache_size"`
+	// L1CacheTTL caps how long the in-process cache serves a tenant (default 30s)
+	L1CacheTTL time.Duration `json:"l1_cache_ttl"`
+	// NotFoundCacheTTL caches unknown tenant names for this long, so lookups of names
+	// that do not exist skip the registry; 0 disables it
+	NotFoundCacheTTL time.Duration `json:"not_found_cache_ttl"`
+	// CacheRefreshAhead reloads hot tenants in the background when they are read less than
+	// this long before their cache entry expires; 0 disables it
+	CacheRefreshAhead time.Duration `json:"cache_refresh_ahead"`
+	// StaleCacheTTL keeps serving expired tenants for this long while the registry is
+	// unavailable, flagging them with tenantcontext.IsStale; 0 disables it
+	StaleCacheTTL time.Duration `json:"stale_cache_ttl"`
+	// CacheCodec serializes tenants stored in Redis: "json" (default) or "msgpack".
+	// Entries written with either codec stay readable while switching.
+	CacheCodec string `json:"cache_codec"`
+	// CacheCompression gzips large tenants stored in Redis
+	CacheCompression bool `json:"cache_compression"`
+	// CacheKeyPrefix namespaces the Redis keys and pub/sub channel, so environments or
+	// applications can share a Redis
+	CacheKeyPrefix string `json:"cache_key_prefix"`
+
+	// WarmUp loads the active tenants into the cache when the client starts, so the
+	// first request of each tenant after a deploy does not reach the registry
+	WarmUp bool `json:"warm_up"`
+	// WarmUpTimeout bounds how long NewMultitenantClient waits for the warm-up (default
+	// 10s); the rest of it continues in the background
+	WarmUpTimeout time.Duration `json:"warm_up_timeout"`
+	// WarmUpTenants are hot tenants whose connection pools are created during the warm-up
+	WarmUpTenants []string `json:"warm_up_tenants"`
+	// OnWarmUpProgress is called after each warm-up step and once when it is done
+	OnWarmUpProgress func(WarmUpProgress) `json:"-"`
 
 	// HTTP configuration
 	HeaderName       string   `json:"header_name"`
@@ -35,9 +114,15 @@ type Config struct {
 	PoolSize   int           `json:"pool_size"`
 	MaxRetries int           `json:"max_retries"`
 	RetryDelay time.Duration `json:"retry_delay"`
+	// RetryWrites also retries repository writes on transient errors, which may apply
+	// a write twice when a response is lost
+	RetryWrites bool `json:"retry_writes"`
 
 	// Logging configuration
 	LogLevel string `json:"log_level"`
+	// Logger receives the structured logs of every component; when nil they go to
+	// slog.Default() for the levels allowed by LogLevel
+	Logger *slog.Logger `json:"-"`
 }
 
 // LoadConfigFromEnv loads configuration from environment variables
@@ -58,9 +143,13 @@ func LoadConfigFromEnv() (*Config, error) {
 			config.DatabaseType = PostgreSQL
 		} else if dbType == "mongodb" {
 			config.DatabaseType = MongoDB
+		} else if dbType == "memory" {
+			config.DatabaseType = Memory
+		} else if dbType == "file" {
+			config.DatabaseType = File
 		} else {
 			return nil, core.ErrConfigInvalid("MULTITENANT_DATABASE_TYPE",
-				fmt.Sprintf("invalid database type: %s (must be 'postgres' or 'mongodb')", dbType))
+				fmt.Sprintf("invalid database type: %s (must be 'postgres', 'mongodb', 'file' or 'memory')", dbType))
 		}
 	}
 
@@ -68,11 +157,80 @@ func LoadConfigFromEnv() (*Config, error) {
 		config.DatabaseDSN = dbDSN
 	}
 
+	// Registry location inside a shared database
+	config.Postgres.Schema = os.Getenv("MULTITENANT_POSTGRES_SCHEMA")
+	config.Postgres.TablePrefix = os.Getenv("MULTITENANT_POSTGRES_TABLE_PREFIX")
+	config.Mongo.Database = os.Getenv("MULTITENANT_MONGO_DATABASE")
+	config.Mongo.Collection = os.Getenv("MULTITENANT_MONGO_COLLECTION")
+
+	if skip := os.Getenv("MULTITENANT_SKIP_MIGRATIONS"); skip != "" {
+		value, err := strconv.ParseBool(skip)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_SKIP_MIGRATIONS",
+				fmt.Sprintf("invalid skip migrations value: %s (must be true or false)", skip)).
+				WithCause(err)
+		}
+		config.Postgres.SkipMigrations = value
+	}
+
 	// Redis configuration
 	if redisURL := os.Getenv("MULTITENANT_REDIS_URL"); redisURL != "" {
 		config.RedisURL = redisURL
 	}
 
+	if addrs := os.Getenv("MULTITENANT_REDIS_ADDRS"); addrs != "" {
+		config.Redis.Addrs = strings.Split(addrs, ",")
+		for i, addr := range config.Redis.Addrs {
+			config.Redis.Addrs[i] = strings.TrimSpace(addr)
+		}
+	}
+
+	if masterName := os.Getenv("MULTITENANT_REDIS_MASTER_NAME"); masterName != "" {
+		config.Redis.MasterName = masterName
+	}
+
+	if cluster := os.Getenv("MULTITENANT_REDIS_CLUSTER"); cluster != "" {
+		value, err := strconv.ParseBool(cluster)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_REDIS_CLUSTER",
+				fmt.Sprintf("invalid Redis cluster value: %s (must be true or false)", cluster)).
+				WithCause(err)
+		}
+		config.Redis.Cluster = value
+	}
+
+	if username := os.Getenv("MULTITENANT_REDIS_USERNAME"); username != "" {
+		config.Redis.Username = username
+	}
+
+	if password := os.Getenv("MULTITENANT_REDIS_PASSWORD"); password != "" {
+		config.Redis.Password = password
+	}
+
+	if useTLS := os.Getenv("MULTITENANT_REDIS_TLS"); useTLS != "" {
+		value, err := strconv.ParseBool(useTLS)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_REDIS_TLS",
+				fmt.Sprintf("invalid Redis TLS value: %s (must be true or false)", useTLS)).
+				WithCause(err)
+		}
+		config.Redis.TLS = value
+	}
+
+	if cacheType := os.Getenv("MULTITENANT_CACHE_TYPE"); cacheType != "" {
+		config.CacheType = CacheType(cacheType)
+	}
+
+	if memorySize := os.Getenv("MULTITENANT_MEMORY_CACHE_SIZE"); memorySize != "" {
+		size, err := strconv.Atoi(memorySize)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_MEMORY_CACHE_SIZE",
+				fmt.Sprintf("invalid memory cache size: %s (must be a non-negative integer)", memorySize)).
+				WithCause(err)
+		}
+		config.MemoryCacheSize = size
+	}
+
 	if cacheTTL := os.Getenv("MULTITENANT_CACHE_TTL"); cacheTTL != "" {
 		ttl, err := time.ParseDuration(cacheTTL)
 		if err != nil {
@@ -83,6 +241,125 @@ func LoadConfigFromEnv() (*Config, error) {
 		config.CacheTTL = ttl
 	}
 
+	if l1Size := os.Getenv("MULTITENANT_L1_CACHE_SIZE"); l1Size != "" {
+		size, err := strconv.Atoi(l1Size)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_L1_CACHE_SIZE",
+				fmt.Sprintf("invalid L1 cache size: %s (must be a non-negative integer)", l1Size)).
+				WithCause(err)
+		}
+		config.L1CacheSize = size
+	}
+
+	if l1TTL := os.Getenv("MULTITENANT_L1_CACHE_TTL"); l1TTL != "" {
+		ttl, err := time.ParseDuration(l1TTL)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_L1_CACHE_TTL",
+				fmt.Sprintf("invalid L1 cache TTL format: %s (example: '30s', '1m')", l1TTL)).
+				WithCause(err)
+		}
+		config.L1CacheTTL = ttl
+	}
+
+	if notFoundTTL := os.Getenv("MULTITENANT_NOT_FOUND_CACHE_TTL"); notFoundTTL != "" {
+		ttl, err := time.ParseDuration(notFoundTTL)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_NOT_FOUND_CACHE_TTL",
+				fmt.Sprintf("invalid not found cache TTL format: %s (example: '10s', '1m')", notFoundTTL)).
+				WithCause(err)
+		}
+		config.NotFoundCacheTTL = ttl
+	}
+
+	if refreshAhead := os.Getenv("MULTITENANT_CACHE_REFRESH_AHEAD"); refreshAhead != "" {
+		window, err := time.ParseDuration(refreshAhead)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_CACHE_REFRESH_AHEAD",
+				fmt.Sprintf("invalid cache refresh ahead format: %s (example: '30s', '1m')", refreshAhead)).
+				WithCause(err)
+		}
+		config.CacheRefreshAhead = window
+	}
+
+	if staleTTL := os.Getenv("MULTITENANT_STALE_CACHE_TTL"); staleTTL != "" {
+		ttl, err := time.ParseDuration(staleTTL)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_STALE_CACHE_TTL",
+				fmt.Sprintf("invalid stale cache TTL format: %s (example: '10m', '1h')", staleTTL)).
+				WithCause(err)
+		}
+		config.StaleCacheTTL = ttl
+	}
+
+	if codec := os.Getenv("MULTITENANT_CACHE_CODEC"); codec != "" {
+		config.CacheCodec = codec
+	}
+
+	if compression := os.Getenv("MULTITENANT_CACHE_COMPRESSION"); compression != "" {
+		value, err := strconv.ParseBool(compression)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_CACHE_COMPRESSION",
+				fmt.Sprintf("invalid cache compression value: %s (must be true or false)", compression)).
+				WithCause(err)
+		}
+		config.CacheCompression = value
+	}
+
+	if keyPrefix := os.Getenv("MULTITENANT_CACHE_KEY_PREFIX"); keyPrefix != "" {
+		config.CacheKeyPrefix = keyPrefix
+	}
+
+	if warmUp := os.Getenv("MULTITENANT_WARM_UP"); warmUp != "" {
+		value, err := strconv.ParseBool(warmUp)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_WARM_UP",
+				fmt.Sprintf("invalid warm up value: %s (must be true or false)", warmUp)).
+				WithCause(err)
+		}
+		config.WarmUp = value
+	}
+
+	if warmUpTimeout := os.Getenv("MULTITENANT_WARM_UP_TIMEOUT"); warmUpTimeout != "" {
+		timeout, err := time.ParseDuration(warmUpTimeout)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_WARM_UP_TIMEOUT",
+				fmt.Sprintf("invalid warm up timeout format: %s (example: '10s', '1m')", warmUpTimeout)).
+				WithCause(err)
+		}
+		config.WarmUpTimeout = timeout
+	}
+
+	if warmUpTenants := os.Getenv("MULTITENANT_WARM_UP_TENANTS"); warmUpTenants != "" {
+		config.WarmUpTenants = strings.Split(warmUpTenants, ",")
+		for i, name := range config.WarmUpTenants {
+			config.WarmUpTenants[i] = strings.TrimSpace(name)
+		}
+	}
+
+	if watch := os.Getenv("MULTITENANT_WATCH_CHANGES"); watch != "" {
+		value, err := strconv.ParseBool(watch)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_WATCH_CHANGES",
+				fmt.Sprintf("invalid watch changes value: %s (must be true or false)", watch)).
+				WithCause(err)
+		}
+		config.WatchChanges = value
+	}
+
+	if watcherName := os.Getenv("MULTITENANT_WATCHER_NAME"); watcherName != "" {
+		config.WatcherName = watcherName
+	}
+
+	if publish := os.Getenv("MULTITENANT_PUBLISH_CHANGES"); publish != "" {
+		value, err := strconv.ParseBool(publish)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_PUBLISH_CHANGES",
+				fmt.Sprintf("invalid publish changes value: %s (must be true or false)", publish)).
+				WithCause(err)
+		}
+		config.PublishChanges = value
+	}
+
 	// HTTP configuration
 	if headerName := os.Getenv("MULTITENANT_HEADER_NAME"); headerName != "" {
 		config.HeaderName = headerName
@@ -127,6 +404,16 @@ func LoadConfigFromEnv() (*Config, error) {
 		config.RetryDelay = delay
 	}
 
+	if retryWrites := os.Getenv("MULTITENANT_RETRY_WRITES"); retryWrites != "" {
+		value, err := strconv.ParseBool(retryWrites)
+		if err != nil {
+			return nil, core.ErrConfigInvalid("MULTITENANT_RETRY_WRITES",
+				fmt.Sprintf("invalid retry writes value: %s (must be true or false)", retryWrites)).
+				WithCause(err)
+		}
+		config.RetryWrites = value
+	}
+
 	// Logging configuration
 	if logLevel := os.Getenv("MULTITENANT_LOG_LEVEL"); logLevel != "" {
 		config.LogLevel = logLevel
@@ -137,8 +424,8 @@ func LoadConfigFromEnv() (*Config, error) {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	// Database DSN validation
-	if c.DatabaseDSN == "" {
+	// Database DSN validation (the in-memory registry needs no DSN)
+	if c.DatabaseDSN == "" && c.DatabaseType != Memory {
 		return core.ErrConfigInvalid("DatabaseDSN", "database DSN is required")
 	}
 
@@ -147,15 +434,38 @@ func (c *Config) Validate() error {
 		return err
 	}
 
-	// Redis URL validation
-	if c.RedisURL == "" {
-		return core.ErrConfigInvalid("RedisURL", "Redis URL is required")
+	switch c.CacheType {
+	case "", RedisCache, MemoryCache, NoCache:
+	default:
+		return core.ErrConfigInvalid("CacheType",
+			fmt.Sprintf("invalid cache type: %s (must be redis, memory or none)", c.CacheType))
 	}
 
-	if err := c.validateRedisURL(); err != nil {
+	// Redis URL validation (local registries fall back to an in-memory cache)
+	if c.cacheType() == RedisCache && !c.hasRedis() {
+		return core.ErrConfigInvalid("RedisURL", "Redis URL is required (or set CacheType to memory or none)")
+	}
+
+	if c.RedisURL != "" && len(c.Redis.Addrs) == 0 {
+		if err := c.validateRedisURL(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Redis.Validate(); err != nil {
 		return err
 	}
 
+	// Changes are published by PostgreSQL notifications and MongoDB change streams
+	if c.WatchChanges && c.DatabaseType != PostgreSQL && c.DatabaseType != MongoDB {
+		return core.ErrConfigInvalid("WatchChanges",
+			fmt.Sprintf("watching changes is not supported for database type: %s", c.DatabaseType))
+	}
+
+	if c.PublishChanges && !c.hasRedis() {
+		return core.ErrConfigInvalid("PublishChanges", "publishing changes requires a Redis URL")
+	}
+
 	// Pool size validation
 	if c.PoolSize <= 0 {
 		return core.ErrConfigInvalid("PoolSize",
@@ -189,6 +499,95 @@ func (c *Config) Validate() error {
 			fmt.Sprintf("cache TTL too large (max 24h), got: %v", c.CacheTTL))
 	}
 
+	if c.MemoryCacheSize < 0 {
+		return core.ErrConfigInvalid("MemoryCacheSize",
+			fmt.Sprintf("memory cache size cannot be negative, got: %d", c.MemoryCacheSize))
+	}
+
+	// L1 cache validation
+	if c.L1CacheSize < 0 {
+		return core.ErrConfigInvalid("L1CacheSize",
+			fmt.Sprintf("L1 cache size cannot be negative, got: %d", c.L1CacheSize))
+	}
+
+	if c.L1CacheTTL < 0 {
+		return core.ErrConfigInvalid("L1CacheTTL",
+			fmt.Sprintf("L1 cache TTL cannot be negative, got: %v", c.L1CacheTTL))
+	}
+
+	if c.L1CacheTTL > c.CacheTTL {
+		return core.ErrConfigInvalid("L1CacheTTL",
+			fmt.Sprintf("L1 cache TTL cannot exceed the cache TTL (%v), got: %v", c.CacheTTL, c.L1CacheTTL))
+	}
+
+	if c.NotFoundCacheTTL < 0 {
+		return core.ErrConfigInvalid("NotFoundCacheTTL",
+			fmt.Sprintf("not found cache TTL cannot be negative, got: %v", c.NotFoundCacheTTL))
+	}
+
+	if c.NotFoundCacheTTL > c.CacheTTL {
+		return core.ErrConfigInvalid("NotFoundCacheTTL",
+			fmt.Sprintf("not found cache TTL cannot exceed the cache TTL (%v), got: %v", c.CacheTTL, c.NotFoundCacheTTL))
+	}
+
+	if c.CacheRefreshAhead < 0 || c.CacheRefreshAhead >= c.CacheTTL {
+		return core.ErrConfigInvalid("CacheRefreshAhead",
+			fmt.Sprintf("cache refresh ahead must be between 0 and the cache TTL (%v), got: %v", c.CacheTTL, c.CacheRefreshAhead))
+	}
+
+	if c.StaleCacheTTL < 0 {
+		return core.ErrConfigInvalid("StaleCacheTTL",
+			fmt.Sprintf("stale cache TTL cannot be negative, got: %v", c.StaleCacheTTL))
+	}
+
+	// The L1 cache sits in front of Redis, and the cache features need a cache to work
+	if c.L1CacheSize > 0 && c.cacheType() != RedisCache {
+		return core.ErrConfigInvalid("L1CacheSize",
+			fmt.Sprintf("L1 cache requires the Redis cache, got cache type: %s", c.cacheType()))
+	}
+
+	if c.MemoryCacheSize > 0 && c.cacheType() != MemoryCache {
+		return core.ErrConfigInvalid("MemoryCacheSize",
+			fmt.Sprintf("memory cache size requires the memory cache, got cache type: %s", c.cacheType()))
+	}
+
+	if c.cacheType() == NoCache && (c.NotFoundCacheTTL > 0 || c.CacheRefreshAhead > 0 || c.StaleCacheTTL > 0) {
+		return core.ErrConfigInvalid("CacheType",
+			"not found caching, refresh ahead and stale cache require a cache")
+	}
+
+	validCacheCodecs := map[string]bool{
+		"":        true,
+		"json":    true,
+		"msgpack": true,
+	}
+
+	if !validCacheCodecs[c.CacheCodec] {
+		return core.ErrConfigInvalid("CacheCodec",
+			fmt.Sprintf("invalid cache codec: %s (must be json or msgpack)", c.CacheCodec))
+	}
+
+	if strings.ContainsAny(c.CacheKeyPrefix, " \t\r\n") {
+		return core.ErrConfigInvalid("CacheKeyPrefix",
+			fmt.Sprintf("cache key prefix cannot contain whitespace, got: %q", c.CacheKeyPrefix))
+	}
+
+	// Warm-up validation
+	if c.WarmUpTimeout < 0 {
+		return core.ErrConfigInvalid("WarmUpTimeout",
+			fmt.Sprintf("warm up timeout cannot be negative, got: %v", c.WarmUpTimeout))
+	}
+
+	if len(c.WarmUpTenants) > 0 && !c.WarmUp {
+		return core.ErrConfigInvalid("WarmUpTenants", "warm up tenants require WarmUp")
+	}
+
+	for _, name := range c.WarmUpTenants {
+		if name == "" {
+			return core.ErrConfigInvalid("WarmUpTenants", "warm up tenant names cannot be empty")
+		}
+	}
+
 	// Retry delay validation
 	if c.RetryDelay < 0 {
 		return core.ErrConfigInvalid("RetryDelay",
@@ -230,15 +629,67 @@ func (c *Config) Validate() error {
 func (c *Config) validateDSN() error {
 	switch c.DatabaseType {
 	case PostgreSQL:
-		return c.validatePostgresDSN()
+		if err := c.validatePostgresDSN(); err != nil {
+			return err
+		}
+		return c.Postgres.Validate()
 	case MongoDB:
-		return c.validateMongoDSN()
+		if err := c.validateMongoDSN(); err != nil {
+			return err
+		}
+		return c.Mongo.Validate()
+	case Memory:
+		return nil
+	case File:
+		if _, err := file.ParseDSN(c.DatabaseDSN); err != nil {
+			return err
+		}
+		return nil
 	default:
 		return core.ErrConfigInvalid("DatabaseType",
 			fmt.Sprintf("unsupported database type: %s", c.DatabaseType))
 	}
 }
 
+// hasRedis reports whether a Redis URL or Redis addresses are configured
+func (c *Config) hasRedis() bool {
+	return c.RedisURL != "" || len(c.Redis.Addrs) > 0
+}
+
+// cacheType returns the configured cache type, or the default one when it is empty
+func (c *Config) cacheType() CacheType {
+	if c.CacheType != "" {
+		return c.CacheType
+	}
+	if c.hasRedis() || !c.usesLocalRegistry() {
+		return RedisCache
+	}
+	return MemoryCache
+}
+
+// memoryCacheSize returns the bound of the in-memory cache
+func (c *Config) memoryCacheSize() int {
+	if c.MemoryCacheSize > 0 {
+		return c.MemoryCacheSize
+	}
+	return DefaultMemoryCacheSize
+}
+
+// watcherName returns the name of this instance's change stream watcher. The hostname
+// differs between replicas; without one the watcher falls back to its default name.
+func (c *Config) watcherName() string {
+	if c.WatcherName != "" {
+		return c.WatcherName
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// usesLocalRegistry reports whether tenants are kept in process, so Redis is optional
+func (c *Config) usesLocalRegistry() bool {
+	return c.DatabaseType == Memory || c.DatabaseType == File
+}
+
 // validatePostgresDSN validates PostgreSQL DSN format
 func (c *Config) validatePostgresDSN() error {
 	// Basic format check for PostgreSQL DSN
@@ -302,8 +753,11 @@ func (c *Config) GetDatabaseHost() (string, error) {
 	return u.Host, nil
 }
 
-// GetRedisHost extracts the host from the Redis URL
+// GetRedisHost extracts the host from the Redis URL, or lists the configured Redis addresses
 func (c *Config) GetRedisHost() (string, error) {
+	if len(c.Redis.Addrs) > 0 {
+		return strings.Join(c.Redis.Addrs, ","), nil
+	}
 	u, err := url.Parse(c.RedisURL)
 	if err != nil {
 		return "", core.ErrConfigInvalid("RedisURL", "failed to parse Redis URL").WithCause(err)
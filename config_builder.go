@@ -1,7 +1,12 @@
 package multitenant
 
 import (
+	"crypto/tls"
+	"log/slog"
 	"time"
+
+	"github.com/victorximenis/multitenant/infra/mongodb"
+	"github.com/victorximenis/multitenant/infra/postgres"
 )
 
 // ConfigBuilder provides a fluent interface for building configuration
@@ -38,18 +43,172 @@ func (b *ConfigBuilder) WithMongoDB(dsn string) *ConfigBuilder {
 	return b
 }
 
+// WithPostgresOptions sets the schema, table prefix, migration, index and pool options of the PostgreSQL registry
+func (b *ConfigBuilder) WithPostgresOptions(opts postgres.Options) *ConfigBuilder {
+	b.config.Postgres = opts
+	return b
+}
+
+// WithMongoOptions sets the database, collection, index and pool options of the MongoDB registry
+func (b *ConfigBuilder) WithMongoOptions(opts mongodb.Options) *ConfigBuilder {
+	b.config.Mongo = opts
+	return b
+}
+
+// WithMemory configures the in-memory registry, intended for tests and local development
+func (b *ConfigBuilder) WithMemory() *ConfigBuilder {
+	b.config.DatabaseType = Memory
+	b.config.DatabaseDSN = ""
+	return b
+}
+
+// WithFile configures a file-backed registry read from a YAML/JSON document or directory
+func (b *ConfigBuilder) WithFile(path string) *ConfigBuilder {
+	b.config.DatabaseType = File
+	b.config.DatabaseDSN = path
+	return b
+}
+
 // WithRedis configures Redis connection
 func (b *ConfigBuilder) WithRedis(url string) *ConfigBuilder {
 	b.config.RedisURL = url
 	return b
 }
 
+// WithCacheType selects the tenant cache: RedisCache, MemoryCache or NoCache
+func (b *ConfigBuilder) WithCacheType(cacheType CacheType) *ConfigBuilder {
+	b.config.CacheType = cacheType
+	return b
+}
+
+// WithMemoryCacheSize bounds the in-memory cache to size tenants and unknown names
+func (b *ConfigBuilder) WithMemoryCacheSize(size int) *ConfigBuilder {
+	b.config.MemoryCacheSize = size
+	return b
+}
+
+// WithRedisCluster connects to Redis Cluster through the given seed nodes
+func (b *ConfigBuilder) WithRedisCluster(addrs ...string) *ConfigBuilder {
+	b.config.Redis.Addrs = addrs
+	b.config.Redis.MasterName = ""
+	b.config.Redis.Cluster = true
+	return b
+}
+
+// WithRedisSentinel connects to the master named masterName through the given sentinels
+func (b *ConfigBuilder) WithRedisSentinel(masterName string, sentinelAddrs ...string) *ConfigBuilder {
+	b.config.Redis.Addrs = sentinelAddrs
+	b.config.Redis.MasterName = masterName
+	b.config.Redis.Cluster = false
+	return b
+}
+
+// WithRedisCredentials sets the Redis ACL username and password
+func (b *ConfigBuilder) WithRedisCredentials(username, password string) *ConfigBuilder {
+	b.config.Redis.Username = username
+	b.config.Redis.Password = password
+	return b
+}
+
+// WithRedisTLS enables TLS for Redis; a nil config uses the default settings
+func (b *ConfigBuilder) WithRedisTLS(tlsConfig *tls.Config) *ConfigBuilder {
+	b.config.Redis.TLS = true
+	b.config.Redis.TLSConfig = tlsConfig
+	return b
+}
+
 // WithCacheTTL sets the cache TTL
 func (b *ConfigBuilder) WithCacheTTL(ttl time.Duration) *ConfigBuilder {
 	b.config.CacheTTL = ttl
 	return b
 }
 
+// WithL1Cache enables an in-process LRU of up to size tenants in front of Redis,
+// serving each tenant for at most ttl
+func (b *ConfigBuilder) WithL1Cache(size int, ttl time.Duration) *ConfigBuilder {
+	b.config.L1CacheSize = size
+	b.config.L1CacheTTL = ttl
+	return b
+}
+
+// WithNotFoundCache caches unknown tenant names for ttl, so lookups of names that do
+// not exist skip the registry
+func (b *ConfigBuilder) WithNotFoundCache(ttl time.Duration) *ConfigBuilder {
+	b.config.NotFoundCacheTTL = ttl
+	return b
+}
+
+// WithRefreshAhead reloads hot tenants in the background when they are read less than
+// window before their cache entry expires
+func (b *ConfigBuilder) WithRefreshAhead(window time.Duration) *ConfigBuilder {
+	b.config.CacheRefreshAhead = window
+	return b
+}
+
+// WithStaleCache keeps serving expired tenants for up to ttl while the registry is unavailable
+func (b *ConfigBuilder) WithStaleCache(ttl time.Duration) *ConfigBuilder {
+	b.config.StaleCacheTTL = ttl
+	return b
+}
+
+// WithCacheCodec selects the codec of tenants stored in Redis: "json" or "msgpack"
+func (b *ConfigBuilder) WithCacheCodec(codec string) *ConfigBuilder {
+	b.config.CacheCodec = codec
+	return b
+}
+
+// WithCacheCompression gzips large tenants stored in Redis
+func (b *ConfigBuilder) WithCacheCompression() *ConfigBuilder {
+	b.config.CacheCompression = true
+	return b
+}
+
+// WithCacheKeyPrefix namespaces the Redis keys and pub/sub channel, e.g. "prod:billing"
+func (b *ConfigBuilder) WithCacheKeyPrefix(prefix string) *ConfigBuilder {
+	b.config.CacheKeyPrefix = prefix
+	return b
+}
+
+// WithWarmUp loads the active tenants into the cache on startup, waiting at most timeout,
+// and creates the connection pools of hotTenants
+func (b *ConfigBuilder) WithWarmUp(timeout time.Duration, hotTenants ...string) *ConfigBuilder {
+	b.config.WarmUp = true
+	b.config.WarmUpTimeout = timeout
+	b.config.WarmUpTenants = hotTenants
+	return b
+}
+
+// WithWarmUpProgress sets the callback reporting the progress of the startup warm-up
+func (b *ConfigBuilder) WithWarmUpProgress(fn func(WarmUpProgress)) *ConfigBuilder {
+	b.config.OnWarmUpProgress = fn
+	return b
+}
+
+// WithPublishChanges broadcasts tenant changes over Redis pub/sub so every instance
+// invalidates its caches and pools
+func (b *ConfigBuilder) WithPublishChanges() *ConfigBuilder {
+	b.config.PublishChanges = true
+	return b
+}
+
+// WithWatchChanges invalidates caches and pools when the registry reports tenant changes
+func (b *ConfigBuilder) WithWatchChanges() *ConfigBuilder {
+	b.config.WatchChanges = true
+	return b
+}
+
+// WithWatcherName names this instance's MongoDB change stream in the resume token store
+func (b *ConfigBuilder) WithWatcherName(name string) *ConfigBuilder {
+	b.config.WatcherName = name
+	return b
+}
+
+// WithRetryWrites also retries repository writes on transient errors
+func (b *ConfigBuilder) WithRetryWrites() *ConfigBuilder {
+	b.config.RetryWrites = true
+	return b
+}
+
 // WithHeaderName sets the HTTP header name for tenant identification
 func (b *ConfigBuilder) WithHeaderName(name string) *ConfigBuilder {
 	b.config.HeaderName = name
@@ -75,6 +234,12 @@ func (b *ConfigBuilder) WithLogLevel(level string) *ConfigBuilder {
 	return b
 }
 
+// WithLogger sets the structured logger of the library, which then ignores LogLevel
+func (b *ConfigBuilder) WithLogger(logger *slog.Logger) *ConfigBuilder {
+	b.config.Logger = logger
+	return b
+}
+
 // WithDevelopmentDefaults sets development-friendly defaults
 func (b *ConfigBuilder) WithDevelopmentDefaults() *ConfigBuilder {
 	b.config.LogLevel = "debug"
@@ -139,16 +304,38 @@ func (b *ConfigBuilder) MustBuild() *Config {
 func (b *ConfigBuilder) Clone() *ConfigBuilder {
 	return &ConfigBuilder{
 		config: &Config{
-			DatabaseType:     b.config.DatabaseType,
-			DatabaseDSN:      b.config.DatabaseDSN,
-			RedisURL:         b.config.RedisURL,
-			CacheTTL:         b.config.CacheTTL,
-			HeaderName:       b.config.HeaderName,
-			PoolSize:         b.config.PoolSize,
-			MaxRetries:       b.config.MaxRetries,
-			RetryDelay:       b.config.RetryDelay,
-			LogLevel:         b.config.LogLevel,
-			IgnoredEndpoints: append([]string(nil), b.config.IgnoredEndpoints...),
+			DatabaseType:      b.config.DatabaseType,
+			DatabaseDSN:       b.config.DatabaseDSN,
+			Postgres:          b.config.Postgres,
+			Mongo:             b.config.Mongo,
+			RedisURL:          b.config.RedisURL,
+			Redis:             b.config.Redis,
+			CacheType:         b.config.CacheType,
+			CacheTTL:          b.config.CacheTTL,
+			MemoryCacheSize:   b.config.MemoryCacheSize,
+			WatchChanges:      b.config.WatchChanges,
+			WatcherName:       b.config.WatcherName,
+			PublishChanges:    b.config.PublishChanges,
+			L1CacheSize:       b.config.L1CacheSize,
+			L1CacheTTL:        b.config.L1CacheTTL,
+			NotFoundCacheTTL:  b.config.NotFoundCacheTTL,
+			CacheRefreshAhead: b.config.CacheRefreshAhead,
+			StaleCacheTTL:     b.config.StaleCacheTTL,
+			CacheCodec:        b.config.CacheCodec,
+			CacheCompression:  b.config.CacheCompression,
+			CacheKeyPrefix:    b.config.CacheKeyPrefix,
+			WarmUp:            b.config.WarmUp,
+			WarmUpTimeout:     b.config.WarmUpTimeout,
+			WarmUpTenants:     append([]string(nil), b.config.WarmUpTenants...),
+			OnWarmUpProgress:  b.config.OnWarmUpProgress,
+			HeaderName:        b.config.HeaderName,
+			PoolSize:          b.config.PoolSize,
+			MaxRetries:        b.config.MaxRetries,
+			RetryDelay:        b.config.RetryDelay,
+			RetryWrites:       b.config.RetryWrites,
+			LogLevel:          b.config.LogLevel,
+			Logger:            b.config.Logger,
+			IgnoredEndpoints:  append([]string(nil), b.config.IgnoredEndpoints...),
 		},
 	}
 }
@@ -157,16 +344,38 @@ func (b *ConfigBuilder) Clone() *ConfigBuilder {
 func FromConfig(config *Config) *ConfigBuilder {
 	return &ConfigBuilder{
 		config: &Config{
-			DatabaseType:     config.DatabaseType,
-			DatabaseDSN:      config.DatabaseDSN,
-			RedisURL:         config.RedisURL,
-			CacheTTL:         config.CacheTTL,
-			HeaderName:       config.HeaderName,
-			PoolSize:         config.PoolSize,
-			MaxRetries:       config.MaxRetries,
-			RetryDelay:       config.RetryDelay,
-			LogLevel:         config.LogLevel,
-			IgnoredEndpoints: append([]string(nil), config.IgnoredEndpoints...),
+			DatabaseType:      config.DatabaseType,
+			DatabaseDSN:       config.DatabaseDSN,
+			Postgres:          config.Postgres,
+			Mongo:             config.Mongo,
+			RedisURL:          config.RedisURL,
+			Redis:             config.Redis,
+			CacheType:         config.CacheType,
+			CacheTTL:          config.CacheTTL,
+			MemoryCacheSize:   config.MemoryCacheSize,
+			WatchChanges:      config.WatchChanges,
+			WatcherName:       config.WatcherName,
+			PublishChanges:    config.PublishChanges,
+			L1CacheSize:       config.L1CacheSize,
+			L1CacheTTL:        config.L1CacheTTL,
+			NotFoundCacheTTL:  config.NotFoundCacheTTL,
+			CacheRefreshAhead: config.CacheRefreshAhead,
+			StaleCacheTTL:     config.StaleCacheTTL,
+			CacheCodec:        config.CacheCodec,
+			CacheCompression:  config.CacheCompression,
+			CacheKeyPrefix:    config.CacheKeyPrefix,
+			WarmUp:            config.WarmUp,
+			WarmUpTimeout:     config.WarmUpTimeout,
+			WarmUpTenants:     append([]string(nil), config.WarmUpTenants...),
+			OnWarmUpProgress:  config.OnWarmUpProgress,
+			HeaderName:        config.HeaderName,
+			PoolSize:          config.PoolSize,
+			MaxRetries:        config.MaxRetries,
+			RetryDelay:        config.RetryDelay,
+			RetryWrites:       config.RetryWrites,
+			LogLevel:          config.LogLevel,
+			Logger:            config.Logger,
+			IgnoredEndpoints:  append([]string(nil), config.IgnoredEndpoints...),
 		},
 	}
 }
@@ -207,3 +416,11 @@ func MongoConfig(mongoURL, redisURL string) *Config {
 		WithRedis(redisURL).
 		MustBuild()
 }
+
+// MemoryConfig returns a configuration using the in-memory registry and cache
+func MemoryConfig() *Config {
+	return NewConfigBuilder().
+		WithMemory().
+		WithTestDefaults().
+		MustBuild()
+}
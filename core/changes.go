@@ -0,0 +1,34 @@
+package core
+
+import "context"
+
+// ChangeOp is the kind of change reported for a tenant
+type ChangeOp string
+
+const (
+	ChangeInsert ChangeOp = "INSERT"
+	ChangeUpdate ChangeOp = "UPDATE"
+	ChangeDelete ChangeOp = "DELETE"
+	// ChangeDatasource means only the datasources of the tenant changed
+	ChangeDatasource ChangeOp = "DATASOURCE"
+	// ChangeResync means changes may have been missed, e.g. after a reconnect, and every
+	// cached tenant must be considered stale
+	ChangeResync ChangeOp = "RESYNC"
+)
+
+// TenantChange describes a change to a tenant or one of its datasources, as reported
+// by a registry change feed
+type TenantChange struct {
+	Op ChangeOp `json:"op"`
+	ID string   `json:"id"`
+	// Name is empty when it is no longer known, e.g. for datasources removed by a cascade
+	Name string `json:"name"`
+	// OldName is set when the tenant was renamed
+	OldName string `json:"old_name,omitempty"`
+}
+
+// ChangePublisher broadcasts tenant changes to the instances sharing the registry, so
+// they can drop the tenants they cached and the pools they opened
+type ChangePublisher interface {
+	PublishChange(ctx context.Context, change TenantChange) error
+}
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"context"
+	"io"
+)
+
+// CloseResource releases a repository, cache or other resource that has a Close method
+// with one of the signatures used in this module, and does nothing otherwise
+func CloseResource(ctx context.Context, resource interface{}) error {
+	switch r := resource.(type) {
+	case interface{ Close(context.Context) error }:
+		return r.Close(ctx)
+	case io.Closer:
+		return r.Close()
+	case interface{ Close() }:
+		r.Close()
+	}
+	return nil
+}
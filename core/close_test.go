@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closeFunc func()
+
+func (f closeFunc) Close() { f() }
+
+type closeErrFunc func() error
+
+func (f closeErrFunc) Close() error { return f() }
+
+type closeCtxFunc func(context.Context) error
+
+func (f closeCtxFunc) Close(ctx context.Context) error { return f(ctx) }
+
+func TestCloseResource(t *testing.T) {
+	ctx := context.Background()
+	failed := errors.New("close failed")
+
+	var closed bool
+	assert.NoError(t, CloseResource(ctx, closeFunc(func() { closed = true })))
+	assert.True(t, closed)
+
+	assert.ErrorIs(t, CloseResource(ctx, closeErrFunc(func() error { return failed })), failed)
+	assert.ErrorIs(t, CloseResource(ctx, closeCtxFunc(func(context.Context) error { return failed })), failed)
+
+	assert.NoError(t, CloseResource(ctx, nil))
+	assert.NoError(t, CloseResource(ctx, "not closable"))
+}
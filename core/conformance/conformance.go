@@ -0,0 +1,284 @@
+// Package conformance provides shared tests that every core.TenantRepository and
+// core.TenantCache implementation must pass, so all backends report the same
+// structured errors for the same situations.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// RunRepositoryTests runs the repository conformance suite against the repository
+// returned by newRepo. Tenant names are unique per run, so the repository may be shared
+// with other tests.
+func RunRepositoryTests(t *testing.T, newRepo func(t *testing.T) core.TenantRepository) {
+	t.Helper()
+
+	t.Run("GetByNameMissing", func(t *testing.T) {
+		repo := newRepo(t)
+		name := uniqueName("missing")
+
+		_, err := repo.GetByName(context.Background(), name)
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+
+		var notFound core.TenantNotFoundError
+		require.True(t, errors.As(err, &notFound), "errors.As must fill core.TenantNotFoundError")
+		assert.Equal(t, name, notFound.Name)
+		assert.True(t, errors.Is(err, core.TenantNotFoundError{}))
+	})
+
+	t.Run("GetByID", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		tenant := newTenant(uniqueName("by-id"))
+		require.NoError(t, repo.Create(ctx, tenant))
+
+		found, err := repo.GetByID(ctx, tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Name, found.Name)
+		assert.Len(t, found.Datasources, 1)
+
+		_, err = repo.GetByID(ctx, uuid.New().String())
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+
+		_, err = repo.GetByID(ctx, tenant.Name)
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+	})
+
+	t.Run("CreateDuplicateName", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		name := uniqueName("duplicate")
+
+		require.NoError(t, repo.Create(ctx, newTenant(name)))
+
+		err := repo.Create(ctx, newTenant(name))
+		assertCode(t, err, core.ErrCodeTenantExists)
+		assert.True(t, errors.Is(err, core.NewError(core.ErrCodeTenantExists, "")))
+	})
+
+	t.Run("CreateInvalid", func(t *testing.T) {
+		repo := newRepo(t)
+		tenant := newTenant(uniqueName("invalid"))
+		tenant.ID = "not-a-uuid"
+
+		err := repo.Create(context.Background(), tenant)
+		assertCode(t, err, core.ErrCodeTenantInvalid)
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		repo := newRepo(t)
+
+		err := repo.Update(context.Background(), newTenant(uniqueName("update-missing")))
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+	})
+
+	t.Run("DeleteByID", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		tenant := newTenant(uniqueName("delete"))
+		require.NoError(t, repo.Create(ctx, tenant))
+
+		// Deletes address tenants by ID only, never by name
+		assertCode(t, repo.Delete(ctx, tenant.Name), core.ErrCodeTenantNotFound)
+		require.NoError(t, repo.Delete(ctx, tenant.ID))
+
+		_, err := repo.GetByName(ctx, tenant.Name)
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+		_, err = repo.GetByID(ctx, tenant.ID)
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+	})
+
+	t.Run("DeleteMissing", func(t *testing.T) {
+		repo := newRepo(t)
+
+		err := repo.Delete(context.Background(), uuid.New().String())
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+	})
+
+	t.Run("ListOrderedByName", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		prefix := uniqueName("list")
+
+		for _, suffix := range []string{"-c", "-a", "-b"} {
+			require.NoError(t, repo.Create(ctx, newTenant(prefix+suffix)))
+		}
+
+		tenants, err := repo.List(ctx)
+		require.NoError(t, err)
+
+		var names []string
+		for _, tenant := range tenants {
+			names = append(names, tenant.Name)
+		}
+		assert.True(t, sort.StringsAreSorted(names), "List must return tenants ordered by name")
+		assert.Subset(t, names, []string{prefix + "-a", prefix + "-b", prefix + "-c"})
+	})
+
+	t.Run("ListByLabels", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		run := uniqueName("labels")[len("conformance-labels-"):]
+
+		gold := newTenant(uniqueName("gold"))
+		gold.Labels = map[string]string{"run": run, "tier": "gold"}
+		silver := newTenant(uniqueName("silver"))
+		silver.Labels = map[string]string{"run": run, "tier": "silver", "legacy": ""}
+		require.NoError(t, repo.Create(ctx, gold))
+		require.NoError(t, repo.Create(ctx, silver))
+
+		found, err := repo.GetByID(ctx, gold.ID)
+		require.NoError(t, err)
+		assert.Equal(t, gold.Labels, found.Labels, "labels must round-trip")
+
+		selectNames := func(selector string) []string {
+			tenants, err := core.ListByLabels(ctx, repo, core.MustParseLabelSelector("run="+run+","+selector))
+			require.NoError(t, err)
+			var names []string
+			for _, tenant := range tenants {
+				names = append(names, tenant.Name)
+			}
+			return names
+		}
+
+		assert.Equal(t, []string{gold.Name}, selectNames("tier=gold"))
+		assert.Equal(t, []string{gold.Name}, selectNames("tier!=silver"))
+		assert.ElementsMatch(t, []string{gold.Name, silver.Name}, selectNames("tier in (gold,silver)"))
+		assert.Equal(t, []string{silver.Name}, selectNames("tier notin (gold)"))
+		assert.Equal(t, []string{silver.Name}, selectNames("legacy"))
+		assert.Equal(t, []string{gold.Name}, selectNames("!legacy"))
+
+		invalid := newTenant(uniqueName("bad-labels"))
+		invalid.Labels = map[string]string{"bad.key": "x"}
+		assertCode(t, repo.Create(ctx, invalid), core.ErrCodeTenantInvalid)
+	})
+
+	t.Run("FindByMetadataExactValues", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		run := uniqueName("metadata")
+
+		full := newTenant(uniqueName("full"))
+		full.Metadata = map[string]interface{}{
+			"run":     run,
+			"tags":    []interface{}{"a", "b"},
+			"billing": map[string]interface{}{"plan": "pro", "seats": 5},
+		}
+		partial := newTenant(uniqueName("partial"))
+		partial.Metadata = map[string]interface{}{
+			"run":     run,
+			"tags":    []interface{}{"a"},
+			"billing": map[string]interface{}{"plan": "pro"},
+		}
+		require.NoError(t, repo.Create(ctx, full))
+		require.NoError(t, repo.Create(ctx, partial))
+
+		findNames := func(condition core.MetadataCondition) []string {
+			tenants, err := core.FindByMetadata(ctx, repo, core.NewMetadataQuery(core.MetadataEquals("run", run), condition))
+			require.NoError(t, err)
+			var names []string
+			for _, tenant := range tenants {
+				names = append(names, tenant.Name)
+			}
+			return names
+		}
+
+		// Objects and arrays equal whole values, never values that merely contain them
+		assert.Equal(t, []string{partial.Name}, findNames(core.MetadataEquals("tags", []string{"a"})))
+		assert.Empty(t, findNames(core.MetadataEquals("tags", "a")))
+		assert.Equal(t, []string{partial.Name}, findNames(core.MetadataEquals("billing", map[string]interface{}{"plan": "pro"})))
+		assert.Equal(t, []string{full.Name}, findNames(core.MetadataEquals("billing", map[string]interface{}{"seats": 5, "plan": "pro"})))
+		assert.Equal(t, []string{full.Name}, findNames(core.MetadataNotEquals("billing", map[string]interface{}{"plan": "pro"})))
+		assert.Equal(t, []string{full.Name}, findNames(core.MetadataNotEquals("tags", []string{"a"})))
+		assert.Equal(t, []string{full.Name}, findNames(core.MetadataIn("tags", []string{"a", "b"}, "a")))
+	})
+}
+
+// RunCacheTests runs the cache conformance suite against the cache returned by newCache
+func RunCacheTests(t *testing.T, newCache func(t *testing.T) core.TenantCache) {
+	t.Helper()
+
+	t.Run("GetMissing", func(t *testing.T) {
+		cache := newCache(t)
+		name := uniqueName("missing")
+
+		_, err := cache.Get(context.Background(), name)
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+
+		var notFound core.TenantNotFoundError
+		require.True(t, errors.As(err, &notFound), "errors.As must fill core.TenantNotFoundError")
+		assert.Equal(t, name, notFound.Name)
+	})
+
+	t.Run("SetGetDelete", func(t *testing.T) {
+		cache := newCache(t)
+		ctx := context.Background()
+		tenant := newTenant(uniqueName("cached"))
+
+		require.NoError(t, cache.Set(ctx, tenant, time.Minute))
+
+		cached, err := cache.Get(ctx, tenant.Name)
+		require.NoError(t, err)
+		assert.Equal(t, tenant.ID, cached.ID)
+
+		cached, err = cache.GetByID(ctx, tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Name, cached.Name)
+
+		require.NoError(t, cache.Delete(ctx, tenant.Name))
+
+		_, err = cache.Get(ctx, tenant.Name)
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+		_, err = cache.GetByID(ctx, tenant.ID)
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+	})
+
+	t.Run("GetByIDMissing", func(t *testing.T) {
+		cache := newCache(t)
+
+		_, err := cache.GetByID(context.Background(), uuid.New().String())
+		assertCode(t, err, core.ErrCodeTenantNotFound)
+	})
+
+	t.Run("SetNil", func(t *testing.T) {
+		cache := newCache(t)
+
+		err := cache.Set(context.Background(), nil, time.Minute)
+		assertCode(t, err, core.ErrCodeTenantInvalid)
+	})
+}
+
+// assertCode checks that err is a structured error with the given code
+func assertCode(t *testing.T, err error, code core.ErrorCode) {
+	t.Helper()
+
+	require.Error(t, err)
+
+	var mtErr *core.MultitenantError
+	require.True(t, errors.As(err, &mtErr), "expected *core.MultitenantError, got %T: %v", err, err)
+	assert.Equal(t, code, mtErr.Code, "unexpected error: %v", err)
+	assert.True(t, core.IsErrorCode(err, code))
+}
+
+// uniqueName returns a tenant name that does not collide with other runs
+func uniqueName(prefix string) string {
+	return "conformance-" + prefix + "-" + uuid.New().String()[:8]
+}
+
+// newTenant returns a valid tenant with one datasource
+func newTenant(name string) *core.Tenant {
+	tenant := core.NewTenant(name)
+	tenant.Datasources = append(tenant.Datasources,
+		*core.NewDatasource(tenant.ID, "postgres://localhost:5432/"+name, "rw", 5))
+	return tenant
+}
@@ -0,0 +1,93 @@
+package core
+
+// CopyTenant returns a deep copy of a tenant, sharing no datasources, metadata or labels
+// with the original
+func CopyTenant(tenant *Tenant) *Tenant {
+	if tenant == nil {
+		return nil
+	}
+
+	copied := *tenant
+	copied.Metadata = cloneMap(tenant.Metadata)
+	copied.Labels = cloneLabels(tenant.Labels)
+
+	if tenant.Datasources != nil {
+		copied.Datasources = make([]Datasource, len(tenant.Datasources))
+		for i, ds := range tenant.Datasources {
+			ds.Metadata = cloneMap(ds.Metadata)
+			copied.Datasources[i] = ds
+		}
+	}
+
+	return &copied
+}
+
+// CopyTemplate returns a deep copy of a tenant template
+func CopyTemplate(tmpl *TenantTemplate) *TenantTemplate {
+	if tmpl == nil {
+		return nil
+	}
+
+	copied := *tmpl
+	copied.Metadata = cloneMap(tmpl.Metadata)
+	copied.Labels = cloneLabels(tmpl.Labels)
+
+	if tmpl.Features != nil {
+		copied.Features = make(map[string]bool, len(tmpl.Features))
+		for flag, enabled := range tmpl.Features {
+			copied.Features[flag] = enabled
+		}
+	}
+
+	if tmpl.Datasources != nil {
+		copied.Datasources = make([]DatasourceTemplate, len(tmpl.Datasources))
+		for i, ds := range tmpl.Datasources {
+			ds.Metadata = cloneMap(ds.Metadata)
+			copied.Datasources[i] = ds
+		}
+	}
+
+	return &copied
+}
+
+// cloneLabels copies tenant labels
+func cloneLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+
+	copied := make(map[string]string, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
+// cloneMap deep copies JSON-like metadata
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	copied := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		copied[key] = copyValue(value)
+	}
+	return copied
+}
+
+// copyValue deep copies JSON-like metadata values
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
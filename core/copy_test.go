@@ -0,0 +1,29 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyTenant(t *testing.T) {
+	assert.Nil(t, CopyTenant(nil))
+
+	tenant := NewTenant("acme")
+	tenant.Metadata = map[string]interface{}{"tags": []interface{}{"a", map[string]interface{}{"b": 1}}}
+	tenant.Labels = map[string]string{"tier": "free"}
+	tenant.Datasources = []Datasource{{DSN: "postgres://localhost/acme", Metadata: map[string]interface{}{"region": "us"}}}
+
+	copied := CopyTenant(tenant)
+	assert.Equal(t, tenant, copied)
+
+	copied.Metadata["tags"].([]interface{})[1].(map[string]interface{})["b"] = 2
+	copied.Labels["tier"] = "enterprise"
+	copied.Datasources[0].Metadata["region"] = "eu"
+	copied.Datasources = append(copied.Datasources, Datasource{DSN: "postgres://localhost/other"})
+
+	assert.Equal(t, 1, tenant.Metadata["tags"].([]interface{})[1].(map[string]interface{})["b"])
+	assert.Equal(t, "free", tenant.Labels["tier"])
+	assert.Equal(t, "us", tenant.Datasources[0].Metadata["region"])
+	assert.Len(t, tenant.Datasources, 1)
+}
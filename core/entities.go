@@ -14,6 +14,7 @@ type Tenant struct {
 	Name        string                 `json:"name"`
 	IsActive    bool                   `json:"is_active"`
 	Metadata    map[string]interface{} `json:"metadata"`
+	Labels      map[string]string      `json:"labels,omitempty"`
 	Datasources []Datasource           `json:"datasources"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
@@ -34,6 +35,10 @@ func (t *Tenant) Validate() error {
 		return errors.New("tenant ID must be a valid UUID")
 	}
 
+	if err := ValidateLabels(t.Labels); err != nil {
+		return errors.New("labels validation failed: " + err.Error())
+	}
+
 	// Validate datasources
 	for i, ds := range t.Datasources {
 		if err := ds.Validate(); err != nil {
@@ -1,6 +1,9 @@
 package core
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrorCode represents specific error types
 type ErrorCode string
@@ -12,14 +15,23 @@ const (
 	ErrCodeTenantExists   ErrorCode = "TENANT_EXISTS"
 	ErrCodeTenantInvalid  ErrorCode = "TENANT_INVALID"
 
+	// Template related errors
+	ErrCodeTemplateNotFound ErrorCode = "TEMPLATE_NOT_FOUND"
+
 	// Database related errors
+	// Lost or refused connections. The backends keep the driver error as the cause but
+	// leave the DSN or URL out of the error, since it may embed credentials.
 	ErrCodeDatabaseConnection ErrorCode = "DATABASE_CONNECTION"
 	ErrCodeDatabaseQuery      ErrorCode = "DATABASE_QUERY"
 	ErrCodeDatabaseTimeout    ErrorCode = "DATABASE_TIMEOUT"
+	// Serialization failures and deadlocks; the operation can be retried as a whole
+	ErrCodeDatabaseSerialization ErrorCode = "DATABASE_SERIALIZATION"
 
 	// Cache related errors
+	// Reported like ErrCodeDatabaseConnection
 	ErrCodeCacheConnection ErrorCode = "CACHE_CONNECTION"
 	ErrCodeCacheTimeout    ErrorCode = "CACHE_TIMEOUT"
+	ErrCodeCacheOperation  ErrorCode = "CACHE_OPERATION"
 
 	// Configuration errors
 	ErrCodeConfigInvalid ErrorCode = "CONFIG_INVALID"
@@ -28,6 +40,9 @@ const (
 	// Validation errors
 	ErrCodeValidationFailed ErrorCode = "VALIDATION_FAILED"
 
+	// Storage errors
+	ErrCodeReadOnly ErrorCode = "READ_ONLY"
+
 	// Internal errors
 	ErrCodeInternal ErrorCode = "INTERNAL_ERROR"
 )
@@ -53,6 +68,47 @@ func (e *MultitenantError) Unwrap() error {
 	return e.Cause
 }
 
+// Is reports whether target is a *MultitenantError with the same code, or the legacy
+// TenantNotFoundError/TenantInactiveError matching this error's code. This lets callers
+// use errors.Is(err, core.NewError(core.ErrCodeTenantExists, "")) or
+// errors.Is(err, core.TenantNotFoundError{}) regardless of how the error was wrapped.
+func (e *MultitenantError) Is(target error) bool {
+	switch t := target.(type) {
+	case *MultitenantError:
+		return t != nil && t.Code == e.Code
+	case TenantNotFoundError:
+		return e.Code == ErrCodeTenantNotFound
+	case TenantInactiveError:
+		return e.Code == ErrCodeTenantInactive
+	default:
+		return false
+	}
+}
+
+// As fills the legacy TenantNotFoundError and TenantInactiveError types, so existing
+// errors.As checks keep working against structured errors
+func (e *MultitenantError) As(target interface{}) bool {
+	switch t := target.(type) {
+	case *TenantNotFoundError:
+		if e.Code == ErrCodeTenantNotFound {
+			t.Name = e.tenantName()
+			return true
+		}
+	case *TenantInactiveError:
+		if e.Code == ErrCodeTenantInactive {
+			t.Name = e.tenantName()
+			return true
+		}
+	}
+	return false
+}
+
+// tenantName returns the tenant_name detail, if any
+func (e *MultitenantError) tenantName() string {
+	name, _ := e.Details["tenant_name"].(string)
+	return name
+}
+
 // WithDetail adds a detail to the error
 func (e *MultitenantError) WithDetail(key string, value interface{}) *MultitenantError {
 	if e.Details == nil {
@@ -87,6 +143,12 @@ func (e TenantNotFoundError) Error() string {
 	return fmt.Sprintf("tenant not found: %s", e.Name)
 }
 
+// Is matches any TenantNotFoundError, so TenantNotFoundError{} can be used with errors.Is
+func (e TenantNotFoundError) Is(target error) bool {
+	_, ok := target.(TenantNotFoundError)
+	return ok
+}
+
 // TenantInactiveError represents an error when a tenant is inactive
 type TenantInactiveError struct {
 	Name string
@@ -97,6 +159,12 @@ func (e TenantInactiveError) Error() string {
 	return fmt.Sprintf("tenant is inactive: %s", e.Name)
 }
 
+// Is matches any TenantInactiveError, so TenantInactiveError{} can be used with errors.Is
+func (e TenantInactiveError) Is(target error) bool {
+	_, ok := target.(TenantInactiveError)
+	return ok
+}
+
 // Helper functions for common errors
 
 // ErrTenantNotFound creates a tenant not found error
@@ -105,6 +173,22 @@ func ErrTenantNotFound(name string) *MultitenantError {
 		WithDetail("tenant_name", name)
 }
 
+// ErrCachedNotFound creates the tenant not found error returned for names a cache
+// remembers as not existing
+func ErrCachedNotFound(name string) *MultitenantError {
+	return ErrTenantNotFound(name).WithDetail("cached", true)
+}
+
+// IsCachedNotFound reports whether err is a not found result served from a negative cache entry
+func IsCachedNotFound(err error) bool {
+	var mtErr *MultitenantError
+	if !errors.As(err, &mtErr) || mtErr.Code != ErrCodeTenantNotFound {
+		return false
+	}
+	cached, _ := mtErr.Details["cached"].(bool)
+	return cached
+}
+
 // ErrTenantInactive creates a tenant inactive error
 func ErrTenantInactive(name string) *MultitenantError {
 	return NewError(ErrCodeTenantInactive, fmt.Sprintf("tenant is inactive: %s", name)).
@@ -124,6 +208,12 @@ func ErrTenantInvalid(name string, reason string) *MultitenantError {
 		WithDetail("reason", reason)
 }
 
+// ErrTemplateNotFound creates a template not found error
+func ErrTemplateNotFound(name string) *MultitenantError {
+	return NewError(ErrCodeTemplateNotFound, fmt.Sprintf("template not found: %s", name)).
+		WithDetail("template_name", name)
+}
+
 // ErrDatabaseConnection creates a database connection error
 func ErrDatabaseConnection(dsn string, cause error) *MultitenantError {
 	return NewError(ErrCodeDatabaseConnection, "failed to connect to database").
@@ -138,6 +228,24 @@ func ErrDatabaseQuery(query string, cause error) *MultitenantError {
 		WithCause(cause)
 }
 
+// ErrDatabaseTimeout creates a database timeout error
+func ErrDatabaseTimeout(cause error) *MultitenantError {
+	return NewError(ErrCodeDatabaseTimeout, "database operation timed out").
+		WithCause(cause)
+}
+
+// ErrDatabaseSerialization creates an error for a transaction aborted by a conflict
+func ErrDatabaseSerialization(cause error) *MultitenantError {
+	return NewError(ErrCodeDatabaseSerialization, "database transaction conflict").
+		WithCause(cause)
+}
+
+// ErrCacheTimeout creates a cache timeout error
+func ErrCacheTimeout(cause error) *MultitenantError {
+	return NewError(ErrCodeCacheTimeout, "cache operation timed out").
+		WithCause(cause)
+}
+
 // ErrCacheConnection creates a cache connection error
 func ErrCacheConnection(url string, cause error) *MultitenantError {
 	return NewError(ErrCodeCacheConnection, "failed to connect to cache").
@@ -159,18 +267,44 @@ func ErrValidationFailed(entity string, reason string) *MultitenantError {
 		WithDetail("reason", reason)
 }
 
-// IsErrorCode checks if an error has a specific error code
+// ErrReadOnly creates an error for write operations against a read-only store
+func ErrReadOnly(operation string) *MultitenantError {
+	return NewError(ErrCodeReadOnly, fmt.Sprintf("tenant registry is read-only: %s not allowed", operation)).
+		WithDetail("operation", operation)
+}
+
+// IsErrorCode checks if an error, or any error it wraps, has a specific error code
 func IsErrorCode(err error, code ErrorCode) bool {
-	if mtErr, ok := err.(*MultitenantError); ok {
-		return mtErr.Code == code
+	if err == nil {
+		return false
 	}
-	return false
+	return GetErrorCode(err) == code && (code != ErrCodeInternal || hasMultitenantError(err))
 }
 
-// GetErrorCode extracts the error code from an error
+// GetErrorCode extracts the error code from an error chain. The legacy
+// TenantNotFoundError and TenantInactiveError types map to their codes;
+// anything else is ErrCodeInternal.
 func GetErrorCode(err error) ErrorCode {
-	if mtErr, ok := err.(*MultitenantError); ok {
+	var mtErr *MultitenantError
+	if errors.As(err, &mtErr) {
 		return mtErr.Code
 	}
+
+	var notFound TenantNotFoundError
+	if errors.As(err, &notFound) {
+		return ErrCodeTenantNotFound
+	}
+
+	var inactive TenantInactiveError
+	if errors.As(err, &inactive) {
+		return ErrCodeTenantInactive
+	}
+
 	return ErrCodeInternal
 }
+
+// hasMultitenantError reports whether err wraps a *MultitenantError
+func hasMultitenantError(err error) bool {
+	var mtErr *MultitenantError
+	return errors.As(err, &mtErr)
+}
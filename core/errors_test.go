@@ -1,6 +1,8 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,3 +54,38 @@ func TestErrorTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestMultitenantError_ComposesWithErrorsIsAs(t *testing.T) {
+	err := fmt.Errorf("loading tenant: %w", ErrTenantNotFound("acme"))
+
+	assert.True(t, IsErrorCode(err, ErrCodeTenantNotFound))
+	assert.Equal(t, ErrCodeTenantNotFound, GetErrorCode(err))
+	assert.True(t, errors.Is(err, NewError(ErrCodeTenantNotFound, "")))
+	assert.False(t, errors.Is(err, NewError(ErrCodeTenantExists, "")))
+	assert.True(t, errors.Is(err, TenantNotFoundError{}))
+
+	var notFound TenantNotFoundError
+	assert.True(t, errors.As(err, &notFound))
+	assert.Equal(t, "acme", notFound.Name)
+
+	var inactive TenantInactiveError
+	assert.False(t, errors.As(err, &inactive))
+	assert.True(t, errors.As(ErrTenantInactive("acme"), &inactive))
+	assert.Equal(t, "acme", inactive.Name)
+}
+
+func TestGetErrorCode_LegacyAndPlainErrors(t *testing.T) {
+	assert.Equal(t, ErrCodeTenantNotFound, GetErrorCode(TenantNotFoundError{Name: "acme"}))
+	assert.Equal(t, ErrCodeTenantInactive, GetErrorCode(fmt.Errorf("wrapped: %w", TenantInactiveError{Name: "acme"})))
+	assert.True(t, errors.Is(TenantNotFoundError{Name: "acme"}, TenantNotFoundError{}))
+
+	plain := errors.New("boom")
+	assert.Equal(t, ErrCodeInternal, GetErrorCode(plain))
+	assert.False(t, IsErrorCode(plain, ErrCodeInternal))
+	assert.False(t, IsErrorCode(nil, ErrCodeInternal))
+	assert.True(t, IsErrorCode(NewError(ErrCodeInternal, "boom"), ErrCodeInternal))
+
+	cause := errors.New("i/o timeout")
+	timeout := ErrDatabaseTimeout(cause)
+	assert.True(t, errors.Is(timeout, cause))
+}
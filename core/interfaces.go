@@ -8,24 +8,87 @@ import (
 // TenantRepository defines the interface for tenant data persistence operations
 type TenantRepository interface {
 	GetByName(ctx context.Context, name string) (*Tenant, error)
+	GetByID(ctx context.Context, id string) (*Tenant, error)
 	List(ctx context.Context) ([]Tenant, error)
 	Create(ctx context.Context, tenant *Tenant) error
 	Update(ctx context.Context, tenant *Tenant) error
 	Delete(ctx context.Context, id string) error
 }
 
+// TemplateRepository defines the interface for tenant template persistence operations
+type TemplateRepository interface {
+	GetTemplate(ctx context.Context, name string) (*TenantTemplate, error)
+	ListTemplates(ctx context.Context) ([]TenantTemplate, error)
+	SaveTemplate(ctx context.Context, template *TenantTemplate) error
+	DeleteTemplate(ctx context.Context, name string) error
+}
+
 // TenantCache defines the interface for tenant caching operations
 type TenantCache interface {
 	Get(ctx context.Context, name string) (*Tenant, error)
+	GetByID(ctx context.Context, id string) (*Tenant, error)
 	Set(ctx context.Context, tenant *Tenant, ttl time.Duration) error
 	Delete(ctx context.Context, name string) error
 }
 
+// NegativeCache is implemented by caches that can remember tenant names that do not
+// exist. Get returns an error matched by IsCachedNotFound for such names until ttl
+// expires or a tenant with that name is stored or deleted.
+type NegativeCache interface {
+	SetNotFound(ctx context.Context, name string, ttl time.Duration) error
+}
+
+// CacheEntry is a cached tenant along with the time it stops being fresh. A zero
+// FreshUntil means the cache does not track freshness.
+type CacheEntry struct {
+	Tenant     *Tenant
+	FreshUntil time.Time
+}
+
+// Fresh reports whether the entry is still fresh at now
+func (e *CacheEntry) Fresh(now time.Time) bool {
+	return e.FreshUntil.IsZero() || now.Before(e.FreshUntil)
+}
+
+// EntryCache is implemented by caches that report when their entries expire. Caches
+// with a stale grace period keep entries that long after they expire: Get no longer
+// returns them, but GetEntry does, so they can be served while the repository is down.
+type EntryCache interface {
+	GetEntry(ctx context.Context, name string) (*CacheEntry, error)
+}
+
+// BatchCache is implemented by caches that can store many tenants in one round trip
+type BatchCache interface {
+	SetMany(ctx context.Context, tenants []*Tenant, ttl time.Duration) error
+}
+
+// SetMany stores tenants in one batch when cache implements BatchCache, and one by one
+// otherwise
+func SetMany(ctx context.Context, cache TenantCache, tenants []*Tenant, ttl time.Duration) error {
+	if batch, ok := cache.(BatchCache); ok {
+		return batch.SetMany(ctx, tenants, ttl)
+	}
+
+	for _, tenant := range tenants {
+		if err := cache.Set(ctx, tenant, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // TenantService defines the interface for tenant business logic operations
 type TenantService interface {
 	GetTenant(ctx context.Context, name string) (*Tenant, error)
+	GetTenantByID(ctx context.Context, id string) (*Tenant, error)
 	ListTenants(ctx context.Context) ([]Tenant, error)
 	CreateTenant(ctx context.Context, tenant *Tenant) error
 	UpdateTenant(ctx context.Context, tenant *Tenant) error
 	DeleteTenant(ctx context.Context, id string) error
 }
+
+// TenantProvisioner defines operations that create tenants from existing definitions
+type TenantProvisioner interface {
+	CreateTenantFromTemplate(ctx context.Context, name, templateName string, vars map[string]string) (*Tenant, error)
+	CloneTenant(ctx context.Context, sourceName, newName string) (*Tenant, error)
+}
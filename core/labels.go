@@ -0,0 +1,347 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MaxLabelLength is the maximum length of a label key or value
+const MaxLabelLength = 63
+
+var (
+	// labelKeyPattern allows an optional "prefix/" before the name. Keys never contain
+	// dots so they can be used as MongoDB field paths.
+	labelKeyPattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9_-]*[A-Za-z0-9])?/)?[A-Za-z0-9]([A-Za-z0-9_-]*[A-Za-z0-9])?$`)
+	// labelValuePattern matches non-empty label values; empty values are also allowed
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+)
+
+// ValidateLabelKey checks that key is a valid label key
+func ValidateLabelKey(key string) error {
+	if len(key) > MaxLabelLength || !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q: must be at most %d alphanumeric, '-' or '_' characters with an optional prefix/",
+			key, MaxLabelLength)
+	}
+	return nil
+}
+
+// ValidateLabelValue checks that value is a valid label value
+func ValidateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > MaxLabelLength || !labelValuePattern.MatchString(value) {
+		return fmt.Errorf("invalid label value %q: must be at most %d alphanumeric, '-', '_' or '.' characters",
+			value, MaxLabelLength)
+	}
+	return nil
+}
+
+// ValidateLabels checks every key and value of labels
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if err := ValidateLabelKey(key); err != nil {
+			return err
+		}
+		if err := ValidateLabelValue(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SelectorOperator is the operator of a label selector requirement
+type SelectorOperator string
+
+const (
+	SelectorEquals       SelectorOperator = "="
+	SelectorNotEquals    SelectorOperator = "!="
+	SelectorIn           SelectorOperator = "in"
+	SelectorNotIn        SelectorOperator = "notin"
+	SelectorExists       SelectorOperator = "exists"
+	SelectorDoesNotExist SelectorOperator = "!"
+)
+
+// LabelRequirement is a single requirement of a label selector. Equals and NotEquals
+// take exactly one value, In and NotIn at least one, Exists and DoesNotExist none.
+// NotEquals and NotIn also match tenants without the label.
+type LabelRequirement struct {
+	Key      string           `json:"key"`
+	Operator SelectorOperator `json:"operator"`
+	Values   []string         `json:"values,omitempty"`
+}
+
+// LabelSelector selects tenants whose labels satisfy all requirements; the empty
+// selector matches every tenant
+type LabelSelector struct {
+	Requirements []LabelRequirement `json:"requirements"`
+}
+
+// LabelLister is implemented by repositories and services that can select tenants by labels
+type LabelLister interface {
+	ListByLabels(ctx context.Context, selector LabelSelector) ([]Tenant, error)
+}
+
+// ListByLabels lists the tenants matching selector natively when repo implements
+// LabelLister, and filters the full tenant list in memory otherwise
+func ListByLabels(ctx context.Context, repo TenantRepository, selector LabelSelector) ([]Tenant, error) {
+	if lister, ok := repo.(LabelLister); ok {
+		return lister.ListByLabels(ctx, selector)
+	}
+
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+
+	tenants, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return selector.Filter(tenants), nil
+}
+
+// ParseLabelSelector parses a comma-separated selector such as
+// "tier=gold,region in (eu,us),!legacy,beta". Supported forms are key=value,
+// key==value, key!=value, key in (a,b), key notin (a,b), key and !key.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var parsed LabelSelector
+
+	terms, err := splitSelector(selector)
+	if err != nil {
+		return LabelSelector{}, err
+	}
+
+	for _, term := range terms {
+		requirement, err := parseRequirement(term)
+		if err != nil {
+			return LabelSelector{}, err
+		}
+		parsed.Requirements = append(parsed.Requirements, requirement)
+	}
+
+	if err := parsed.Validate(); err != nil {
+		return LabelSelector{}, err
+	}
+	return parsed, nil
+}
+
+// MustParseLabelSelector is like ParseLabelSelector but panics on invalid selectors
+func MustParseLabelSelector(selector string) LabelSelector {
+	parsed, err := ParseLabelSelector(selector)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// splitSelector splits selector on the commas outside parentheses
+func splitSelector(selector string) ([]string, error) {
+	var terms []string
+	depth, start := 0, 0
+
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, ErrValidationFailed("label selector", "unbalanced parentheses in "+selector)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, ErrValidationFailed("label selector", "unbalanced parentheses in "+selector)
+	}
+	terms = append(terms, selector[start:])
+
+	// An empty selector has no requirements, but empty terms between commas are errors
+	if len(terms) == 1 && strings.TrimSpace(terms[0]) == "" {
+		return nil, nil
+	}
+	for i, term := range terms {
+		terms[i] = strings.TrimSpace(term)
+		if terms[i] == "" {
+			return nil, ErrValidationFailed("label selector", "empty requirement in "+selector)
+		}
+	}
+	return terms, nil
+}
+
+// parseRequirement parses a single selector term
+func parseRequirement(term string) (LabelRequirement, error) {
+	if strings.HasPrefix(term, "!") && !strings.ContainsAny(term, "=( ") {
+		return LabelRequirement{Key: strings.TrimSpace(term[1:]), Operator: SelectorDoesNotExist}, nil
+	}
+
+	if i := strings.Index(term, "!="); i >= 0 {
+		return LabelRequirement{Key: strings.TrimSpace(term[:i]), Operator: SelectorNotEquals,
+			Values: []string{strings.TrimSpace(term[i+2:])}}, nil
+	}
+	if i := strings.Index(term, "=="); i >= 0 {
+		return LabelRequirement{Key: strings.TrimSpace(term[:i]), Operator: SelectorEquals,
+			Values: []string{strings.TrimSpace(term[i+2:])}}, nil
+	}
+	if i := strings.Index(term, "="); i >= 0 {
+		return LabelRequirement{Key: strings.TrimSpace(term[:i]), Operator: SelectorEquals,
+			Values: []string{strings.TrimSpace(term[i+1:])}}, nil
+	}
+
+	if open := strings.Index(term, "("); open >= 0 {
+		fields := strings.Fields(term[:open])
+		if len(fields) != 2 || !strings.HasSuffix(term, ")") {
+			return LabelRequirement{}, ErrValidationFailed("label selector", "invalid requirement: "+term)
+		}
+
+		operator := SelectorOperator(fields[1])
+		if operator != SelectorIn && operator != SelectorNotIn {
+			return LabelRequirement{}, ErrValidationFailed("label selector", "unknown operator in requirement: "+term)
+		}
+
+		var values []string
+		if list := strings.TrimSpace(term[open+1 : len(term)-1]); list != "" {
+			for _, value := range strings.Split(list, ",") {
+				values = append(values, strings.TrimSpace(value))
+			}
+		}
+		return LabelRequirement{Key: fields[0], Operator: operator, Values: values}, nil
+	}
+
+	if strings.ContainsAny(term, " ()") {
+		return LabelRequirement{}, ErrValidationFailed("label selector", "invalid requirement: "+term)
+	}
+	return LabelRequirement{Key: term, Operator: SelectorExists}, nil
+}
+
+// Validate checks the keys, operators and values of every requirement
+func (s LabelSelector) Validate() error {
+	for _, requirement := range s.Requirements {
+		if err := requirement.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the key, operator and values of the requirement
+func (r LabelRequirement) Validate() error {
+	if err := ValidateLabelKey(r.Key); err != nil {
+		return ErrValidationFailed("label selector", err.Error()).WithCause(err)
+	}
+
+	switch r.Operator {
+	case SelectorEquals, SelectorNotEquals:
+		if len(r.Values) != 1 {
+			return ErrValidationFailed("label selector", fmt.Sprintf("%s on %s requires exactly one value", r.Operator, r.Key))
+		}
+	case SelectorIn, SelectorNotIn:
+		if len(r.Values) == 0 {
+			return ErrValidationFailed("label selector", fmt.Sprintf("%s on %s requires at least one value", r.Operator, r.Key))
+		}
+	case SelectorExists, SelectorDoesNotExist:
+		if len(r.Values) != 0 {
+			return ErrValidationFailed("label selector", fmt.Sprintf("%s on %s takes no values", r.Operator, r.Key))
+		}
+	default:
+		return ErrValidationFailed("label selector", fmt.Sprintf("unknown selector operator: %q", r.Operator))
+	}
+
+	for _, value := range r.Values {
+		if err := ValidateLabelValue(value); err != nil {
+			return ErrValidationFailed("label selector", err.Error()).WithCause(err)
+		}
+	}
+	return nil
+}
+
+// Empty reports whether the selector has no requirements and matches every tenant
+func (s LabelSelector) Empty() bool {
+	return len(s.Requirements) == 0
+}
+
+// String formats the selector in the syntax accepted by ParseLabelSelector
+func (s LabelSelector) String() string {
+	terms := make([]string, len(s.Requirements))
+	for i, requirement := range s.Requirements {
+		terms[i] = requirement.String()
+	}
+	return strings.Join(terms, ",")
+}
+
+// String formats the requirement in the syntax accepted by ParseLabelSelector
+func (r LabelRequirement) String() string {
+	switch r.Operator {
+	case SelectorEquals, SelectorNotEquals:
+		return r.Key + string(r.Operator) + strings.Join(r.Values, "")
+	case SelectorIn, SelectorNotIn:
+		values := append([]string(nil), r.Values...)
+		sort.Strings(values)
+		return r.Key + " " + string(r.Operator) + " (" + strings.Join(values, ",") + ")"
+	case SelectorDoesNotExist:
+		return "!" + r.Key
+	default:
+		return r.Key
+	}
+}
+
+// Filter returns the tenants matching the selector, reusing the backing array of tenants
+func (s LabelSelector) Filter(tenants []Tenant) []Tenant {
+	matching := tenants[:0]
+	for i := range tenants {
+		if s.Matches(tenants[i].Labels) {
+			matching = append(matching, tenants[i])
+		}
+	}
+	return matching
+}
+
+// Matches evaluates the selector against labels
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, requirement := range s.Requirements {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches evaluates the requirement against labels
+func (r LabelRequirement) Matches(labels map[string]string) bool {
+	value, found := labels[r.Key]
+
+	switch r.Operator {
+	case SelectorExists:
+		return found
+	case SelectorDoesNotExist:
+		return !found
+	case SelectorEquals:
+		return found && len(r.Values) == 1 && value == r.Values[0]
+	case SelectorNotEquals:
+		return !found || len(r.Values) != 1 || value != r.Values[0]
+	case SelectorIn:
+		return found && containsString(r.Values, value)
+	case SelectorNotIn:
+		return !found || !containsString(r.Values, value)
+	default:
+		return false
+	}
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
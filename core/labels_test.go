@@ -0,0 +1,104 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, ValidateLabels(map[string]string{
+		"tier":            "gold",
+		"acme/team":       "billing_v2",
+		"feature-flag":    "",
+		"version":         "1.2.3",
+		"region_eu-west1": "eu-west-1",
+	}))
+
+	invalid := []map[string]string{
+		{"": "x"},
+		{"bad.key": "x"},
+		{"-tier": "x"},
+		{"a/b/c": "x"},
+		{"tier": "gold silver"},
+		{"tier": "-gold"},
+		{"tier": string(make([]byte, MaxLabelLength+1))},
+	}
+	for _, labels := range invalid {
+		assert.Error(t, ValidateLabels(labels), "%v", labels)
+	}
+
+	tenant := NewTenant("acme")
+	tenant.Labels = map[string]string{"bad key": "x"}
+	assert.Error(t, tenant.Validate())
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	selector, err := ParseLabelSelector(" tier = gold, region in (eu, us),env!=dev,zone notin (a),beta,!legacy,plan==pro")
+	require.NoError(t, err)
+	assert.Equal(t, []LabelRequirement{
+		{Key: "tier", Operator: SelectorEquals, Values: []string{"gold"}},
+		{Key: "region", Operator: SelectorIn, Values: []string{"eu", "us"}},
+		{Key: "env", Operator: SelectorNotEquals, Values: []string{"dev"}},
+		{Key: "zone", Operator: SelectorNotIn, Values: []string{"a"}},
+		{Key: "beta", Operator: SelectorExists},
+		{Key: "legacy", Operator: SelectorDoesNotExist},
+		{Key: "plan", Operator: SelectorEquals, Values: []string{"pro"}},
+	}, selector.Requirements)
+	assert.Equal(t, "tier=gold,region in (eu,us),env!=dev,zone notin (a),beta,!legacy,plan=pro", selector.String())
+
+	empty, err := ParseLabelSelector("  ")
+	require.NoError(t, err)
+	assert.True(t, empty.Empty())
+
+	for _, invalid := range []string{
+		"tier=gold,",
+		"region in (eu",
+		"region between (a,b)",
+		"region in ()",
+		"bad.key=x",
+		"tier=gold silver",
+		"tier gold",
+	} {
+		_, err := ParseLabelSelector(invalid)
+		assert.True(t, IsErrorCode(err, ErrCodeValidationFailed), "%q", invalid)
+	}
+}
+
+func TestLabelSelector_Matches(t *testing.T) {
+	labels := map[string]string{"tier": "gold", "region": "eu", "legacy": ""}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"tier=gold", true},
+		{"tier=silver", false},
+		{"tier!=silver", true},
+		{"owner!=acme", true},
+		{"region in (eu,us)", true},
+		{"region in (us)", false},
+		{"region notin (us)", true},
+		{"owner notin (acme)", true},
+		{"legacy", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!legacy", false},
+		{"tier=gold,region=us", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			assert.Equal(t, tt.want, MustParseLabelSelector(tt.selector).Matches(labels))
+		})
+	}
+
+	gold := NewTenant("gold")
+	gold.Labels = map[string]string{"tier": "gold"}
+	plain := NewTenant("plain")
+	tenants := MustParseLabelSelector("!tier").Filter([]Tenant{*gold, *plain})
+	require.Len(t, tenants, 1)
+	assert.Equal(t, "plain", tenants[0].Name)
+}
@@ -0,0 +1,22 @@
+package core
+
+import "log/slog"
+
+// Log attribute keys shared by every subsystem. The tenant keys match the span attributes
+// set by tenantcontext.PropagateToSpan.
+const (
+	LogKeyComponent  = "component"
+	LogKeyTenantID   = "tenant.id"
+	LogKeyTenantName = "tenant.name"
+	LogKeyRole       = "role"
+	LogKeyError      = "error"
+)
+
+// ComponentLogger returns logger, or slog.Default() when it is nil, with the component
+// attribute set to component
+func ComponentLogger(logger *slog.Logger, component string) *slog.Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return logger.With(LogKeyComponent, component)
+}
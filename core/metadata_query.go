@@ -0,0 +1,331 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// MetadataOperator is the comparison applied by a metadata condition
+type MetadataOperator string
+
+const (
+	MetadataOpEq     MetadataOperator = "eq"
+	MetadataOpNe     MetadataOperator = "ne"
+	MetadataOpIn     MetadataOperator = "in"
+	MetadataOpExists MetadataOperator = "exists"
+	MetadataOpGt     MetadataOperator = "gt"
+	MetadataOpGte    MetadataOperator = "gte"
+	MetadataOpLt     MetadataOperator = "lt"
+	MetadataOpLte    MetadataOperator = "lte"
+)
+
+// metadataPathSegment restricts path segments to names that are safe as JSON keys and
+// MongoDB field names
+var metadataPathSegment = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// MetadataCondition compares the metadata value at Path, a dot-separated path such as
+// "billing.plan", with Value (or Values for MetadataOpIn)
+type MetadataCondition struct {
+	Path     string           `json:"path"`
+	Operator MetadataOperator `json:"operator"`
+	Value    interface{}      `json:"value,omitempty"`
+	Values   []interface{}    `json:"values,omitempty"`
+}
+
+// MetadataQuery selects tenants whose metadata matches all conditions. Comparisons only
+// match values of the same kind: numbers with numbers and strings with strings.
+type MetadataQuery struct {
+	Conditions []MetadataCondition `json:"conditions"`
+}
+
+// MetadataQuerier is implemented by repositories and services that can select tenants by metadata
+type MetadataQuerier interface {
+	FindByMetadata(ctx context.Context, query MetadataQuery) ([]Tenant, error)
+}
+
+// FindByMetadata queries repo natively when it implements MetadataQuerier, and filters
+// the full tenant list in memory otherwise
+func FindByMetadata(ctx context.Context, repo TenantRepository, query MetadataQuery) ([]Tenant, error) {
+	if querier, ok := repo.(MetadataQuerier); ok {
+		return querier.FindByMetadata(ctx, query)
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	tenants, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.Filter(tenants), nil
+}
+
+// NewMetadataQuery creates a query matching all conditions
+func NewMetadataQuery(conditions ...MetadataCondition) MetadataQuery {
+	return MetadataQuery{Conditions: conditions}
+}
+
+// MetadataEquals matches tenants whose value at path equals value
+func MetadataEquals(path string, value interface{}) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpEq, Value: value}
+}
+
+// MetadataNotEquals matches tenants whose value at path is missing or differs from value
+func MetadataNotEquals(path string, value interface{}) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpNe, Value: value}
+}
+
+// MetadataIn matches tenants whose value at path equals one of values
+func MetadataIn(path string, values ...interface{}) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpIn, Values: values}
+}
+
+// MetadataExists matches tenants that have a value at path
+func MetadataExists(path string) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpExists}
+}
+
+// MetadataGreaterThan matches tenants whose value at path is greater than value
+func MetadataGreaterThan(path string, value interface{}) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpGt, Value: value}
+}
+
+// MetadataGreaterOrEqual matches tenants whose value at path is greater than or equal to value
+func MetadataGreaterOrEqual(path string, value interface{}) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpGte, Value: value}
+}
+
+// MetadataLessThan matches tenants whose value at path is less than value
+func MetadataLessThan(path string, value interface{}) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpLt, Value: value}
+}
+
+// MetadataLessOrEqual matches tenants whose value at path is less than or equal to value
+func MetadataLessOrEqual(path string, value interface{}) MetadataCondition {
+	return MetadataCondition{Path: path, Operator: MetadataOpLte, Value: value}
+}
+
+// Segments splits the condition path into its keys
+func (c MetadataCondition) Segments() []string {
+	return strings.Split(c.Path, ".")
+}
+
+// Validate checks the path, operator and operands of every condition
+func (q MetadataQuery) Validate() error {
+	for _, condition := range q.Conditions {
+		if err := condition.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the path, operator and operands of the condition
+func (c MetadataCondition) Validate() error {
+	for _, segment := range c.Segments() {
+		if !metadataPathSegment.MatchString(segment) {
+			return ErrValidationFailed("metadata query", fmt.Sprintf("invalid metadata path: %q", c.Path))
+		}
+	}
+
+	switch c.Operator {
+	case MetadataOpEq, MetadataOpNe:
+		if c.Value == nil {
+			return ErrValidationFailed("metadata query", fmt.Sprintf("%s on %s requires a value", c.Operator, c.Path))
+		}
+	case MetadataOpIn:
+		if len(c.Values) == 0 {
+			return ErrValidationFailed("metadata query", fmt.Sprintf("in on %s requires at least one value", c.Path))
+		}
+	case MetadataOpExists:
+	case MetadataOpGt, MetadataOpGte, MetadataOpLt, MetadataOpLte:
+		if _, ok := toFloat(c.Value); !ok {
+			if _, ok := c.Value.(string); !ok {
+				return ErrValidationFailed("metadata query",
+					fmt.Sprintf("%s on %s requires a number or string", c.Operator, c.Path))
+			}
+		}
+	default:
+		return ErrValidationFailed("metadata query", fmt.Sprintf("unknown metadata operator: %q", c.Operator))
+	}
+
+	return nil
+}
+
+// Filter returns the tenants matching the query, reusing the backing array of tenants
+func (q MetadataQuery) Filter(tenants []Tenant) []Tenant {
+	matching := tenants[:0]
+	for i := range tenants {
+		if q.Matches(&tenants[i]) {
+			matching = append(matching, tenants[i])
+		}
+	}
+	return matching
+}
+
+// Matches evaluates the query against the metadata of tenant; backends without a native
+// metadata filter use it to filter in memory
+func (q MetadataQuery) Matches(tenant *Tenant) bool {
+	for _, condition := range q.Conditions {
+		if !condition.Matches(tenant.Metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches evaluates the condition against metadata
+func (c MetadataCondition) Matches(metadata map[string]interface{}) bool {
+	value, found := lookupMetadata(metadata, c.Segments())
+
+	switch c.Operator {
+	case MetadataOpExists:
+		return found
+	case MetadataOpEq:
+		return found && metadataEqual(value, c.Value)
+	case MetadataOpNe:
+		return !found || !metadataEqual(value, c.Value)
+	case MetadataOpIn:
+		if !found {
+			return false
+		}
+		for _, candidate := range c.Values {
+			if metadataEqual(value, candidate) {
+				return true
+			}
+		}
+		return false
+	case MetadataOpGt, MetadataOpGte, MetadataOpLt, MetadataOpLte:
+		if !found {
+			return false
+		}
+		cmp, ok := compareMetadata(value, c.Value)
+		if !ok {
+			return false
+		}
+		switch c.Operator {
+		case MetadataOpGt:
+			return cmp > 0
+		case MetadataOpGte:
+			return cmp >= 0
+		case MetadataOpLt:
+			return cmp < 0
+		default:
+			return cmp <= 0
+		}
+	default:
+		return false
+	}
+}
+
+// lookupMetadata follows path through nested metadata maps
+func lookupMetadata(metadata map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = metadata
+	for _, key := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// metadataEqual compares two metadata values, treating all numeric types alike
+func metadataEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(normalizeMetadata(a), normalizeMetadata(b))
+}
+
+// compareMetadata orders two numbers or two strings; other combinations are not comparable
+func compareMetadata(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	x, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	y, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// normalizeMetadata round-trips composite values through JSON so values decoded by
+// different backends compare equal
+func normalizeMetadata(value interface{}) interface{} {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		var normalized interface{}
+		if err := json.Unmarshal(data, &normalized); err != nil {
+			return value
+		}
+		return normalized
+	default:
+		return value
+	}
+}
+
+// toFloat converts any numeric metadata value to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
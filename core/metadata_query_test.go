@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataCondition_Matches(t *testing.T) {
+	metadata := map[string]interface{}{
+		"region": "eu",
+		"seats":  25,
+		"billing": map[string]interface{}{
+			"plan":  "pro",
+			"since": "2023-05-01",
+			"limit": json.Number("100"),
+		},
+		"tags": []interface{}{"beta"},
+	}
+
+	tests := []struct {
+		name      string
+		condition MetadataCondition
+		want      bool
+	}{
+		{"equals", MetadataEquals("region", "eu"), true},
+		{"equals other", MetadataEquals("region", "us"), false},
+		{"equals nested", MetadataEquals("billing.plan", "pro"), true},
+		{"equals across numeric types", MetadataEquals("seats", 25.0), true},
+		{"equals composite", MetadataEquals("tags", []string{"beta"}), true},
+		{"not equals", MetadataNotEquals("region", "us"), true},
+		{"not equals missing", MetadataNotEquals("owner", "acme"), true},
+		{"in", MetadataIn("billing.plan", "pro", "enterprise"), true},
+		{"in missing", MetadataIn("billing.plan", "free"), false},
+		{"exists", MetadataExists("billing.since"), true},
+		{"exists missing", MetadataExists("billing.owner"), false},
+		{"exists through scalar", MetadataExists("region.code"), false},
+		{"greater than", MetadataGreaterThan("seats", 10), true},
+		{"greater or equal", MetadataGreaterOrEqual("billing.limit", 100), true},
+		{"less than", MetadataLessThan("seats", 25), false},
+		{"less or equal strings", MetadataLessOrEqual("billing.since", "2024-01-01"), true},
+		{"comparison across kinds", MetadataGreaterThan("region", 1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.condition.Validate())
+			assert.Equal(t, tt.want, tt.condition.Matches(metadata))
+		})
+	}
+}
+
+func TestMetadataQuery_Validate(t *testing.T) {
+	assert.NoError(t, NewMetadataQuery().Validate())
+	assert.NoError(t, NewMetadataQuery(MetadataEquals("billing.plan", "pro")).Validate())
+
+	invalid := []MetadataCondition{
+		MetadataEquals("", "eu"),
+		MetadataEquals("billing..plan", "pro"),
+		MetadataEquals("$where", "1"),
+		MetadataEquals("region", nil),
+		MetadataIn("region"),
+		MetadataGreaterThan("seats", true),
+		{Path: "region", Operator: "like", Value: "e%"},
+	}
+	for _, condition := range invalid {
+		err := NewMetadataQuery(condition).Validate()
+		assert.True(t, IsErrorCode(err, ErrCodeValidationFailed), "%+v", condition)
+	}
+}
+
+// listRepository only supports List, so FindByMetadata filters in memory
+type listRepository struct {
+	TenantRepository
+	tenants []Tenant
+}
+
+func (r *listRepository) List(ctx context.Context) ([]Tenant, error) {
+	return append([]Tenant(nil), r.tenants...), nil
+}
+
+func TestFindByMetadata_FiltersList(t *testing.T) {
+	eu := NewTenant("eu")
+	eu.Metadata["region"] = "eu"
+	us := NewTenant("us")
+	us.Metadata["region"] = "us"
+	repo := &listRepository{tenants: []Tenant{*eu, *us}}
+
+	tenants, err := FindByMetadata(context.Background(), repo, NewMetadataQuery(MetadataEquals("region", "eu")))
+	require.NoError(t, err)
+	require.Len(t, tenants, 1)
+	assert.Equal(t, "eu", tenants[0].Name)
+}
@@ -0,0 +1,86 @@
+package reconcile
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/transfer"
+)
+
+// ActionType represents the kind of change applied to a tenant
+type ActionType string
+
+const (
+	ActionCreate ActionType = "create"
+	ActionUpdate ActionType = "update"
+	ActionDelete ActionType = "delete"
+)
+
+// Action is a single change required to reach the desired state
+type Action struct {
+	Type    ActionType
+	Name    string
+	Changes []string
+	// Desired is the tenant to write for create and update actions
+	Desired *core.Tenant
+	// Current is the registry state for update and delete actions
+	Current *core.Tenant
+}
+
+// Plan is the ordered list of actions produced by diffing desired and current state
+type Plan struct {
+	Actions []Action
+	// Unmanaged lists registry tenants missing from the desired state that are kept because pruning is off
+	Unmanaged []string
+}
+
+// Empty reports whether the plan has nothing to apply
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Count returns the number of actions of the given type
+func (p *Plan) Count(actionType ActionType) int {
+	count := 0
+	for _, action := range p.Actions {
+		if action.Type == actionType {
+			count++
+		}
+	}
+	return count
+}
+
+// Print writes a human readable description of the plan to w
+func (p *Plan) Print(w io.Writer) {
+	if p.Empty() {
+		fmt.Fprintln(w, "No changes. The tenant registry matches the desired state.")
+	}
+
+	for _, action := range p.Actions {
+		switch action.Type {
+		case ActionCreate:
+			fmt.Fprintf(w, "+ create tenant %s\n", action.Name)
+		case ActionUpdate:
+			fmt.Fprintf(w, "~ update tenant %s\n", action.Name)
+		case ActionDelete:
+			fmt.Fprintf(w, "- delete tenant %s\n", action.Name)
+		}
+
+		for _, change := range action.Changes {
+			fmt.Fprintf(w, "    %s\n", change)
+		}
+	}
+
+	for _, name := range p.Unmanaged {
+		fmt.Fprintf(w, "! tenant %s is not in the desired state (use prune to delete it)\n", name)
+	}
+
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete))
+}
+
+// describeDatasource renders a datasource for plan output without leaking passwords
+func describeDatasource(ds core.Datasource) string {
+	return fmt.Sprintf("%s %s (pool_size=%d)", ds.Role, transfer.RedactDSN(ds.DSN), ds.PoolSize)
+}
@@ -0,0 +1,302 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/transfer"
+)
+
+// Options controls how the desired state is reconciled into the registry
+type Options struct {
+	// Prune deletes registry tenants that are missing from the desired state
+	Prune bool
+	// DryRun computes and prints the plan without applying it
+	DryRun bool
+	// EncryptionKey decrypts DSNs encrypted with transfer.EncryptDSN
+	EncryptionKey []byte
+}
+
+// Result summarizes the actions applied by the reconciler
+type Result struct {
+	Created []string
+	Updated []string
+	Deleted []string
+}
+
+// Reconciler diffs a desired-state document against a tenant repository
+type Reconciler struct {
+	repo    core.TenantRepository
+	options Options
+}
+
+// NewReconciler creates a reconciler for the given repository
+func NewReconciler(repo core.TenantRepository, options Options) *Reconciler {
+	return &Reconciler{
+		repo:    repo,
+		options: options,
+	}
+}
+
+// Reconcile plans the changes for doc, prints the plan to out and applies it unless DryRun is set
+func (r *Reconciler) Reconcile(ctx context.Context, doc *transfer.Document, out io.Writer) (*Plan, error) {
+	plan, err := r.Plan(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if out != nil {
+		plan.Print(out)
+	}
+
+	if r.options.DryRun || plan.Empty() {
+		return plan, nil
+	}
+
+	if _, err := r.Apply(ctx, plan); err != nil {
+		return plan, err
+	}
+
+	return plan, nil
+}
+
+// ReconcileFile is a convenience wrapper that reads the desired state from a JSON or YAML file
+func ReconcileFile(ctx context.Context, repo core.TenantRepository, path string, options Options, out io.Writer) (*Plan, error) {
+	doc, err := transfer.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReconciler(repo, options).Reconcile(ctx, doc, out)
+}
+
+// Plan computes the actions needed to make the registry match doc
+func (r *Reconciler) Plan(ctx context.Context, doc *transfer.Document) (*Plan, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	current, err := r.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*core.Tenant, len(current))
+	byID := make(map[string]*core.Tenant, len(current))
+	for i := range current {
+		byName[current[i].Name] = &current[i]
+		byID[current[i].ID] = &current[i]
+	}
+
+	plan := &Plan{}
+	desiredNames := make(map[string]bool, len(doc.Tenants))
+
+	for _, spec := range doc.Tenants {
+		desiredNames[spec.Name] = true
+
+		desired := spec.ToTenant()
+		if err := transfer.RestoreSecrets(desired, r.options.EncryptionKey, false); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", spec.Name, err)
+		}
+
+		existing, ok := byName[spec.Name]
+		if !ok {
+			if owner, taken := byID[desired.ID]; taken {
+				return nil, core.ErrValidationFailed("document",
+					fmt.Sprintf("tenant %s uses ID %s which belongs to tenant %s", spec.Name, desired.ID, owner.Name))
+			}
+			if err := desired.Validate(); err != nil {
+				return nil, fmt.Errorf("tenant %s: %w", spec.Name, err)
+			}
+
+			changes := make([]string, 0, len(desired.Datasources))
+			for _, ds := range desired.Datasources {
+				changes = append(changes, "+ datasource "+describeDatasource(ds))
+			}
+
+			plan.Actions = append(plan.Actions, Action{
+				Type:    ActionCreate,
+				Name:    spec.Name,
+				Changes: changes,
+				Desired: desired,
+			})
+			continue
+		}
+
+		desired.ID = existing.ID
+		desired.CreatedAt = existing.CreatedAt
+		changes := diffTenant(desired, existing)
+		if len(changes) == 0 {
+			continue
+		}
+
+		if err := desired.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", spec.Name, err)
+		}
+
+		plan.Actions = append(plan.Actions, Action{
+			Type:    ActionUpdate,
+			Name:    spec.Name,
+			Changes: changes,
+			Desired: desired,
+			Current: existing,
+		})
+	}
+
+	var missing []string
+	for name := range byName {
+		if !desiredNames[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, name := range missing {
+		if !r.options.Prune {
+			plan.Unmanaged = append(plan.Unmanaged, name)
+			continue
+		}
+
+		plan.Actions = append(plan.Actions, Action{
+			Type:    ActionDelete,
+			Name:    name,
+			Current: byName[name],
+		})
+	}
+
+	return plan, nil
+}
+
+// Apply executes the plan against the repository, stopping at the first failure
+func (r *Reconciler) Apply(ctx context.Context, plan *Plan) (*Result, error) {
+	result := &Result{}
+
+	for _, action := range plan.Actions {
+		switch action.Type {
+		case ActionCreate:
+			if err := r.repo.Create(ctx, action.Desired); err != nil {
+				return result, fmt.Errorf("failed to create tenant %s: %w", action.Name, err)
+			}
+			result.Created = append(result.Created, action.Name)
+		case ActionUpdate:
+			if err := r.repo.Update(ctx, action.Desired); err != nil {
+				return result, fmt.Errorf("failed to update tenant %s: %w", action.Name, err)
+			}
+			result.Updated = append(result.Updated, action.Name)
+		case ActionDelete:
+			if err := r.repo.Delete(ctx, action.Current.ID); err != nil {
+				return result, fmt.Errorf("failed to delete tenant %s: %w", action.Name, err)
+			}
+			result.Deleted = append(result.Deleted, action.Name)
+		}
+	}
+
+	return result, nil
+}
+
+// diffTenant lists the differences between desired and current, reusing datasource identities in desired
+func diffTenant(desired, current *core.Tenant) []string {
+	var changes []string
+
+	if desired.IsActive != current.IsActive {
+		changes = append(changes, fmt.Sprintf("is_active: %t -> %t", current.IsActive, desired.IsActive))
+	}
+
+	if !sameJSON(desired.Metadata, current.Metadata) {
+		changes = append(changes, "metadata changed")
+	}
+
+	if !sameLabels(desired.Labels, current.Labels) {
+		changes = append(changes, "labels changed")
+	}
+
+	return append(changes, diffDatasources(desired, current)...)
+}
+
+// diffDatasources matches datasources by ID, then by role in declaration order
+func diffDatasources(desired, current *core.Tenant) []string {
+	var changes []string
+	matched := make([]bool, len(current.Datasources))
+
+	match := func(ds *core.Datasource) int {
+		for i, cur := range current.Datasources {
+			if !matched[i] && cur.ID == ds.ID {
+				return i
+			}
+		}
+		for i, cur := range current.Datasources {
+			if !matched[i] && cur.Role == ds.Role {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i := range desired.Datasources {
+		ds := &desired.Datasources[i]
+		ds.TenantID = current.ID
+
+		idx := match(ds)
+		if idx < 0 {
+			changes = append(changes, "+ datasource "+describeDatasource(*ds))
+			continue
+		}
+		matched[idx] = true
+
+		cur := current.Datasources[idx]
+		ds.ID = cur.ID
+		ds.CreatedAt = cur.CreatedAt
+
+		if ds.DSN != cur.DSN || ds.PoolSize != cur.PoolSize || !sameJSON(ds.Metadata, cur.Metadata) {
+			changes = append(changes, fmt.Sprintf("~ datasource %s -> %s", describeDatasource(cur), describeDatasource(*ds)))
+		}
+	}
+
+	for i, cur := range current.Datasources {
+		if !matched[i] {
+			changes = append(changes, "- datasource "+describeDatasource(cur))
+		}
+	}
+
+	return changes
+}
+
+// sameJSON compares two values by their JSON representation, treating nil and empty maps as equal
+func sameJSON(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// sameLabels compares label sets, treating nil and empty alike
+func sameLabels(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(value map[string]interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
@@ -0,0 +1,123 @@
+package resilience
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Cache decorates a core.TenantCache with retries. Cache writes overwrite or remove a
+// whole entry, so every operation is safe to retry.
+type Cache struct {
+	cache   core.TenantCache
+	retrier *Retrier
+}
+
+// NewCache wraps cache with the retry policy
+func NewCache(cache core.TenantCache, policy Policy) *Cache {
+	return &Cache{
+		cache:   cache,
+		retrier: NewRetrier(policy),
+	}
+}
+
+// Unwrap returns the decorated cache
+func (c *Cache) Unwrap() core.TenantCache {
+	return c.cache
+}
+
+func (c *Cache) Get(ctx context.Context, name string) (*core.Tenant, error) {
+	return retryValue(ctx, c.retrier, func(ctx context.Context) (*core.Tenant, error) {
+		return c.cache.Get(ctx, name)
+	})
+}
+
+func (c *Cache) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return retryValue(ctx, c.retrier, func(ctx context.Context) (*core.Tenant, error) {
+		return c.cache.GetByID(ctx, id)
+	})
+}
+
+func (c *Cache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	return c.retrier.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Set(ctx, tenant, ttl)
+	})
+}
+
+// SetMany stores tenants in one batch when the decorated cache supports batches, and
+// one by one otherwise
+func (c *Cache) SetMany(ctx context.Context, tenants []*core.Tenant, ttl time.Duration) error {
+	return c.retrier.Do(ctx, func(ctx context.Context) error {
+		return core.SetMany(ctx, c.cache, tenants, ttl)
+	})
+}
+
+func (c *Cache) Delete(ctx context.Context, name string) error {
+	return c.retrier.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Delete(ctx, name)
+	})
+}
+
+// DeleteAll clears the decorated cache when it supports clearing, and is a no-op otherwise
+func (c *Cache) DeleteAll(ctx context.Context) error {
+	cache, ok := c.cache.(interface {
+		DeleteAll(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+	return c.retrier.Do(ctx, cache.DeleteAll)
+}
+
+// DeleteLocal clears the in-process entries of the decorated cache when it keeps any,
+// and is a no-op otherwise. Local clears cannot fail transiently, so they are not retried.
+func (c *Cache) DeleteLocal(ctx context.Context) error {
+	cache, ok := c.cache.(interface {
+		DeleteLocal(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+	return cache.DeleteLocal(ctx)
+}
+
+// DeleteLocalName evicts an in-process entry of the decorated cache when it keeps any,
+// and is a no-op otherwise
+func (c *Cache) DeleteLocalName(ctx context.Context, name string) error {
+	cache, ok := c.cache.(interface {
+		DeleteLocalName(ctx context.Context, name string) error
+	})
+	if !ok {
+		return nil
+	}
+	return cache.DeleteLocalName(ctx, name)
+}
+
+// SetNotFound forwards to the decorated cache when it supports negative entries, and
+// is a no-op otherwise
+func (c *Cache) SetNotFound(ctx context.Context, name string, ttl time.Duration) error {
+	cache, ok := c.cache.(core.NegativeCache)
+	if !ok {
+		return nil
+	}
+	return c.retrier.Do(ctx, func(ctx context.Context) error {
+		return cache.SetNotFound(ctx, name, ttl)
+	})
+}
+
+// GetEntry forwards to the decorated cache when it reports freshness, and otherwise
+// returns the tenant from Get with an unknown freshness
+func (c *Cache) GetEntry(ctx context.Context, name string) (*core.CacheEntry, error) {
+	cache, ok := c.cache.(core.EntryCache)
+	if !ok {
+		tenant, err := c.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return &core.CacheEntry{Tenant: tenant}, nil
+	}
+	return retryValue(ctx, c.retrier, func(ctx context.Context) (*core.CacheEntry, error) {
+		return cache.GetEntry(ctx, name)
+	})
+}
@@ -0,0 +1,24 @@
+package resilience
+
+import "github.com/victorximenis/multitenant/core"
+
+// Compile-time check to ensure Repository implements core.TenantRepository interface
+var _ core.TenantRepository = (*Repository)(nil)
+
+// Compile-time check to ensure Repository implements core.MetadataQuerier interface
+var _ core.MetadataQuerier = (*Repository)(nil)
+
+// Compile-time check to ensure Repository implements core.LabelLister interface
+var _ core.LabelLister = (*Repository)(nil)
+
+// Compile-time check to ensure Cache implements core.TenantCache interface
+var _ core.TenantCache = (*Cache)(nil)
+
+// Compile-time check to ensure Cache implements core.NegativeCache interface
+var _ core.NegativeCache = (*Cache)(nil)
+
+// Compile-time check to ensure Cache implements core.EntryCache interface
+var _ core.EntryCache = (*Cache)(nil)
+
+// Compile-time check to ensure Cache implements core.BatchCache interface
+var _ core.BatchCache = (*Cache)(nil)
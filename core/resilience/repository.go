@@ -0,0 +1,87 @@
+package resilience
+
+import (
+	"context"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Repository decorates a core.TenantRepository with retries. Reads are always retried;
+// writes only when Policy.RetryWrites is set.
+type Repository struct {
+	repo        core.TenantRepository
+	retrier     *Retrier
+	retryWrites bool
+}
+
+// NewRepository wraps repo with the retry policy
+func NewRepository(repo core.TenantRepository, policy Policy) *Repository {
+	return &Repository{
+		repo:        repo,
+		retrier:     NewRetrier(policy),
+		retryWrites: policy.RetryWrites,
+	}
+}
+
+// Unwrap returns the decorated repository
+func (r *Repository) Unwrap() core.TenantRepository {
+	return r.repo
+}
+
+func (r *Repository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	return retryValue(ctx, r.retrier, func(ctx context.Context) (*core.Tenant, error) {
+		return r.repo.GetByName(ctx, name)
+	})
+}
+
+func (r *Repository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return retryValue(ctx, r.retrier, func(ctx context.Context) (*core.Tenant, error) {
+		return r.repo.GetByID(ctx, id)
+	})
+}
+
+func (r *Repository) List(ctx context.Context) ([]core.Tenant, error) {
+	return retryValue(ctx, r.retrier, r.repo.List)
+}
+
+// FindByMetadata retries the metadata query of the decorated repository, which is
+// filtered in memory when the repository has no native support
+func (r *Repository) FindByMetadata(ctx context.Context, query core.MetadataQuery) ([]core.Tenant, error) {
+	return retryValue(ctx, r.retrier, func(ctx context.Context) ([]core.Tenant, error) {
+		return core.FindByMetadata(ctx, r.repo, query)
+	})
+}
+
+// ListByLabels retries the label selection of the decorated repository, which is
+// filtered in memory when the repository has no native support
+func (r *Repository) ListByLabels(ctx context.Context, selector core.LabelSelector) ([]core.Tenant, error) {
+	return retryValue(ctx, r.retrier, func(ctx context.Context) ([]core.Tenant, error) {
+		return core.ListByLabels(ctx, r.repo, selector)
+	})
+}
+
+func (r *Repository) Create(ctx context.Context, tenant *core.Tenant) error {
+	return r.write(ctx, func(ctx context.Context) error {
+		return r.repo.Create(ctx, tenant)
+	})
+}
+
+func (r *Repository) Update(ctx context.Context, tenant *core.Tenant) error {
+	return r.write(ctx, func(ctx context.Context) error {
+		return r.repo.Update(ctx, tenant)
+	})
+}
+
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	return r.write(ctx, func(ctx context.Context) error {
+		return r.repo.Delete(ctx, id)
+	})
+}
+
+// write runs a non-idempotent operation, retrying it only when allowed
+func (r *Repository) write(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !r.retryWrites {
+		return fn(ctx)
+	}
+	return r.retrier.Do(ctx, fn)
+}
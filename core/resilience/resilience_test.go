@@ -0,0 +1,157 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/infra/memory"
+)
+
+// newTestRetrier returns a retrier that records its waits instead of sleeping
+func newTestRetrier(policy Policy) (*Retrier, *[]time.Duration) {
+	var waits []time.Duration
+	retrier := NewRetrier(policy)
+	retrier.jitter = func(d time.Duration) time.Duration { return d }
+	retrier.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return retrier, &waits
+}
+
+// failing returns an operation failing with errs in order and then succeeding
+func failing(calls *int, errs ...error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestRetrier_RetriesTransientErrors(t *testing.T) {
+	retrier, waits := newTestRetrier(Policy{MaxRetries: 5, Delay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond})
+
+	calls := 0
+	timeout := core.ErrDatabaseTimeout(context.DeadlineExceeded)
+	err := retrier.Do(context.Background(), failing(&calls,
+		timeout, core.ErrDatabaseSerialization(nil), timeout, core.ErrCacheConnection("", nil)))
+
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}, *waits)
+}
+
+func TestRetrier_StopsOnPermanentErrors(t *testing.T) {
+	retrier, waits := newTestRetrier(Policy{MaxRetries: 3, Delay: time.Millisecond})
+
+	calls := 0
+	err := retrier.Do(context.Background(), failing(&calls, core.ErrTenantNotFound("acme")))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *waits)
+}
+
+func TestRetrier_GivesUpAfterMaxRetries(t *testing.T) {
+	retrier, _ := newTestRetrier(Policy{MaxRetries: 2, Delay: time.Millisecond})
+
+	calls := 0
+	lost := core.NewError(core.ErrCodeDatabaseConnection, "database connection failed")
+	err := retrier.Do(context.Background(), failing(&calls, lost, lost, lost, lost))
+	assert.Same(t, lost, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetrier_RespectsDeadline(t *testing.T) {
+	retrier, waits := newTestRetrier(Policy{MaxRetries: 3, Delay: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	calls := 0
+	err := retrier.Do(ctx, failing(&calls, core.ErrCacheTimeout(nil), core.ErrCacheTimeout(nil)))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeCacheTimeout))
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *waits)
+}
+
+func TestEqualJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		wait := equalJitter(time.Second)
+		assert.GreaterOrEqual(t, wait, 500*time.Millisecond)
+		assert.LessOrEqual(t, wait, time.Second)
+	}
+}
+
+// flakyRepository fails every call with a timeout until it has failed failures times
+type flakyRepository struct {
+	core.TenantRepository
+	failures int
+	calls    int
+}
+
+func (r *flakyRepository) fail() error {
+	r.calls++
+	if r.calls <= r.failures {
+		return core.ErrDatabaseTimeout(errors.New("i/o timeout"))
+	}
+	return nil
+}
+
+func (r *flakyRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	if err := r.fail(); err != nil {
+		return nil, err
+	}
+	return r.TenantRepository.GetByName(ctx, name)
+}
+
+func (r *flakyRepository) Create(ctx context.Context, tenant *core.Tenant) error {
+	if err := r.fail(); err != nil {
+		return err
+	}
+	return r.TenantRepository.Create(ctx, tenant)
+}
+
+func TestRepository_RetriesReadsButNotWrites(t *testing.T) {
+	ctx := context.Background()
+	flaky := &flakyRepository{TenantRepository: memory.NewTenantRepository(), failures: 1}
+	repo := NewRepository(flaky, Policy{MaxRetries: 2})
+
+	err := repo.Create(ctx, core.NewTenant("acme"))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeDatabaseTimeout))
+	assert.Equal(t, 1, flaky.calls)
+
+	require.NoError(t, repo.Create(ctx, core.NewTenant("acme")))
+
+	flaky.calls, flaky.failures = 0, 2
+	tenant, err := repo.GetByName(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant.Name)
+	assert.Equal(t, 3, flaky.calls)
+}
+
+func TestRepository_RetryWrites(t *testing.T) {
+	flaky := &flakyRepository{TenantRepository: memory.NewTenantRepository(), failures: 1}
+	repo := NewRepository(flaky, Policy{MaxRetries: 2, RetryWrites: true})
+
+	require.NoError(t, repo.Create(context.Background(), core.NewTenant("acme")))
+	assert.Equal(t, 2, flaky.calls)
+	assert.Same(t, flaky, repo.Unwrap())
+}
+
+func TestCache_DeleteAll(t *testing.T) {
+	ctx := context.Background()
+	inner := memory.NewTenantCache(memory.CacheConfig{})
+	cache := NewCache(inner, Policy{MaxRetries: 1})
+
+	require.NoError(t, cache.Set(ctx, core.NewTenant("acme"), time.Minute))
+	require.NoError(t, cache.DeleteAll(ctx))
+	assert.Equal(t, 0, inner.Len())
+}
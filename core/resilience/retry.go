@@ -0,0 +1,122 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// DefaultMaxDelay caps the backoff when Policy.MaxDelay is not set
+const DefaultMaxDelay = 10 * time.Second
+
+// Policy controls how operations are retried
+type Policy struct {
+	// MaxRetries is the number of retries after the first attempt; zero disables retries
+	MaxRetries int
+	// Delay is the backoff before the first retry, doubled on every further retry
+	Delay time.Duration
+	// MaxDelay caps the backoff, defaults to DefaultMaxDelay
+	MaxDelay time.Duration
+	// RetryWrites also retries repository writes, which may then be applied twice when
+	// an attempt succeeded but its response was lost
+	RetryWrites bool
+}
+
+// IsTransient reports whether err is worth retrying: lost connections, timeouts and
+// transaction conflicts
+func IsTransient(err error) bool {
+	switch core.GetErrorCode(err) {
+	case core.ErrCodeDatabaseConnection, core.ErrCodeDatabaseTimeout, core.ErrCodeDatabaseSerialization,
+		core.ErrCodeCacheConnection, core.ErrCodeCacheTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retrier runs operations with exponential backoff and jitter
+type Retrier struct {
+	policy Policy
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+}
+
+// NewRetrier creates a retrier for policy
+func NewRetrier(policy Policy) *Retrier {
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultMaxDelay
+	}
+
+	return &Retrier{
+		policy: policy,
+		sleep:  sleepContext,
+		jitter: equalJitter,
+	}
+}
+
+// Do calls fn until it succeeds, fails with a non-transient error or runs out of retries,
+// and returns the last error. It stops early when ctx is done or its deadline would pass
+// before the next attempt.
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	delay := r.policy.Delay
+
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= r.policy.MaxRetries || !IsTransient(err) {
+			return err
+		}
+
+		wait := r.jitter(delay)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		if r.sleep(ctx, wait) != nil {
+			return err
+		}
+
+		delay *= 2
+		if delay > r.policy.MaxDelay {
+			delay = r.policy.MaxDelay
+		}
+	}
+}
+
+// retryValue runs fn through r and returns its value
+func retryValue[T any](ctx context.Context, r *Retrier, fn func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	err := r.Do(ctx, func(ctx context.Context) error {
+		var err error
+		value, err = fn(ctx)
+		return err
+	})
+	return value, err
+}
+
+// equalJitter returns a random duration between d/2 and d, spreading out retries of
+// clients that failed together
+func equalJitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
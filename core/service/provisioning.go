@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Compile-time check to ensure TenantService implements core.TenantProvisioner interface
+var _ core.TenantProvisioner = (*TenantService)(nil)
+
+// CreateTenantFromTemplate renders the named template for a new tenant and creates it
+func (s *TenantService) CreateTenantFromTemplate(ctx context.Context, name, templateName string, vars map[string]string) (*core.Tenant, error) {
+	if s.templates == nil {
+		return nil, core.ErrConfigInvalid("Templates", "no template repository configured")
+	}
+
+	tmpl, err := s.templates.GetTemplate(ctx, templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, err := tmpl.Render(name, vars)
+	if err != nil {
+		return nil, core.ErrTenantInvalid(name, err.Error()).WithCause(err)
+	}
+
+	if err := s.CreateTenant(ctx, tenant); err != nil {
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+// CloneTenant creates newName with the metadata and datasources of sourceName, assigning
+// new IDs and rewriting occurrences of the source name in DSNs
+func (s *TenantService) CloneTenant(ctx context.Context, sourceName, newName string) (*core.Tenant, error) {
+	source, err := s.repo.GetByName(ctx, sourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, err := core.TemplateFromTenant(source).Render(newName, nil)
+	if err != nil {
+		return nil, core.ErrTenantInvalid(newName, err.Error()).WithCause(err)
+	}
+
+	// Keep the provenance of the source rather than pointing at the source itself
+	if origin, ok := source.Metadata[core.MetadataKeyTemplate]; ok {
+		tenant.Metadata[core.MetadataKeyTemplate] = origin
+	} else {
+		delete(tenant.Metadata, core.MetadataKeyTemplate)
+	}
+
+	if err := s.CreateTenant(ctx, tenant); err != nil {
+		return nil, err
+	}
+
+	return tenant, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+type stubRepository struct {
+	core.TenantRepository
+	tenants map[string]*core.Tenant
+}
+
+func (r *stubRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	tenant, ok := r.tenants[name]
+	if !ok {
+		return nil, core.TenantNotFoundError{Name: name}
+	}
+	return tenant, nil
+}
+
+func (r *stubRepository) Create(ctx context.Context, tenant *core.Tenant) error {
+	r.tenants[tenant.Name] = tenant
+	return nil
+}
+
+type stubCache struct {
+	core.TenantCache
+}
+
+func (c *stubCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	return nil
+}
+
+type stubTemplates struct {
+	core.TemplateRepository
+	templates map[string]*core.TenantTemplate
+}
+
+func (r *stubTemplates) GetTemplate(ctx context.Context, name string) (*core.TenantTemplate, error) {
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return nil, core.ErrTemplateNotFound(name)
+	}
+	return tmpl, nil
+}
+
+func TestTenantService_CreateTenantFromTemplate(t *testing.T) {
+	repo := &stubRepository{tenants: make(map[string]*core.Tenant)}
+	tmpl := core.NewTenantTemplate("standard")
+	tmpl.Plan = "pro"
+	tmpl.Datasources = append(tmpl.Datasources, core.DatasourceTemplate{
+		DSN: "postgres://app:pass@{{.Host}}:5432/{{.TenantName}}", Role: "rw", PoolSize: 5,
+	})
+
+	svc := NewTenantService(Config{
+		Repository: repo,
+		Cache:      &stubCache{},
+		Templates:  &stubTemplates{templates: map[string]*core.TenantTemplate{"standard": tmpl}},
+	})
+
+	tenant, err := svc.CreateTenantFromTemplate(context.Background(), "acme", "standard", map[string]string{"Host": "db"})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://app:pass@db:5432/acme", tenant.Datasources[0].DSN)
+	assert.Same(t, tenant, repo.tenants["acme"])
+
+	_, err = svc.CreateTenantFromTemplate(context.Background(), "acme", "missing", nil)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTemplateNotFound))
+
+	_, err = svc.CreateTenantFromTemplate(context.Background(), "other", "standard", nil)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantInvalid))
+}
+
+func TestTenantService_CloneTenant(t *testing.T) {
+	source := core.NewTenant("acme")
+	source.Metadata[core.MetadataKeyTemplate] = "standard"
+	source.Labels = map[string]string{"tier": "gold"}
+	source.Datasources = append(source.Datasources, *core.NewDatasource(source.ID, "postgres://app:pass@db:5432/acme", "rw", 5))
+	repo := &stubRepository{tenants: map[string]*core.Tenant{"acme": source}}
+
+	svc := NewTenantService(Config{Repository: repo, Cache: &stubCache{}})
+
+	clone, err := svc.CloneTenant(context.Background(), "acme", "globex")
+	require.NoError(t, err)
+	assert.NotEqual(t, source.ID, clone.ID)
+	assert.Equal(t, "postgres://app:pass@db:5432/globex", clone.Datasources[0].DSN)
+	assert.Equal(t, "standard", clone.Metadata[core.MetadataKeyTemplate])
+	assert.Equal(t, source.Labels, clone.Labels)
+	assert.Contains(t, repo.tenants, "globex")
+
+	_, err = svc.CloneTenant(context.Background(), "missing", "other")
+	assert.IsType(t, core.TenantNotFoundError{}, err)
+}
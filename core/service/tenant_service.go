@@ -2,21 +2,85 @@ package service
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
 	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/tenantcontext"
+)
+
+const (
+	// refreshTimeout bounds the background reload of a tenant about to expire
+	refreshTimeout = 30 * time.Second
+	// repairAttempts bounds the background retries of a side effect that failed after a
+	// committed write, such as a cache invalidation or a change notification
+	repairAttempts = 5
+	// repairBackoff is the delay before the first retry, doubled after every attempt
+	repairBackoff = 500 * time.Millisecond
 )
 
 type TenantService struct {
-	repo  core.TenantRepository
-	cache core.TenantCache
-	ttl   time.Duration
+	repo      core.TenantRepository
+	cache     core.TenantCache
+	templates core.TemplateRepository
+	publisher core.ChangePublisher
+	ttl       time.Duration
+	// notFoundTTL is how long unknown names are cached; 0 disables negative caching
+	notFoundTTL time.Duration
+	// refreshAhead is how long before expiry a cache hit reloads the tenant in the background
+	refreshAhead time.Duration
+	// lookups collapses concurrent repository lookups of the same name
+	lookups singleflight.Group
+	// refreshing holds the names being reloaded in the background
+	refreshing sync.Map
+	logger     *slog.Logger
+	// repairDelay is the backoff before the first background retry of a failed side effect
+	repairDelay time.Duration
+
+	cacheHits    atomic.Uint64
+	negativeHits atomic.Uint64
+	cacheMisses  atomic.Uint64
+	staleHits    atomic.Uint64
+	refreshes    atomic.Uint64
 }
 
 type Config struct {
 	Repository core.TenantRepository
 	Cache      core.TenantCache
 	CacheTTL   time.Duration
+	// Templates is optional and enables CreateTenantFromTemplate
+	Templates core.TemplateRepository
+	// NotFoundTTL caches "not found" results of GetTenant for this long when the cache
+	// implements core.NegativeCache, so unknown names do not reach the repository on
+	// every request. Creating a tenant with the name clears the entry. 0 disables it.
+	NotFoundTTL time.Duration
+	// Publisher is optional and broadcasts every change made through the service
+	Publisher core.ChangePublisher
+	// RefreshAhead reloads a tenant in the background when it is read less than this long
+	// before its cache entry expires, so hot tenants never miss. It requires a cache
+	// implementing core.EntryCache; 0 disables it.
+	RefreshAhead time.Duration
+	// Logger receives the service logs, such as cache failures that do not fail an
+	// operation; defaults to slog.Default()
+	Logger *slog.Logger
+}
+
+// LookupStats counts the GetTenant lookups by how the cache answered them
+type LookupStats struct {
+	CacheHits uint64 `json:"cache_hits"`
+	// NegativeHits counts lookups answered by a cached "not found" result
+	NegativeHits uint64 `json:"negative_hits"`
+	// CacheMisses counts lookups that went to the repository
+	CacheMisses uint64 `json:"cache_misses"`
+	// StaleHits counts misses answered with a stale entry because the repository failed
+	StaleHits uint64 `json:"stale_hits"`
+	// Refreshes counts the background reloads started by RefreshAhead
+	Refreshes uint64 `json:"refreshes"`
 }
 
 func NewTenantService(config Config) *TenantService {
@@ -26,29 +90,181 @@ func NewTenantService(config Config) *TenantService {
 	}
 
 	return &TenantService{
-		repo:  config.Repository,
-		cache: config.Cache,
-		ttl:   ttl,
+		repo:         config.Repository,
+		cache:        config.Cache,
+		templates:    config.Templates,
+		publisher:    config.Publisher,
+		ttl:          ttl,
+		notFoundTTL:  config.NotFoundTTL,
+		refreshAhead: config.RefreshAhead,
+		logger:       core.ComponentLogger(config.Logger, "service"),
+		repairDelay:  repairBackoff,
 	}
 }
 
+// GetTenant returns a tenant by name, from the cache when possible. When the cache keeps
+// expired entries and the repository fails, the stale tenant is returned instead and
+// flagged with tenantcontext.MarkStale.
 func (s *TenantService) GetTenant(ctx context.Context, name string) (*core.Tenant, error) {
 	// Try to get from cache first
+	entry, err := s.cachedEntry(ctx, name)
+	if err == nil && entry.Fresh(time.Now()) {
+		s.cacheHits.Add(1)
+		s.refreshIfExpiring(ctx, name, entry)
+		return entry.Tenant, nil
+	}
+	if core.IsCachedNotFound(err) {
+		s.negativeHits.Add(1)
+		return nil, err
+	}
+	s.cacheMisses.Add(1)
+
+	// If not in cache, expired or error, try repository
+	tenant, loadErr := s.lookup(ctx, name)
+	if loadErr != nil && err == nil && s.canServeStale(ctx, loadErr) {
+		s.staleHits.Add(1)
+		tenantcontext.MarkStale(ctx)
+		s.logger.WarnContext(ctx, "serving stale tenant while the repository fails",
+			core.LogKeyTenantName, name, core.LogKeyError, loadErr)
+		return entry.Tenant, nil
+	}
+	return tenant, loadErr
+}
+
+// cachedEntry reads name from the cache, along with its freshness when the cache reports it
+func (s *TenantService) cachedEntry(ctx context.Context, name string) (*core.CacheEntry, error) {
+	if cache, ok := s.cache.(core.EntryCache); ok {
+		return cache.GetEntry(ctx, name)
+	}
+
 	tenant, err := s.cache.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &core.CacheEntry{Tenant: tenant}, nil
+}
+
+// canServeStale reports whether loadErr means the repository is unavailable rather than
+// the tenant being gone or the caller having given up
+func (s *TenantService) canServeStale(ctx context.Context, loadErr error) bool {
+	return ctx.Err() == nil && !core.IsErrorCode(loadErr, core.ErrCodeTenantNotFound)
+}
+
+// refreshIfExpiring reloads name in the background when entry expires within the
+// refresh-ahead window, unless a reload is already running
+func (s *TenantService) refreshIfExpiring(ctx context.Context, name string, entry *core.CacheEntry) {
+	if s.refreshAhead <= 0 || entry.FreshUntil.IsZero() || time.Until(entry.FreshUntil) > s.refreshAhead {
+		return
+	}
+	if _, running := s.refreshing.LoadOrStore(name, struct{}{}); running {
+		return
+	}
+	s.refreshes.Add(1)
+
+	// Keep the values of ctx, such as trace spans, but not its cancellation
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+	go func() {
+		defer cancel()
+		defer s.refreshing.Delete(name)
+
+		_, err, _ := s.lookups.Do(name, func() (interface{}, error) {
+			return s.load(ctx, name)
+		})
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to refresh tenant ahead of expiry",
+				core.LogKeyTenantName, name, core.LogKeyError, err)
+		}
+	}()
+}
+
+// lookup loads a tenant from the repository. Concurrent lookups of the same name share
+// a single repository call, made with the context of the first caller.
+func (s *TenantService) lookup(ctx context.Context, name string) (*core.Tenant, error) {
+	result := s.lookups.DoChan(name, func() (interface{}, error) {
+		return s.load(ctx, name)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			// The first caller gave up; load again with our own context
+			if res.Shared && isContextError(res.Err) && ctx.Err() == nil {
+				return s.load(ctx, name)
+			}
+			return nil, res.Err
+		}
+
+		tenant := res.Val.(*core.Tenant)
+		if res.Shared {
+			// Give every caller its own copy of the tenant, maps and slices included
+			tenant = core.CopyTenant(tenant)
+		}
+		return tenant, nil
+	}
+}
+
+// load reads a tenant from the repository and caches the result
+func (s *TenantService) load(ctx context.Context, name string) (*core.Tenant, error) {
+	tenant, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		if core.IsErrorCode(err, core.ErrCodeTenantNotFound) {
+			s.cacheNotFound(ctx, name)
+		}
+		return nil, err
+	}
+
+	// Cache the tenant for future requests; a failure only costs a later miss
+	if err := s.cache.Set(ctx, tenant, s.ttl); err != nil {
+		s.logger.WarnContext(ctx, "failed to cache tenant", core.LogKeyTenantName, name, core.LogKeyError, err)
+	}
+
+	return tenant, nil
+}
+
+// isContextError reports whether err comes from a canceled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// cacheNotFound remembers name as unknown when negative caching is enabled and supported
+func (s *TenantService) cacheNotFound(ctx context.Context, name string) {
+	cache, ok := s.cache.(core.NegativeCache)
+	if !ok || s.notFoundTTL <= 0 {
+		return
+	}
+
+	if err := cache.SetNotFound(ctx, name, s.notFoundTTL); err != nil {
+		s.logger.WarnContext(ctx, "failed to cache unknown tenant name", core.LogKeyTenantName, name, core.LogKeyError, err)
+	}
+}
+
+// LookupStats returns the cache and refresh counters of GetTenant
+func (s *TenantService) LookupStats() LookupStats {
+	return LookupStats{
+		CacheHits:    s.cacheHits.Load(),
+		NegativeHits: s.negativeHits.Load(),
+		CacheMisses:  s.cacheMisses.Load(),
+		StaleHits:    s.staleHits.Load(),
+		Refreshes:    s.refreshes.Load(),
+	}
+}
+
+// GetTenantByID returns a tenant by ID, from the cache when possible
+func (s *TenantService) GetTenantByID(ctx context.Context, id string) (*core.Tenant, error) {
+	tenant, err := s.cache.GetByID(ctx, id)
 	if err == nil {
 		return tenant, nil
 	}
 
-	// If not in cache or error, try repository
-	tenant, err = s.repo.GetByName(ctx, name)
+	tenant, err = s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Cache the tenant for future requests
 	if err := s.cache.Set(ctx, tenant, s.ttl); err != nil {
-		// Log error but don't fail the request
-		// TODO: Add proper logging
+		s.logger.WarnContext(ctx, "failed to cache tenant", core.LogKeyTenantName, tenant.Name, core.LogKeyError, err)
 	}
 
 	return tenant, nil
@@ -59,47 +275,213 @@ func (s *TenantService) ListTenants(ctx context.Context) ([]core.Tenant, error)
 	return s.repo.List(ctx)
 }
 
+// FindByMetadata returns the tenants whose metadata matches query, straight from the repository
+func (s *TenantService) FindByMetadata(ctx context.Context, query core.MetadataQuery) ([]core.Tenant, error) {
+	return core.FindByMetadata(ctx, s.repo, query)
+}
+
+// ListByLabels returns the tenants whose labels match selector, straight from the repository
+func (s *TenantService) ListByLabels(ctx context.Context, selector core.LabelSelector) ([]core.Tenant, error) {
+	return core.ListByLabels(ctx, s.repo, selector)
+}
+
 func (s *TenantService) CreateTenant(ctx context.Context, tenant *core.Tenant) error {
 	if err := s.repo.Create(ctx, tenant); err != nil {
 		return err
 	}
 
 	// Cache the new tenant
-	return s.cache.Set(ctx, tenant, s.ttl)
+	s.syncCache(ctx, func() error {
+		return s.cache.Set(ctx, tenant, s.ttl)
+	}, tenant.Name)
+
+	s.publish(ctx, core.TenantChange{Op: core.ChangeInsert, ID: tenant.ID, Name: tenant.Name})
+	return nil
 }
 
 func (s *TenantService) UpdateTenant(ctx context.Context, tenant *core.Tenant) error {
+	// Look the stored tenant up so a rename also evicts the entry under the previous name
+	previous, err := s.repo.GetByID(ctx, tenant.ID)
+	if err != nil {
+		return err
+	}
+
 	if err := s.repo.Update(ctx, tenant); err != nil {
 		return err
 	}
 
+	change := core.TenantChange{Op: core.ChangeUpdate, ID: tenant.ID, Name: tenant.Name}
+	if previous.Name != tenant.Name {
+		change.OldName = previous.Name
+	}
+
 	// Update cache
-	return s.cache.Set(ctx, tenant, s.ttl)
+	s.syncCache(ctx, func() error {
+		if change.OldName != "" {
+			if err := s.cache.Delete(ctx, change.OldName); err != nil {
+				return err
+			}
+		}
+		return s.cache.Set(ctx, tenant, s.ttl)
+	}, tenant.Name, change.OldName)
+
+	s.publish(ctx, change)
+	return nil
 }
 
 func (s *TenantService) DeleteTenant(ctx context.Context, id string) error {
-	// Get tenant first to know the name for cache invalidation
-	tenants, err := s.repo.List(ctx)
+	// Look the tenant up by ID to know the name for cache invalidation
+	tenant, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	var tenantName string
-	for _, t := range tenants {
-		if t.ID == id {
-			tenantName = t.Name
-			break
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.syncCache(ctx, func() error {
+		return s.cache.Delete(ctx, tenant.Name)
+	}, tenant.Name)
+
+	s.publish(ctx, core.TenantChange{Op: core.ChangeDelete, ID: tenant.ID, Name: tenant.Name})
+	return nil
+}
+
+// syncCache applies a cache write that follows a committed repository write. The change
+// already happened, so a failing cache does not fail the operation: the failure is logged
+// and names are invalidated in the background until that succeeds, so readers reload the
+// tenant from the repository instead of serving the previous version.
+func (s *TenantService) syncCache(ctx context.Context, write func() error, names ...string) {
+	err := write()
+	if err == nil {
+		return
+	}
+	s.logger.WarnContext(ctx, "failed to update the tenant cache after a committed change, invalidating in the background",
+		core.LogKeyTenantName, names[0], core.LogKeyError, err)
+
+	s.repair(ctx, "invalidate the tenant cache", names[0], func(ctx context.Context) error {
+		return s.deleteCached(ctx, names)
+	})
+}
+
+// repair retries a side effect of a committed write in the background, backing off
+// between attempts, and logs whether it eventually succeeded. Cache repairs delete the
+// entries rather than storing the tenant again, which cannot overwrite a newer version
+// cached meanwhile.
+func (s *TenantService) repair(ctx context.Context, task, name string, fn func(context.Context) error) {
+	// Keep the values of ctx, such as trace spans, but not its cancellation
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		delay := s.repairDelay
+		for attempt := 1; ; attempt++ {
+			time.Sleep(delay)
+			delay *= 2
+
+			err := fn(ctx)
+			if err == nil {
+				s.logger.InfoContext(ctx, "repaired a failed side effect of a committed change",
+					"task", task, core.LogKeyTenantName, name, "attempts", attempt)
+				return
+			}
+			if attempt == repairAttempts {
+				s.logger.ErrorContext(ctx, "gave up repairing a side effect of a committed change",
+					"task", task, core.LogKeyTenantName, name, "attempts", attempt, core.LogKeyError, err)
+				return
+			}
+		}
+	}()
+}
+
+// deleteCached deletes the cache entries of the non-empty names
+func (s *TenantService) deleteCached(ctx context.Context, names []string) error {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if err := s.cache.Delete(ctx, name); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	if err := s.repo.Delete(ctx, id); err != nil {
-		return err
+// publish broadcasts change when a publisher is configured. The change already
+// happened, so a failure is logged and the notification retried in the background;
+// until it gets through, other instances serve the previous version.
+func (s *TenantService) publish(ctx context.Context, change core.TenantChange) {
+	if s.publisher == nil {
+		return
 	}
 
-	// Invalidate cache if we found the tenant name
-	if tenantName != "" {
-		return s.cache.Delete(ctx, tenantName)
+	err := s.publisher.PublishChange(ctx, change)
+	if err == nil {
+		return
 	}
+	s.logger.WarnContext(ctx, "failed to publish a committed change, retrying in the background",
+		core.LogKeyTenantName, change.Name, core.LogKeyError, err)
 
+	s.repair(ctx, "publish the tenant change", change.Name, func(ctx context.Context) error {
+		return s.publisher.PublishChange(ctx, change)
+	})
+}
+
+// localCache is implemented by caches that keep entries in process memory and can drop
+// them without touching a tier shared with other instances
+type localCache interface {
+	DeleteLocal(ctx context.Context) error
+	DeleteLocalName(ctx context.Context, name string) error
+}
+
+// InvalidateTenant evicts the in-process cache entries affected by a change broadcast by
+// another TenantService. The instance that made the change already updated the shared
+// tiers, so they are left alone; a resync evicts every in-process entry.
+func (s *TenantService) InvalidateTenant(ctx context.Context, change core.TenantChange) error {
+	cache, ok := s.cache.(localCache)
+	if !ok {
+		return nil
+	}
+
+	if change.Op == core.ChangeResync {
+		return cache.DeleteLocal(ctx)
+	}
+
+	for _, name := range s.changedNames(ctx, change) {
+		if err := cache.DeleteLocalName(ctx, name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// InvalidateRegistryChange drops the cache entries affected by a change reported by a
+// registry change feed from every tier, since the change may not have gone through a
+// TenantService. A resync only evicts in-process entries, as flushing the shared tiers
+// from every instance after each reconnect would send all lookups to the registry at once.
+func (s *TenantService) InvalidateRegistryChange(ctx context.Context, change core.TenantChange) error {
+	if change.Op == core.ChangeResync {
+		return s.InvalidateTenant(ctx, change)
+	}
+
+	return s.deleteCached(ctx, s.changedNames(ctx, change))
+}
+
+// changedNames returns the cached names affected by change
+func (s *TenantService) changedNames(ctx context.Context, change core.TenantChange) []string {
+	names := []string{change.Name, change.OldName}
+	// Without a name, e.g. for a cascaded datasource delete, resolve it through the ID index
+	if change.Name == "" && change.ID != "" {
+		if cached, err := s.cache.GetByID(ctx, change.ID); err == nil {
+			names = append(names, cached.Name)
+		}
+	}
+
+	changed := names[:0]
+	for _, name := range names {
+		if name != "" {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
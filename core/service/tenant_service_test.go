@@ -0,0 +1,643 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/infra/memory"
+	"github.com/victorximenis/multitenant/tenantcontext"
+)
+
+// idRepository is a repository stub addressed by ID; List is left unimplemented so
+// any full scan panics
+type idRepository struct {
+	core.TenantRepository
+	tenants map[string]*core.Tenant
+}
+
+func (r *idRepository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	tenant, ok := r.tenants[id]
+	if !ok {
+		return nil, core.ErrTenantNotFound(id)
+	}
+	return tenant, nil
+}
+
+func (r *idRepository) Update(ctx context.Context, tenant *core.Tenant) error {
+	r.tenants[tenant.ID] = tenant
+	return nil
+}
+
+func (r *idRepository) Delete(ctx context.Context, id string) error {
+	if _, ok := r.tenants[id]; !ok {
+		return core.ErrTenantNotFound(id)
+	}
+	delete(r.tenants, id)
+	return nil
+}
+
+// recordingCache is a cache stub keyed by ID that records invalidated names
+type recordingCache struct {
+	tenants map[string]*core.Tenant
+	deleted []string
+}
+
+func (c *recordingCache) Get(ctx context.Context, name string) (*core.Tenant, error) {
+	for _, tenant := range c.tenants {
+		if tenant.Name == name {
+			return tenant, nil
+		}
+	}
+	return nil, core.ErrTenantNotFound(name)
+}
+
+func (c *recordingCache) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	tenant, ok := c.tenants[id]
+	if !ok {
+		return nil, core.ErrTenantNotFound(id)
+	}
+	return tenant, nil
+}
+
+func (c *recordingCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	copied := *tenant
+	c.tenants[tenant.ID] = &copied
+	return nil
+}
+
+func (c *recordingCache) Delete(ctx context.Context, name string) error {
+	c.deleted = append(c.deleted, name)
+	return nil
+}
+
+func newIDService(tenants ...*core.Tenant) (*TenantService, *idRepository, *recordingCache) {
+	repo := &idRepository{tenants: make(map[string]*core.Tenant)}
+	for _, tenant := range tenants {
+		repo.tenants[tenant.ID] = tenant
+	}
+	cache := &recordingCache{tenants: make(map[string]*core.Tenant)}
+	return NewTenantService(Config{Repository: repo, Cache: cache}), repo, cache
+}
+
+func TestTenantService_DeleteTenantByID(t *testing.T) {
+	acme := core.NewTenant("acme")
+	svc, repo, cache := newIDService(acme)
+
+	require.NoError(t, svc.DeleteTenant(context.Background(), acme.ID))
+	assert.Empty(t, repo.tenants)
+	assert.Equal(t, []string{"acme"}, cache.deleted)
+
+	err := svc.DeleteTenant(context.Background(), acme.ID)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+}
+
+func TestTenantService_GetTenantByID(t *testing.T) {
+	acme := core.NewTenant("acme")
+	svc, repo, cache := newIDService(acme)
+	ctx := context.Background()
+
+	tenant, err := svc.GetTenantByID(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant.Name)
+	assert.Contains(t, cache.tenants, acme.ID)
+
+	// Served from the cache once populated
+	delete(repo.tenants, acme.ID)
+	tenant, err = svc.GetTenantByID(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant.Name)
+}
+
+func TestTenantService_UpdateTenantInvalidatesPreviousName(t *testing.T) {
+	acme := core.NewTenant("acme")
+	svc, _, cache := newIDService(acme)
+	ctx := context.Background()
+
+	_, err := svc.GetTenantByID(ctx, acme.ID)
+	require.NoError(t, err)
+
+	renamed := *acme
+	renamed.Name = "acme-corp"
+	require.NoError(t, svc.UpdateTenant(ctx, &renamed))
+
+	assert.Equal(t, []string{"acme"}, cache.deleted)
+	cached, err := cache.GetByID(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme-corp", cached.Name)
+}
+
+func TestTenantService_UpdateTenantRenameWithoutCachedEntry(t *testing.T) {
+	acme := core.NewTenant("acme")
+	svc, _, cache := newIDService(acme)
+	publisher := &recordingPublisher{}
+	svc.publisher = publisher
+	ctx := context.Background()
+
+	// The previous name comes from the registry, not from whatever happens to be cached
+	renamed := *acme
+	renamed.Name = "acme-corp"
+	require.NoError(t, svc.UpdateTenant(ctx, &renamed))
+
+	assert.Equal(t, []string{"acme"}, cache.deleted)
+	assert.Equal(t, []core.TenantChange{
+		{Op: core.ChangeUpdate, ID: acme.ID, Name: "acme-corp", OldName: "acme"},
+	}, publisher.changes)
+
+	missing := core.NewTenant("missing")
+	assert.True(t, core.IsErrorCode(svc.UpdateTenant(ctx, missing), core.ErrCodeTenantNotFound))
+}
+
+func TestTenantService_InvalidateRegistryChange(t *testing.T) {
+	acme := core.NewTenant("acme")
+	svc, _, cache := newIDService(acme)
+	ctx := context.Background()
+
+	require.NoError(t, svc.InvalidateRegistryChange(ctx, core.TenantChange{
+		Op: core.ChangeUpdate, ID: acme.ID, Name: "acme-corp", OldName: "acme",
+	}))
+	assert.Equal(t, []string{"acme-corp", "acme"}, cache.deleted)
+
+	// A change without a name is resolved through the cached ID
+	cache.deleted = nil
+	_, err := svc.GetTenantByID(ctx, acme.ID)
+	require.NoError(t, err)
+	require.NoError(t, svc.InvalidateRegistryChange(ctx, core.TenantChange{Op: core.ChangeDelete, ID: acme.ID}))
+	assert.Equal(t, []string{"acme"}, cache.deleted)
+
+	// The stub keeps nothing in process memory, so a resync is a no-op
+	cache.deleted = nil
+	require.NoError(t, svc.InvalidateRegistryChange(ctx, core.TenantChange{Op: core.ChangeResync}))
+	assert.Empty(t, cache.deleted)
+}
+
+// tieredCache records which tiers an invalidation reaches
+type tieredCache struct {
+	recordingCache
+	localDeleted []string
+	localClears  int
+	sharedClears int
+}
+
+func (c *tieredCache) DeleteLocal(ctx context.Context) error {
+	c.localClears++
+	return nil
+}
+
+func (c *tieredCache) DeleteLocalName(ctx context.Context, name string) error {
+	c.localDeleted = append(c.localDeleted, name)
+	return nil
+}
+
+func (c *tieredCache) DeleteAll(ctx context.Context) error {
+	c.sharedClears++
+	return nil
+}
+
+func TestTenantService_InvalidateTenantOnlyEvictsLocalEntries(t *testing.T) {
+	acme := core.NewTenant("acme")
+	cache := &tieredCache{recordingCache: recordingCache{tenants: make(map[string]*core.Tenant)}}
+	svc := NewTenantService(Config{Repository: &idRepository{tenants: map[string]*core.Tenant{acme.ID: acme}}, Cache: cache})
+	ctx := context.Background()
+
+	require.NoError(t, svc.InvalidateTenant(ctx, core.TenantChange{
+		Op: core.ChangeUpdate, ID: acme.ID, Name: "acme-corp", OldName: "acme",
+	}))
+	assert.Equal(t, []string{"acme-corp", "acme"}, cache.localDeleted)
+	assert.Empty(t, cache.deleted, "a broadcast change must not delete the entries the writer just stored")
+
+	require.NoError(t, svc.InvalidateTenant(ctx, core.TenantChange{Op: core.ChangeResync}))
+	assert.Equal(t, 1, cache.localClears)
+	assert.Zero(t, cache.sharedClears, "a resync must not flush the cache shared with other instances")
+
+	require.NoError(t, svc.InvalidateRegistryChange(ctx, core.TenantChange{Op: core.ChangeResync}))
+	assert.Equal(t, 2, cache.localClears)
+	assert.Zero(t, cache.sharedClears)
+}
+
+// countingRepository counts the lookups by name reaching the repository
+type countingRepository struct {
+	stubRepository
+	lookups int
+}
+
+func (r *countingRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	r.lookups++
+	return r.stubRepository.GetByName(ctx, name)
+}
+
+func TestTenantService_CachesUnknownNames(t *testing.T) {
+	repo := &countingRepository{stubRepository: stubRepository{tenants: make(map[string]*core.Tenant)}}
+	svc := NewTenantService(Config{
+		Repository:  repo,
+		Cache:       memory.NewTenantCache(memory.CacheConfig{}),
+		NotFoundTTL: time.Minute,
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.GetTenant(ctx, "ghost")
+		assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	}
+	assert.Equal(t, 1, repo.lookups)
+	assert.Equal(t, LookupStats{NegativeHits: 2, CacheMisses: 1}, svc.LookupStats())
+
+	// Creating the tenant replaces the negative entry
+	require.NoError(t, svc.CreateTenant(ctx, core.NewTenant("ghost")))
+	tenant, err := svc.GetTenant(ctx, "ghost")
+	require.NoError(t, err)
+	assert.Equal(t, "ghost", tenant.Name)
+	assert.Equal(t, 1, repo.lookups)
+	assert.Equal(t, LookupStats{CacheHits: 1, NegativeHits: 2, CacheMisses: 1}, svc.LookupStats())
+}
+
+func TestTenantService_NegativeCachingDisabled(t *testing.T) {
+	repo := &countingRepository{stubRepository: stubRepository{tenants: make(map[string]*core.Tenant)}}
+	svc := NewTenantService(Config{Repository: repo, Cache: memory.NewTenantCache(memory.CacheConfig{})})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.GetTenant(ctx, "ghost")
+		assert.False(t, core.IsCachedNotFound(err))
+	}
+	assert.Equal(t, 2, repo.lookups)
+}
+
+// blockingRepository holds lookups by name until release is closed
+type blockingRepository struct {
+	core.TenantRepository
+	tenant  *core.Tenant
+	started chan struct{}
+	release chan struct{}
+	lookups atomic.Int32
+}
+
+func (r *blockingRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	if r.lookups.Add(1) == 1 {
+		close(r.started)
+	}
+	<-r.release
+	return r.tenant, nil
+}
+
+func TestTenantService_CoalescesConcurrentLookups(t *testing.T) {
+	repo := &blockingRepository{
+		tenant:  core.NewTenant("acme"),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	svc := NewTenantService(Config{Repository: repo, Cache: memory.NewTenantCache(memory.CacheConfig{})})
+
+	const callers = 20
+	results := make(chan *core.Tenant, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tenant, err := svc.GetTenant(context.Background(), "acme")
+			assert.NoError(t, err)
+			results <- tenant
+		}()
+	}
+
+	// Let the other callers join the lookup in flight before it completes
+	<-repo.started
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+	close(results)
+
+	assert.Equal(t, int32(1), repo.lookups.Load())
+	seen := make(map[*core.Tenant]bool)
+	for tenant := range results {
+		require.NotNil(t, tenant)
+		assert.Equal(t, "acme", tenant.Name)
+		assert.False(t, seen[tenant], "callers must not share a tenant pointer")
+		seen[tenant] = true
+	}
+}
+
+func TestTenantService_CoalescedLookupsDoNotShareMapsOrSlices(t *testing.T) {
+	tenant := core.NewTenant("acme")
+	tenant.Metadata = map[string]interface{}{"plan": "free", "limits": map[string]interface{}{"users": 10}}
+	tenant.Labels = map[string]string{"tier": "free"}
+	tenant.Datasources = []core.Datasource{{
+		DSN:      "postgres://localhost/acme",
+		Role:     "rw",
+		Metadata: map[string]interface{}{"region": "us"},
+	}}
+	repo := &blockingRepository{
+		tenant:  tenant,
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	svc := NewTenantService(Config{Repository: repo, Cache: memory.NewTenantCache(memory.CacheConfig{})})
+
+	results := make(chan *core.Tenant, 2)
+	for range 2 {
+		go func() {
+			tenant, err := svc.GetTenant(context.Background(), "acme")
+			assert.NoError(t, err)
+			results <- tenant
+		}()
+	}
+
+	<-repo.started
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	first, second := <-results, <-results
+	require.Equal(t, int32(1), repo.lookups.Load())
+
+	first.Metadata["plan"] = "enterprise"
+	first.Metadata["limits"].(map[string]interface{})["users"] = 1000
+	first.Labels["tier"] = "enterprise"
+	first.Datasources[0].DSN = "postgres://localhost/other"
+	first.Datasources[0].Metadata["region"] = "eu"
+
+	assert.Equal(t, "free", second.Metadata["plan"])
+	assert.Equal(t, 10, second.Metadata["limits"].(map[string]interface{})["users"])
+	assert.Equal(t, "free", second.Labels["tier"])
+	assert.Equal(t, "postgres://localhost/acme", second.Datasources[0].DSN)
+	assert.Equal(t, "us", second.Datasources[0].Metadata["region"])
+}
+
+func TestTenantService_LookupOutlivesCanceledCaller(t *testing.T) {
+	repo := &blockingRepository{
+		tenant:  core.NewTenant("acme"),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	svc := NewTenantService(Config{Repository: repo, Cache: memory.NewTenantCache(memory.CacheConfig{})})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.GetTenant(ctx, "acme")
+		done <- err
+	}()
+
+	<-repo.started
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	close(repo.release)
+	tenant, err := svc.GetTenant(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant.Name)
+}
+
+// recordingPublisher records the published changes
+type recordingPublisher struct {
+	changes []core.TenantChange
+}
+
+func (p *recordingPublisher) PublishChange(ctx context.Context, change core.TenantChange) error {
+	p.changes = append(p.changes, change)
+	return nil
+}
+
+func TestTenantService_PublishesChanges(t *testing.T) {
+	publisher := &recordingPublisher{}
+	svc := NewTenantService(Config{
+		Repository: memory.NewTenantRepository(),
+		Cache:      memory.NewTenantCache(memory.CacheConfig{}),
+		Publisher:  publisher,
+	})
+	ctx := context.Background()
+
+	tenant := core.NewTenant("acme")
+	require.NoError(t, svc.CreateTenant(ctx, tenant))
+
+	renamed := *tenant
+	renamed.Name = "acme-corp"
+	require.NoError(t, svc.UpdateTenant(ctx, &renamed))
+	require.NoError(t, svc.DeleteTenant(ctx, tenant.ID))
+
+	assert.Equal(t, []core.TenantChange{
+		{Op: core.ChangeInsert, ID: tenant.ID, Name: "acme"},
+		{Op: core.ChangeUpdate, ID: tenant.ID, Name: "acme-corp", OldName: "acme"},
+		{Op: core.ChangeDelete, ID: tenant.ID, Name: "acme-corp"},
+	}, publisher.changes)
+}
+
+// flakyRepository serves tenants by name, fails every lookup with err when set and
+// reports each lookup on loaded
+type flakyRepository struct {
+	core.TenantRepository
+	mu      sync.Mutex
+	tenants map[string]*core.Tenant
+	err     error
+	loaded  chan string
+}
+
+func (r *flakyRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loaded != nil {
+		r.loaded <- name
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	tenant, ok := r.tenants[name]
+	if !ok {
+		return nil, core.ErrTenantNotFound(name)
+	}
+	return tenant, nil
+}
+
+func TestTenantService_ServesStaleTenantWhileRepositoryFails(t *testing.T) {
+	acme := core.NewTenant("acme")
+	repo := &flakyRepository{
+		tenants: map[string]*core.Tenant{"acme": acme},
+		err:     core.ErrDatabaseConnection("postgres", errors.New("connection refused")),
+	}
+	cache := memory.NewTenantCache(memory.CacheConfig{StaleTTL: time.Minute})
+	svc := NewTenantService(Config{Repository: repo, Cache: cache})
+
+	require.NoError(t, cache.Set(context.Background(), acme, 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	ctx := tenantcontext.WithStaleTracking(context.Background())
+	tenant, err := svc.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, tenant.ID)
+	assert.True(t, tenantcontext.IsStale(ctx))
+	assert.Equal(t, uint64(1), svc.LookupStats().StaleHits)
+
+	// A tenant the repository no longer knows is not served stale
+	repo.err = nil
+	delete(repo.tenants, "acme")
+	ctx = tenantcontext.WithStaleTracking(context.Background())
+	_, err = svc.GetTenant(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	assert.False(t, tenantcontext.IsStale(ctx))
+}
+
+func TestTenantService_RefreshesTenantsAboutToExpire(t *testing.T) {
+	acme := core.NewTenant("acme")
+	repo := &flakyRepository{tenants: map[string]*core.Tenant{"acme": acme}, loaded: make(chan string, 1)}
+	cache := memory.NewTenantCache(memory.CacheConfig{})
+	svc := NewTenantService(Config{Repository: repo, Cache: cache, CacheTTL: time.Hour, RefreshAhead: time.Minute})
+	ctx := context.Background()
+
+	// Far from expiry, a hit does not reload
+	require.NoError(t, cache.Set(ctx, acme, 30*time.Minute))
+	_, err := svc.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), svc.LookupStats().Refreshes)
+
+	// Within the window, the hit is served from the cache and reloads in the background
+	require.NoError(t, cache.Set(ctx, acme, 30*time.Second))
+	tenant, err := svc.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, tenant.ID)
+
+	select {
+	case name := <-repo.loaded:
+		assert.Equal(t, "acme", name)
+	case <-time.After(time.Second):
+		t.Fatal("tenant was not refreshed")
+	}
+	require.Eventually(t, func() bool {
+		entry, err := cache.GetEntry(ctx, "acme")
+		return err == nil && time.Until(entry.FreshUntil) > 30*time.Minute
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, LookupStats{CacheHits: 2, Refreshes: 1}, svc.LookupStats())
+}
+
+// failingCache fails every write with err while it is set
+type failingCache struct {
+	*memory.TenantCache
+	mu  sync.Mutex
+	err error
+}
+
+func (c *failingCache) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+func (c *failingCache) writeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *failingCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	if err := c.writeErr(); err != nil {
+		return err
+	}
+	return c.TenantCache.Set(ctx, tenant, ttl)
+}
+
+func (c *failingCache) Delete(ctx context.Context, name string) error {
+	if err := c.writeErr(); err != nil {
+		return err
+	}
+	return c.TenantCache.Delete(ctx, name)
+}
+
+func TestTenantService_CacheFailuresDoNotFailCommittedWrites(t *testing.T) {
+	repo := memory.NewTenantRepository()
+	cache := &failingCache{TenantCache: memory.NewTenantCache(memory.CacheConfig{})}
+	svc := NewTenantService(Config{Repository: repo, Cache: cache})
+	svc.repairDelay = time.Millisecond
+	ctx := context.Background()
+
+	acme := core.NewTenant("acme")
+	cache.fail(core.ErrCacheConnection("localhost:6379", errors.New("connection refused")))
+	require.NoError(t, svc.CreateTenant(ctx, acme))
+
+	stored, err := repo.GetByID(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", stored.Name)
+
+	// An update that cannot reach the cache leaves the previous version cached until the
+	// background invalidation gets through
+	cache.fail(nil)
+	require.NoError(t, cache.Set(ctx, acme, 0))
+	cache.fail(core.ErrCacheConnection("localhost:6379", errors.New("connection refused")))
+
+	updated := *stored
+	updated.Metadata = map[string]interface{}{"plan": "enterprise"}
+	require.NoError(t, svc.UpdateTenant(ctx, &updated))
+	_, err = cache.Get(ctx, "acme")
+	require.NoError(t, err)
+
+	cache.fail(nil)
+	require.Eventually(t, func() bool {
+		_, err := cache.Get(ctx, "acme")
+		return core.IsErrorCode(err, core.ErrCodeTenantNotFound)
+	}, time.Second, 5*time.Millisecond)
+
+	tenant, err := svc.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "enterprise", tenant.Metadata["plan"])
+
+	cache.fail(core.ErrCacheConnection("localhost:6379", errors.New("connection refused")))
+	assert.NoError(t, svc.DeleteTenant(ctx, acme.ID))
+}
+
+// failingPublisher fails every publish while failures is positive and records the
+// changes published once it recovers
+type failingPublisher struct {
+	mu       sync.Mutex
+	failures int
+	changes  []core.TenantChange
+}
+
+func (p *failingPublisher) PublishChange(ctx context.Context, change core.TenantChange) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return core.ErrCacheConnection("localhost:6379", errors.New("connection refused"))
+	}
+	p.changes = append(p.changes, change)
+	return nil
+}
+
+func (p *failingPublisher) published() []core.TenantChange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]core.TenantChange(nil), p.changes...)
+}
+
+func TestTenantService_PublishFailuresDoNotFailCommittedWrites(t *testing.T) {
+	repo := memory.NewTenantRepository()
+	publisher := &failingPublisher{failures: 4}
+	svc := NewTenantService(Config{
+		Repository: repo,
+		Cache:      memory.NewTenantCache(memory.CacheConfig{}),
+		Publisher:  publisher,
+	})
+	svc.repairDelay = time.Millisecond
+	ctx := context.Background()
+
+	acme := core.NewTenant("acme")
+	require.NoError(t, svc.CreateTenant(ctx, acme))
+	require.NoError(t, svc.DeleteTenant(ctx, acme.ID))
+
+	_, err := repo.GetByID(ctx, acme.ID)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	// Both notifications get through once the bus recovers
+	require.Eventually(t, func() bool {
+		return len(publisher.published()) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []core.TenantChange{
+		{Op: core.ChangeInsert, ID: acme.ID, Name: "acme"},
+		{Op: core.ChangeDelete, ID: acme.ID, Name: "acme"},
+	}, publisher.published())
+}
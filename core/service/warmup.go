@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// DefaultWarmUpBatchSize is the number of tenants WarmCache stores per cache round trip
+const DefaultWarmUpBatchSize = 500
+
+// WarmCache lists the active tenants and stores them in the cache in batches of
+// batchSize, calling progress with the running count after each batch. It returns how
+// many tenants were cached before ctx ended or a batch failed.
+func (s *TenantService) WarmCache(ctx context.Context, batchSize int, progress func(cached, total int)) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultWarmUpBatchSize
+	}
+
+	tenants, err := s.repo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	active := make([]*core.Tenant, 0, len(tenants))
+	for i := range tenants {
+		if tenants[i].IsActive {
+			active = append(active, &tenants[i])
+		}
+	}
+
+	cached := 0
+	for start := 0; start < len(active); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return cached, err
+		}
+
+		batch := active[start:min(start+batchSize, len(active))]
+		if err := core.SetMany(ctx, s.cache, batch, s.ttl); err != nil {
+			return cached, err
+		}
+
+		cached += len(batch)
+		if progress != nil {
+			progress(cached, len(active))
+		}
+	}
+
+	return cached, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/infra/memory"
+)
+
+// unbatchedCache hides the batch support of the memory cache
+type unbatchedCache struct {
+	core.TenantCache
+	sets int
+}
+
+func (c *unbatchedCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	c.sets++
+	return c.TenantCache.Set(ctx, tenant, ttl)
+}
+
+func TestTenantService_WarmCache(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewTenantRepository()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.Create(ctx, core.NewTenant(fmt.Sprintf("tenant-%d", i))))
+	}
+	inactive := core.NewTenant("inactive")
+	inactive.IsActive = false
+	require.NoError(t, repo.Create(ctx, inactive))
+
+	for name, cache := range map[string]core.TenantCache{
+		"batch":     memory.NewTenantCache(memory.CacheConfig{}),
+		"unbatched": &unbatchedCache{TenantCache: memory.NewTenantCache(memory.CacheConfig{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := NewTenantService(Config{Repository: repo, Cache: cache})
+
+			var reports [][2]int
+			cached, err := svc.WarmCache(ctx, 2, func(cached, total int) {
+				reports = append(reports, [2]int{cached, total})
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 5, cached)
+			assert.Equal(t, [][2]int{{2, 5}, {4, 5}, {5, 5}}, reports)
+
+			for i := 0; i < 5; i++ {
+				_, err := cache.Get(ctx, fmt.Sprintf("tenant-%d", i))
+				assert.NoError(t, err)
+			}
+			_, err = cache.Get(ctx, "inactive")
+			assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+			// Warmed tenants are served without reaching the repository
+			_, err = svc.GetTenant(ctx, "tenant-3")
+			require.NoError(t, err)
+			assert.Equal(t, LookupStats{CacheHits: 1}, svc.LookupStats())
+
+			if unbatched, ok := cache.(*unbatchedCache); ok {
+				assert.Equal(t, 5, unbatched.sets)
+			}
+		})
+	}
+}
+
+func TestTenantService_WarmCacheStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := memory.NewTenantRepository()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, repo.Create(ctx, core.NewTenant(fmt.Sprintf("tenant-%d", i))))
+	}
+	svc := NewTenantService(Config{Repository: repo, Cache: memory.NewTenantCache(memory.CacheConfig{})})
+
+	cached, err := svc.WarmCache(ctx, 1, func(cached, total int) {
+		if cached == 2 {
+			cancel()
+		}
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, cached)
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TemplateTenantNameVar is the template variable holding the name of the tenant being created
+const TemplateTenantNameVar = "TenantName"
+
+// Metadata keys written by TenantTemplate.Render
+const (
+	MetadataKeyPlan     = "plan"
+	MetadataKeyFeatures = "features"
+	MetadataKeyTemplate = "template"
+)
+
+// templateFuncs are available inside DSN placeholders, e.g. {{.TenantName | underscore}}
+var templateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"underscore": func(s string) string { return strings.ReplaceAll(s, "-", "_") },
+}
+
+// TenantTemplate describes the shape of tenants created from it
+type TenantTemplate struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Plan        string                 `json:"plan,omitempty"`
+	Features    map[string]bool        `json:"features,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Datasources []DatasourceTemplate   `json:"datasources"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// DatasourceTemplate describes a datasource whose DSN may contain placeholders such as {{.TenantName}}
+type DatasourceTemplate struct {
+	DSN      string                 `json:"dsn"`
+	Role     string                 `json:"role"`
+	PoolSize int                    `json:"pool_size"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// NewTenantTemplate creates a new template with generated ID and timestamps
+func NewTenantTemplate(name string) *TenantTemplate {
+	now := time.Now()
+	return &TenantTemplate{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Features:    make(map[string]bool),
+		Metadata:    make(map[string]interface{}),
+		Datasources: make([]DatasourceTemplate, 0),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// Validate validates the template data and parses every DSN placeholder
+func (t *TenantTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("template name cannot be empty")
+	}
+
+	if _, err := uuid.Parse(t.ID); err != nil {
+		return errors.New("template ID must be a valid UUID")
+	}
+
+	if err := ValidateLabels(t.Labels); err != nil {
+		return errors.New("labels validation failed: " + err.Error())
+	}
+
+	for i, ds := range t.Datasources {
+		if strings.TrimSpace(ds.DSN) == "" {
+			return fmt.Errorf("datasource template %d: DSN cannot be empty", i)
+		}
+		if _, err := parseDSNTemplate(ds.DSN); err != nil {
+			return fmt.Errorf("datasource template %d: invalid DSN placeholder: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Render creates a new tenant named name from the template, expanding DSN placeholders with vars
+func (t *TenantTemplate) Render(name string, vars map[string]string) (*Tenant, error) {
+	data := make(map[string]string, len(vars)+1)
+	for key, value := range vars {
+		data[key] = value
+	}
+	data[TemplateTenantNameVar] = name
+
+	tenant := NewTenant(name)
+	for key, value := range t.Metadata {
+		tenant.Metadata[key] = copyValue(value)
+	}
+
+	if t.Plan != "" {
+		tenant.Metadata[MetadataKeyPlan] = t.Plan
+	}
+
+	if len(t.Features) > 0 {
+		features := make(map[string]interface{}, len(t.Features))
+		for flag, enabled := range t.Features {
+			features[flag] = enabled
+		}
+		tenant.Metadata[MetadataKeyFeatures] = features
+	}
+
+	tenant.Metadata[MetadataKeyTemplate] = t.Name
+	tenant.Labels = cloneLabels(t.Labels)
+
+	for i, dsTemplate := range t.Datasources {
+		tmpl, err := parseDSNTemplate(dsTemplate.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("datasource template %d: %w", i, err)
+		}
+
+		var dsn bytes.Buffer
+		if err := tmpl.Execute(&dsn, data); err != nil {
+			return nil, fmt.Errorf("datasource template %d: %w", i, err)
+		}
+
+		poolSize := dsTemplate.PoolSize
+		if poolSize == 0 {
+			poolSize = 10
+		}
+
+		ds := NewDatasource(tenant.ID, dsn.String(), dsTemplate.Role, poolSize)
+		for key, value := range dsTemplate.Metadata {
+			ds.Metadata[key] = copyValue(value)
+		}
+		tenant.Datasources = append(tenant.Datasources, *ds)
+	}
+
+	if err := tenant.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+// TemplateFromTenant derives a template from an existing tenant, replacing the tenant
+// name in its DSNs with the {{.TenantName}} placeholder
+func TemplateFromTenant(tenant *Tenant) *TenantTemplate {
+	tmpl := NewTenantTemplate(tenant.Name)
+
+	for key, value := range tenant.Metadata {
+		if key == MetadataKeyTemplate {
+			continue
+		}
+		tmpl.Metadata[key] = copyValue(value)
+	}
+	tmpl.Labels = cloneLabels(tenant.Labels)
+
+	for _, ds := range tenant.Datasources {
+		dsTemplate := DatasourceTemplate{
+			DSN:      placeholderizeDSN(ds.DSN, tenant.Name),
+			Role:     ds.Role,
+			PoolSize: ds.PoolSize,
+			Metadata: make(map[string]interface{}, len(ds.Metadata)),
+		}
+		for key, value := range ds.Metadata {
+			dsTemplate.Metadata[key] = copyValue(value)
+		}
+		tmpl.Datasources = append(tmpl.Datasources, dsTemplate)
+	}
+
+	return tmpl
+}
+
+func parseDSNTemplate(dsn string) (*template.Template, error) {
+	return template.New("dsn").Funcs(templateFuncs).Option("missingkey=error").Parse(dsn)
+}
+
+// placeholderizeDSN replaces whole-word occurrences of the tenant name (and its
+// underscore form) with template placeholders, escaping any existing braces
+func placeholderizeDSN(dsn, name string) string {
+	dsn = strings.NewReplacer("{{", `{{"{{"}}`, "}}", `{{"}}"}}`).Replace(dsn)
+	if name == "" {
+		return dsn
+	}
+
+	candidates := []struct {
+		value       string
+		placeholder string
+	}{
+		{name, "{{." + TemplateTenantNameVar + "}}"},
+		{strings.ReplaceAll(name, "-", "_"), "{{." + TemplateTenantNameVar + " | underscore}}"},
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(dsn); {
+		replaced := false
+		for _, c := range candidates {
+			end := i + len(c.value)
+			if strings.HasPrefix(dsn[i:], c.value) &&
+				(i == 0 || !isWordByte(dsn[i-1])) &&
+				(end == len(dsn) || !isWordByte(dsn[end])) {
+				out.WriteString(c.placeholder)
+				i = end
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out.WriteByte(dsn[i])
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,224 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// DocumentVersion is the schema version written to every exported document
+const DocumentVersion = "multitenant/v1"
+
+// Format represents the serialization format of a document
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// FormatFromPath infers the document format from a file extension
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	default:
+		return "", core.ErrValidationFailed("document", fmt.Sprintf("unsupported file extension: %s", path))
+	}
+}
+
+// Document is the versioned representation of a set of tenants
+type Document struct {
+	Version    string       `json:"version" yaml:"version"`
+	ExportedAt time.Time    `json:"exported_at,omitempty" yaml:"exported_at,omitempty"`
+	Secrets    SecretMode   `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	Tenants    []TenantSpec `json:"tenants" yaml:"tenants"`
+}
+
+// TenantSpec describes a tenant inside a document
+type TenantSpec struct {
+	ID          string                 `json:"id,omitempty" yaml:"id,omitempty"`
+	Name        string                 `json:"name" yaml:"name"`
+	IsActive    *bool                  `json:"is_active,omitempty" yaml:"is_active,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Datasources []DatasourceSpec       `json:"datasources,omitempty" yaml:"datasources,omitempty"`
+}
+
+// DatasourceSpec describes a tenant datasource inside a document
+type DatasourceSpec struct {
+	ID       string                 `json:"id,omitempty" yaml:"id,omitempty"`
+	DSN      string                 `json:"dsn" yaml:"dsn"`
+	Role     string                 `json:"role" yaml:"role"`
+	PoolSize int                    `json:"pool_size,omitempty" yaml:"pool_size,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// NewDocument creates an empty document with the current version
+func NewDocument() *Document {
+	return &Document{
+		Version:    DocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		Secrets:    SecretsPlain,
+		Tenants:    make([]TenantSpec, 0),
+	}
+}
+
+// SpecFromTenant converts a tenant into its document representation
+func SpecFromTenant(tenant *core.Tenant) TenantSpec {
+	active := tenant.IsActive
+	spec := TenantSpec{
+		ID:          tenant.ID,
+		Name:        tenant.Name,
+		IsActive:    &active,
+		Metadata:    tenant.Metadata,
+		Labels:      tenant.Labels,
+		Datasources: make([]DatasourceSpec, 0, len(tenant.Datasources)),
+	}
+
+	for _, ds := range tenant.Datasources {
+		spec.Datasources = append(spec.Datasources, DatasourceSpec{
+			ID:       ds.ID,
+			DSN:      ds.DSN,
+			Role:     ds.Role,
+			PoolSize: ds.PoolSize,
+			Metadata: ds.Metadata,
+		})
+	}
+
+	return spec
+}
+
+// Active reports whether the tenant should be active, defaulting to true
+func (s *TenantSpec) Active() bool {
+	return s.IsActive == nil || *s.IsActive
+}
+
+// ToTenant converts the spec into a tenant, generating any missing IDs
+func (s *TenantSpec) ToTenant() *core.Tenant {
+	tenant := core.NewTenant(s.Name)
+	if s.ID != "" {
+		tenant.ID = s.ID
+	}
+	tenant.IsActive = s.Active()
+	if s.Metadata != nil {
+		tenant.Metadata = s.Metadata
+	}
+	tenant.Labels = s.Labels
+
+	for _, dsSpec := range s.Datasources {
+		poolSize := dsSpec.PoolSize
+		if poolSize == 0 {
+			poolSize = 10
+		}
+
+		ds := core.NewDatasource(tenant.ID, dsSpec.DSN, dsSpec.Role, poolSize)
+		if dsSpec.ID != "" {
+			ds.ID = dsSpec.ID
+		}
+		if dsSpec.Metadata != nil {
+			ds.Metadata = dsSpec.Metadata
+		}
+		tenant.Datasources = append(tenant.Datasources, *ds)
+	}
+
+	return tenant
+}
+
+// Validate checks the document version and the uniqueness of tenant names
+func (d *Document) Validate() error {
+	if d.Version != DocumentVersion {
+		return core.ErrValidationFailed("document",
+			fmt.Sprintf("unsupported document version: %q (expected %q)", d.Version, DocumentVersion))
+	}
+
+	seen := make(map[string]bool, len(d.Tenants))
+	for i, spec := range d.Tenants {
+		if strings.TrimSpace(spec.Name) == "" {
+			return core.ErrValidationFailed("document", fmt.Sprintf("tenant %d has no name", i))
+		}
+		if seen[spec.Name] {
+			return core.ErrValidationFailed("document", fmt.Sprintf("duplicate tenant name: %s", spec.Name))
+		}
+		seen[spec.Name] = true
+
+		if spec.ID != "" {
+			if _, err := uuid.Parse(spec.ID); err != nil {
+				return core.ErrValidationFailed("document",
+					fmt.Sprintf("tenant %s has an invalid ID: %s", spec.Name, spec.ID))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Encode writes the document to w in the given format
+func (d *Document) Encode(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(d); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return core.ErrValidationFailed("document", fmt.Sprintf("unsupported format: %s", format))
+	}
+}
+
+// Decode reads and validates a document in the given format
+func Decode(r io.Reader, format Format) (*Document, error) {
+	var doc Document
+
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			return nil, core.ErrValidationFailed("document", "invalid JSON document").WithCause(err)
+		}
+	case FormatYAML:
+		if err := yaml.NewDecoder(r).Decode(&doc); err != nil && err != io.EOF {
+			return nil, core.ErrValidationFailed("document", "invalid YAML document").WithCause(err)
+		}
+	default:
+		return nil, core.ErrValidationFailed("document", fmt.Sprintf("unsupported format: %s", format))
+	}
+
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// ReadFile decodes a document from a file, inferring the format from its extension
+func ReadFile(path string) (*Document, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Decode(file, format)
+}
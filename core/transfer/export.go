@@ -0,0 +1,80 @@
+package transfer
+
+import (
+	"context"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// ExportOptions controls which tenants are exported and how secrets are written
+type ExportOptions struct {
+	// Names restricts the export to the given tenants; empty exports all tenants
+	Names []string
+	// Secrets selects how datasource DSNs are written (defaults to SecretsPlain)
+	Secrets SecretMode
+	// EncryptionKey is required when Secrets is SecretsEncrypted
+	EncryptionKey []byte
+}
+
+// Export reads tenants from the repository into a versioned document
+func Export(ctx context.Context, repo core.TenantRepository, opts ExportOptions) (*Document, error) {
+	if opts.Secrets == "" {
+		opts.Secrets = SecretsPlain
+	}
+
+	tenants, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*core.Tenant, len(tenants))
+	for i := range tenants {
+		byName[tenants[i].Name] = &tenants[i]
+	}
+
+	selected := make([]*core.Tenant, 0, len(tenants))
+	if len(opts.Names) == 0 {
+		for i := range tenants {
+			selected = append(selected, &tenants[i])
+		}
+	} else {
+		for _, name := range opts.Names {
+			tenant, ok := byName[name]
+			if !ok {
+				return nil, core.ErrTenantNotFound(name)
+			}
+			selected = append(selected, tenant)
+		}
+	}
+
+	doc := NewDocument()
+	doc.Secrets = opts.Secrets
+
+	for _, tenant := range selected {
+		spec := SpecFromTenant(tenant)
+		for i := range spec.Datasources {
+			dsn, err := protectDSN(spec.Datasources[i].DSN, opts)
+			if err != nil {
+				return nil, err
+			}
+			spec.Datasources[i].DSN = dsn
+		}
+		doc.Tenants = append(doc.Tenants, spec)
+	}
+
+	return doc, nil
+}
+
+// protectDSN applies the configured secret mode to a single DSN
+func protectDSN(dsn string, opts ExportOptions) (string, error) {
+	switch opts.Secrets {
+	case SecretsPlain:
+		return dsn, nil
+	case SecretsRedacted:
+		return RedactDSN(dsn), nil
+	case SecretsEncrypted:
+		return EncryptDSN(dsn, opts.EncryptionKey)
+	default:
+		return "", core.ErrConfigInvalid("Secrets", "unsupported secret mode: "+string(opts.Secrets))
+	}
+}
@@ -0,0 +1,194 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// ConflictStrategy decides what happens when an imported tenant already exists
+type ConflictStrategy string
+
+const (
+	// ConflictSkip leaves existing tenants untouched
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictOverwrite replaces existing tenants with the imported definition
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictFail aborts the import before any write if a tenant already exists
+	ConflictFail ConflictStrategy = "fail"
+)
+
+// ImportOptions controls how a document is written to the repository
+type ImportOptions struct {
+	// Conflict selects the strategy for existing tenants (defaults to ConflictFail)
+	Conflict ConflictStrategy
+	// EncryptionKey is required to import documents with encrypted DSNs
+	EncryptionKey []byte
+	// AllowRedacted permits importing DSNs whose password was redacted on export
+	AllowRedacted bool
+	// DryRun computes the report without writing to the repository
+	DryRun bool
+}
+
+// ImportFailure describes a tenant that could not be imported
+type ImportFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarizes the outcome of an import
+type ImportReport struct {
+	Created []string        `json:"created"`
+	Updated []string        `json:"updated"`
+	Skipped []string        `json:"skipped"`
+	Failed  []ImportFailure `json:"failed"`
+	DryRun  bool            `json:"dry_run"`
+}
+
+// HasFailures reports whether any tenant failed to import
+func (r *ImportReport) HasFailures() bool {
+	return len(r.Failed) > 0
+}
+
+// String returns a one-line summary of the report
+func (r *ImportReport) String() string {
+	return fmt.Sprintf("created=%d updated=%d skipped=%d failed=%d",
+		len(r.Created), len(r.Updated), len(r.Skipped), len(r.Failed))
+}
+
+func (r *ImportReport) fail(name string, err error) {
+	r.Failed = append(r.Failed, ImportFailure{Name: name, Error: err.Error()})
+}
+
+// Import upserts the tenants of a document into the repository
+func Import(ctx context.Context, repo core.TenantRepository, doc *Document, opts ImportOptions) (*ImportReport, error) {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictFail
+	}
+
+	switch opts.Conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return nil, core.ErrConfigInvalid("Conflict", "unsupported conflict strategy: "+string(opts.Conflict))
+	}
+
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*core.Tenant, len(existing))
+	byID := make(map[string]*core.Tenant, len(existing))
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+		byID[existing[i].ID] = &existing[i]
+	}
+
+	if opts.Conflict == ConflictFail {
+		var conflicts []string
+		for _, spec := range doc.Tenants {
+			if _, ok := byName[spec.Name]; ok {
+				conflicts = append(conflicts, spec.Name)
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, core.ErrTenantExists(strings.Join(conflicts, ", "))
+		}
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun}
+
+	for _, spec := range doc.Tenants {
+		tenant, err := tenantFromSpec(spec, opts)
+		if err != nil {
+			report.fail(spec.Name, err)
+			continue
+		}
+
+		current, exists := byName[spec.Name]
+		if exists && opts.Conflict == ConflictSkip {
+			report.Skipped = append(report.Skipped, spec.Name)
+			continue
+		}
+
+		if exists {
+			rebind(tenant, current)
+		} else if owner, taken := byID[tenant.ID]; taken {
+			report.fail(spec.Name, fmt.Errorf("tenant ID %s already belongs to tenant %s", tenant.ID, owner.Name))
+			continue
+		}
+
+		if err := tenant.Validate(); err != nil {
+			report.fail(spec.Name, err)
+			continue
+		}
+
+		if !opts.DryRun {
+			if exists {
+				err = repo.Update(ctx, tenant)
+			} else {
+				err = repo.Create(ctx, tenant)
+			}
+			if err != nil {
+				report.fail(spec.Name, err)
+				continue
+			}
+		}
+
+		if exists {
+			report.Updated = append(report.Updated, spec.Name)
+		} else {
+			report.Created = append(report.Created, spec.Name)
+		}
+	}
+
+	return report, nil
+}
+
+// tenantFromSpec builds a tenant from a spec, restoring protected DSNs
+func tenantFromSpec(spec TenantSpec, opts ImportOptions) (*core.Tenant, error) {
+	tenant := spec.ToTenant()
+	if err := RestoreSecrets(tenant, opts.EncryptionKey, opts.AllowRedacted); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// RestoreSecrets decrypts encrypted DSNs in place and rejects redacted ones unless allowed
+func RestoreSecrets(tenant *core.Tenant, key []byte, allowRedacted bool) error {
+	for i := range tenant.Datasources {
+		ds := &tenant.Datasources[i]
+
+		if IsEncryptedDSN(ds.DSN) {
+			if len(key) == 0 {
+				return core.ErrConfigInvalid("EncryptionKey", "document contains encrypted DSNs but no key was provided")
+			}
+			dsn, err := DecryptDSN(ds.DSN, key)
+			if err != nil {
+				return err
+			}
+			ds.DSN = dsn
+		}
+
+		if IsRedactedDSN(ds.DSN) && !allowRedacted {
+			return core.ErrValidationFailed("datasource", "DSN password was redacted on export")
+		}
+	}
+
+	return nil
+}
+
+// rebind points an imported tenant at the identity of the existing one
+func rebind(tenant *core.Tenant, current *core.Tenant) {
+	tenant.ID = current.ID
+	tenant.CreatedAt = current.CreatedAt
+	for i := range tenant.Datasources {
+		tenant.Datasources[i].TenantID = current.ID
+	}
+}
@@ -0,0 +1,154 @@
+package transfer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// SecretMode controls how datasource credentials are written to a document
+type SecretMode string
+
+const (
+	// SecretsPlain writes DSNs as they are stored in the registry
+	SecretsPlain SecretMode = "plain"
+	// SecretsRedacted replaces passwords in DSNs with a placeholder
+	SecretsRedacted SecretMode = "redacted"
+	// SecretsEncrypted encrypts the whole DSN with AES-GCM
+	SecretsEncrypted SecretMode = "encrypted"
+)
+
+const (
+	// RedactedPassword is the placeholder written in place of redacted passwords
+	RedactedPassword = "REDACTED"
+
+	encryptedPrefix = "enc:v1:"
+)
+
+// keywordPasswordPattern matches passwords in keyword/value DSNs (password=secret), up to
+// the whitespace separating the next parameter
+var keywordPasswordPattern = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// queryPasswordPattern matches password parameters in URL query strings, up to the & of
+// the next parameter
+var queryPasswordPattern = regexp.MustCompile(`(?i)((?:^|&)password=)([^&]*)`)
+
+// RedactDSN replaces the password of a URL or keyword/value DSN with a placeholder. In
+// URLs both the user info and a password query parameter are redacted.
+func RedactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return keywordPasswordPattern.ReplaceAllString(dsn, "${1}"+RedactedPassword)
+	}
+
+	redacted := false
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), RedactedPassword)
+			redacted = true
+		}
+	}
+	if queryPasswordPattern.MatchString(u.RawQuery) {
+		u.RawQuery = queryPasswordPattern.ReplaceAllString(u.RawQuery, "${1}"+RedactedPassword)
+		redacted = true
+	}
+
+	if !redacted {
+		return dsn
+	}
+	return u.String()
+}
+
+// IsRedactedDSN reports whether a DSN contains a redacted password
+func IsRedactedDSN(dsn string) bool {
+	pattern := keywordPasswordPattern
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if u.User != nil {
+			if password, _ := u.User.Password(); password == RedactedPassword {
+				return true
+			}
+		}
+		dsn, pattern = u.RawQuery, queryPasswordPattern
+	}
+
+	for _, match := range pattern.FindAllStringSubmatch(dsn, -1) {
+		if match[2] == RedactedPassword {
+			return true
+		}
+	}
+	return false
+}
+
+// EncryptDSN encrypts a DSN with AES-GCM using a 16, 24 or 32 byte key
+func EncryptDSN(dsn string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(dsn), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptDSN reverses EncryptDSN
+func DecryptDSN(value string, key []byte) (string, error) {
+	if !IsEncryptedDSN(value) {
+		return "", core.ErrValidationFailed("datasource", "DSN is not encrypted")
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", core.ErrValidationFailed("datasource", "invalid encrypted DSN encoding").WithCause(err)
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", core.ErrValidationFailed("datasource", "encrypted DSN is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", core.ErrValidationFailed("datasource", "failed to decrypt DSN").WithCause(err)
+	}
+
+	return string(plain), nil
+}
+
+// IsEncryptedDSN reports whether a DSN was produced by EncryptDSN
+func IsEncryptedDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, encryptedPrefix)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, core.ErrConfigInvalid("EncryptionKey",
+			fmt.Sprintf("encryption key must be 16, 24 or 32 bytes, got: %d", len(key)))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
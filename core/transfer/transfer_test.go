@@ -0,0 +1,247 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// fakeRepository is a minimal core.TenantRepository backed by a map
+type fakeRepository struct {
+	tenants map[string]core.Tenant
+}
+
+func newFakeRepository(tenants ...*core.Tenant) *fakeRepository {
+	repo := &fakeRepository{tenants: make(map[string]core.Tenant)}
+	for _, tenant := range tenants {
+		repo.tenants[tenant.Name] = *tenant
+	}
+	return repo
+}
+
+func (r *fakeRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	tenant, ok := r.tenants[name]
+	if !ok {
+		return nil, core.TenantNotFoundError{Name: name}
+	}
+	return &tenant, nil
+}
+
+func (r *fakeRepository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	for _, tenant := range r.tenants {
+		if tenant.ID == id {
+			return &tenant, nil
+		}
+	}
+	return nil, core.TenantNotFoundError{Name: id}
+}
+
+func (r *fakeRepository) List(ctx context.Context) ([]core.Tenant, error) {
+	tenants := make([]core.Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+func (r *fakeRepository) Create(ctx context.Context, tenant *core.Tenant) error {
+	r.tenants[tenant.Name] = *tenant
+	return nil
+}
+
+func (r *fakeRepository) Update(ctx context.Context, tenant *core.Tenant) error {
+	r.tenants[tenant.Name] = *tenant
+	return nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, id string) error {
+	for name, tenant := range r.tenants {
+		if tenant.ID == id {
+			delete(r.tenants, name)
+			return nil
+		}
+	}
+	return core.TenantNotFoundError{Name: id}
+}
+
+func newTenantWithDSN(name, dsn string) *core.Tenant {
+	tenant := core.NewTenant(name)
+	tenant.Metadata["plan"] = "pro"
+	tenant.Datasources = append(tenant.Datasources, *core.NewDatasource(tenant.ID, dsn, "rw", 5))
+	return tenant
+}
+
+func TestExport_SelectedTenants(t *testing.T) {
+	repo := newFakeRepository(
+		newTenantWithDSN("alpha", "postgres://user:secret@db:5432/alpha"),
+		newTenantWithDSN("beta", "postgres://user:secret@db:5432/beta"),
+	)
+
+	doc, err := Export(context.Background(), repo, ExportOptions{Names: []string{"beta"}})
+	require.NoError(t, err)
+	assert.Equal(t, DocumentVersion, doc.Version)
+	require.Len(t, doc.Tenants, 1)
+	assert.Equal(t, "beta", doc.Tenants[0].Name)
+	assert.Equal(t, "postgres://user:secret@db:5432/beta", doc.Tenants[0].Datasources[0].DSN)
+
+	_, err = Export(context.Background(), repo, ExportOptions{Names: []string{"missing"}})
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+}
+
+func TestExport_Secrets(t *testing.T) {
+	repo := newFakeRepository(newTenantWithDSN("alpha", "postgres://user:secret@db:5432/alpha"))
+	ctx := context.Background()
+
+	t.Run("redacted", func(t *testing.T) {
+		doc, err := Export(ctx, repo, ExportOptions{Secrets: SecretsRedacted})
+		require.NoError(t, err)
+		dsn := doc.Tenants[0].Datasources[0].DSN
+		assert.NotContains(t, dsn, "secret")
+		assert.True(t, IsRedactedDSN(dsn))
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		key := bytes.Repeat([]byte("k"), 32)
+		doc, err := Export(ctx, repo, ExportOptions{Secrets: SecretsEncrypted, EncryptionKey: key})
+		require.NoError(t, err)
+		dsn := doc.Tenants[0].Datasources[0].DSN
+		assert.True(t, IsEncryptedDSN(dsn))
+
+		plain, err := DecryptDSN(dsn, key)
+		require.NoError(t, err)
+		assert.Equal(t, "postgres://user:secret@db:5432/alpha", plain)
+	})
+
+	t.Run("encrypted without key", func(t *testing.T) {
+		_, err := Export(ctx, repo, ExportOptions{Secrets: SecretsEncrypted})
+		assert.True(t, core.IsErrorCode(err, core.ErrCodeConfigInvalid))
+	})
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		dsn      string
+		expected string
+	}{
+		{"url with password", "postgres://user:secret@db:5432/app", "postgres://user:REDACTED@db:5432/app"},
+		{"url without password", "mongodb://db:27017/app", "mongodb://db:27017/app"},
+		{"keyword dsn", "host=db user=app password=secret dbname=app", "host=db user=app password=REDACTED dbname=app"},
+		{"keyword dsn with parameters after password", "password='s3 cret' sslmode=require connect_timeout=5",
+			"password=REDACTED sslmode=require connect_timeout=5"},
+		{"url query password", "postgres://db:5432/app?user=app&password=secret&sslmode=require",
+			"postgres://db:5432/app?user=app&password=REDACTED&sslmode=require"},
+		{"url with both passwords", "postgres://user:secret@db/app?Password=other&application_name=api",
+			"postgres://user:REDACTED@db/app?Password=REDACTED&application_name=api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RedactDSN(tt.dsn))
+			assert.Equal(t, tt.expected != tt.dsn, IsRedactedDSN(RedactDSN(tt.dsn)))
+		})
+	}
+}
+
+func TestDocument_EncodeDecode(t *testing.T) {
+	repo := newFakeRepository(newTenantWithDSN("alpha", "postgres://user:secret@db:5432/alpha"))
+	doc, err := Export(context.Background(), repo, ExportOptions{})
+	require.NoError(t, err)
+
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, doc.Encode(&buf, format))
+
+			decoded, err := Decode(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, decoded.Tenants, 1)
+			assert.Equal(t, doc.Tenants[0].ID, decoded.Tenants[0].ID)
+			assert.Equal(t, "pro", decoded.Tenants[0].Metadata["plan"])
+			assert.Equal(t, doc.Tenants[0].Datasources[0].DSN, decoded.Tenants[0].Datasources[0].DSN)
+		})
+	}
+}
+
+func TestDecode_RejectsUnknownVersion(t *testing.T) {
+	_, err := Decode(bytes.NewBufferString(`{"version": "multitenant/v0", "tenants": []}`), FormatJSON)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeValidationFailed))
+}
+
+func TestImport_ConflictStrategies(t *testing.T) {
+	ctx := context.Background()
+	existing := newTenantWithDSN("alpha", "postgres://user:old@db:5432/alpha")
+
+	doc := NewDocument()
+	imported := newTenantWithDSN("alpha", "postgres://user:new@db:5432/alpha")
+	doc.Tenants = append(doc.Tenants,
+		SpecFromTenant(imported),
+		SpecFromTenant(newTenantWithDSN("beta", "postgres://user:new@db:5432/beta")),
+	)
+
+	t.Run("skip", func(t *testing.T) {
+		repo := newFakeRepository(existing)
+		report, err := Import(ctx, repo, doc, ImportOptions{Conflict: ConflictSkip})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alpha"}, report.Skipped)
+		assert.Equal(t, []string{"beta"}, report.Created)
+		assert.Equal(t, "postgres://user:old@db:5432/alpha", repo.tenants["alpha"].Datasources[0].DSN)
+	})
+
+	t.Run("overwrite keeps the existing ID", func(t *testing.T) {
+		repo := newFakeRepository(existing)
+		report, err := Import(ctx, repo, doc, ImportOptions{Conflict: ConflictOverwrite})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alpha"}, report.Updated)
+		assert.Equal(t, existing.ID, repo.tenants["alpha"].ID)
+		assert.Equal(t, existing.ID, repo.tenants["alpha"].Datasources[0].TenantID)
+		assert.Equal(t, "postgres://user:new@db:5432/alpha", repo.tenants["alpha"].Datasources[0].DSN)
+	})
+
+	t.Run("fail aborts before writing", func(t *testing.T) {
+		repo := newFakeRepository(existing)
+		report, err := Import(ctx, repo, doc, ImportOptions{Conflict: ConflictFail})
+		assert.Nil(t, report)
+		assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantExists))
+		assert.NotContains(t, repo.tenants, "beta")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		repo := newFakeRepository(existing)
+		report, err := Import(ctx, repo, doc, ImportOptions{Conflict: ConflictOverwrite, DryRun: true})
+		require.NoError(t, err)
+		assert.Equal(t, "created=1 updated=1 skipped=0 failed=0", report.String())
+		assert.NotContains(t, repo.tenants, "beta")
+	})
+}
+
+func TestImport_ProtectedSecrets(t *testing.T) {
+	ctx := context.Background()
+	source := newFakeRepository(newTenantWithDSN("alpha", "postgres://user:secret@db:5432/alpha"))
+	key := bytes.Repeat([]byte("k"), 16)
+
+	encrypted, err := Export(ctx, source, ExportOptions{Secrets: SecretsEncrypted, EncryptionKey: key})
+	require.NoError(t, err)
+
+	target := newFakeRepository()
+	report, err := Import(ctx, target, encrypted, ImportOptions{EncryptionKey: key})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha"}, report.Created)
+	assert.Equal(t, "postgres://user:secret@db:5432/alpha", target.tenants["alpha"].Datasources[0].DSN)
+
+	redacted, err := Export(ctx, source, ExportOptions{Secrets: SecretsRedacted})
+	require.NoError(t, err)
+
+	report, err = Import(ctx, newFakeRepository(), redacted, ImportOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.HasFailures())
+
+	report, err = Import(ctx, newFakeRepository(), redacted, ImportOptions{AllowRedacted: true})
+	require.NoError(t, err)
+	assert.False(t, report.HasFailures())
+}
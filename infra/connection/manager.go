@@ -2,13 +2,17 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/victorximenis/multitenant/core"
 	"github.com/victorximenis/multitenant/tenantcontext"
@@ -27,6 +31,9 @@ type ConnectionManager struct {
 	mongoPools    map[string]map[string]*mongo.Client // tenant -> role -> client
 	mu            sync.RWMutex
 	defaultConfig ConnectionConfig
+	// creating collapses concurrent creations of the same pool or client
+	creating singleflight.Group
+	logger   *slog.Logger
 }
 
 type ConnectionConfig struct {
@@ -35,6 +42,8 @@ type ConnectionConfig struct {
 	MaxIdleTime time.Duration
 	MaxLifetime time.Duration
 	HealthCheck time.Duration
+	// Logger receives the pool lifecycle logs; defaults to slog.Default()
+	Logger *slog.Logger
 }
 
 func DefaultConnectionConfig() ConnectionConfig {
@@ -49,7 +58,9 @@ func DefaultConnectionConfig() ConnectionConfig {
 
 func NewConnectionManager(tenantService core.TenantService, config ConnectionConfig) *ConnectionManager {
 	if config.MaxPoolSize == 0 {
+		logger := config.Logger
 		config = DefaultConnectionConfig()
+		config.Logger = logger
 	}
 
 	return &ConnectionManager{
@@ -57,6 +68,7 @@ func NewConnectionManager(tenantService core.TenantService, config ConnectionCon
 		pgPools:       make(map[string]map[string]*pgxpool.Pool),
 		mongoPools:    make(map[string]map[string]*mongo.Client),
 		defaultConfig: config,
+		logger:        core.ComponentLogger(config.Logger, "connection"),
 	}
 }
 
@@ -80,26 +92,30 @@ func (m *ConnectionManager) GetPostgresPoolForTenant(ctx context.Context, tenant
 	}
 	m.mu.RUnlock()
 
-	// Get tenant configuration
-	tenant, err := m.tenantService.GetTenant(ctx, tenantName)
+	// Concurrent first requests share one pool instead of each creating one
+	pool, err := m.create(ctx, "postgres", tenantName, role, func(ctx context.Context) (interface{}, error) {
+		return m.createPostgresPool(ctx, tenantName, role)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Find matching datasource
-	var dsn string
-	var poolSize int
+	return pool.(*pgxpool.Pool), nil
+}
 
-	for _, ds := range tenant.Datasources {
-		if ds.Role == role || ds.Role == "rw" {
-			dsn = ds.DSN
-			poolSize = ds.PoolSize
-			break
-		}
+// createPostgresPool creates and stores the pool of a tenant and role, unless another
+// caller stored one first
+func (m *ConnectionManager) createPostgresPool(ctx context.Context, tenantName, role string) (*pgxpool.Pool, error) {
+	m.mu.RLock()
+	pool, ok := m.pgPools[tenantName][role]
+	m.mu.RUnlock()
+	if ok {
+		return pool, nil
 	}
 
-	if dsn == "" {
-		return nil, fmt.Errorf("no datasource found for tenant %s with role %s", tenantName, role)
+	dsn, poolSize, err := m.findDatasource(ctx, tenantName, role)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create pool configuration
@@ -120,7 +136,7 @@ func (m *ConnectionManager) GetPostgresPoolForTenant(ctx context.Context, tenant
 	poolConfig.HealthCheckPeriod = m.defaultConfig.HealthCheck
 
 	// Create pool
-	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -134,6 +150,8 @@ func (m *ConnectionManager) GetPostgresPoolForTenant(ctx context.Context, tenant
 	}
 
 	m.pgPools[tenantName][role] = pool
+	m.logger.InfoContext(ctx, "created PostgreSQL pool",
+		core.LogKeyTenantName, tenantName, core.LogKeyRole, role, "max_conns", poolConfig.MaxConns)
 
 	return pool, nil
 }
@@ -158,26 +176,30 @@ func (m *ConnectionManager) GetMongoClientForTenant(ctx context.Context, tenantN
 	}
 	m.mu.RUnlock()
 
-	// Get tenant configuration
-	tenant, err := m.tenantService.GetTenant(ctx, tenantName)
+	// Concurrent first requests share one client instead of each connecting one
+	client, err := m.create(ctx, "mongodb", tenantName, role, func(ctx context.Context) (interface{}, error) {
+		return m.createMongoClient(ctx, tenantName, role)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Find matching datasource
-	var dsn string
-	var poolSize int
+	return client.(*mongo.Client), nil
+}
 
-	for _, ds := range tenant.Datasources {
-		if ds.Role == role || ds.Role == "rw" {
-			dsn = ds.DSN
-			poolSize = ds.PoolSize
-			break
-		}
+// createMongoClient connects and stores the client of a tenant and role, unless another
+// caller stored one first
+func (m *ConnectionManager) createMongoClient(ctx context.Context, tenantName, role string) (*mongo.Client, error) {
+	m.mu.RLock()
+	client, ok := m.mongoPools[tenantName][role]
+	m.mu.RUnlock()
+	if ok {
+		return client, nil
 	}
 
-	if dsn == "" {
-		return nil, fmt.Errorf("no datasource found for tenant %s with role %s", tenantName, role)
+	dsn, poolSize, err := m.findDatasource(ctx, tenantName, role)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create client options
@@ -193,7 +215,7 @@ func (m *ConnectionManager) GetMongoClientForTenant(ctx context.Context, tenantN
 	clientOptions.SetMaxConnIdleTime(m.defaultConfig.MaxIdleTime)
 
 	// Create client
-	client, err := mongo.Connect(ctx, clientOptions)
+	client, err = mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -213,10 +235,91 @@ func (m *ConnectionManager) GetMongoClientForTenant(ctx context.Context, tenantN
 	}
 
 	m.mongoPools[tenantName][role] = client
+	m.logger.InfoContext(ctx, "connected MongoDB client", core.LogKeyTenantName, tenantName, core.LogKeyRole, role)
 
 	return client, nil
 }
 
+// WarmTenant creates the pools and clients of every datasource of a tenant ahead of its
+// first request: "read" and "write" ones for "rw" datasources. It returns how many are
+// ready, including those that already existed.
+func (m *ConnectionManager) WarmTenant(ctx context.Context, tenantName string) (int, error) {
+	tenant, err := m.tenantService.GetTenant(ctx, tenantName)
+	if err != nil {
+		return 0, err
+	}
+
+	ready := 0
+	var errs []error
+	for _, ds := range tenant.Datasources {
+		roles := []string{ds.Role}
+		if ds.Role == "rw" {
+			roles = []string{"read", "write"}
+		}
+
+		for _, role := range roles {
+			switch {
+			case strings.HasPrefix(ds.DSN, "postgres://"), strings.HasPrefix(ds.DSN, "postgresql://"):
+				_, err = m.GetPostgresPoolForTenant(ctx, tenantName, role)
+			case strings.HasPrefix(ds.DSN, "mongodb://"), strings.HasPrefix(ds.DSN, "mongodb+srv://"):
+				_, err = m.GetMongoClientForTenant(ctx, tenantName, role)
+			default:
+				continue
+			}
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("tenant %s role %s: %w", tenantName, role, err))
+				continue
+			}
+			ready++
+		}
+	}
+
+	return ready, errors.Join(errs...)
+}
+
+// findDatasource returns the DSN and pool size of the tenant datasource serving role
+func (m *ConnectionManager) findDatasource(ctx context.Context, tenantName, role string) (string, int, error) {
+	// Get tenant configuration
+	tenant, err := m.tenantService.GetTenant(ctx, tenantName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	for _, ds := range tenant.Datasources {
+		if ds.Role == role || ds.Role == "rw" {
+			return ds.DSN, ds.PoolSize, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("no datasource found for tenant %s with role %s", tenantName, role)
+}
+
+// create runs fn once for concurrent callers asking for the same kind, tenant and role,
+// with the context of the first caller, and hands every caller its result
+func (m *ConnectionManager) create(ctx context.Context, kind, tenantName, role string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	key := kind + "\x00" + tenantName + "\x00" + role
+	result := m.creating.DoChan(key, func() (interface{}, error) {
+		return fn(ctx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		// The first caller gave up; create again with our own context
+		if res.Err != nil && res.Shared && isContextError(res.Err) && ctx.Err() == nil {
+			return fn(ctx)
+		}
+		return res.Val, res.Err
+	}
+}
+
+// isContextError reports whether err comes from a canceled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 func (m *ConnectionManager) CloseAll(ctx context.Context) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -233,9 +336,44 @@ func (m *ConnectionManager) CloseAll(ctx context.Context) {
 	// Close all MongoDB clients
 	for tenant, clients := range m.mongoPools {
 		for role, client := range clients {
-			client.Disconnect(ctx)
+			m.disconnect(ctx, tenant, role, client)
 			delete(clients, role)
 		}
 		delete(m.mongoPools, tenant)
 	}
 }
+
+// RefreshTenant closes the pools and clients of a tenant so they are recreated with its
+// current datasources on next use
+func (m *ConnectionManager) RefreshTenant(ctx context.Context, tenantName string) {
+	m.mu.Lock()
+	pools := m.pgPools[tenantName]
+	clients := m.mongoPools[tenantName]
+	delete(m.pgPools, tenantName)
+	delete(m.mongoPools, tenantName)
+	m.mu.Unlock()
+
+	for _, pool := range pools {
+		pool.Close()
+	}
+	for role, client := range clients {
+		m.disconnect(ctx, tenantName, role, client)
+	}
+	if len(pools) > 0 || len(clients) > 0 {
+		m.logger.InfoContext(ctx, "closed tenant connections", core.LogKeyTenantName, tenantName,
+			"pools", len(pools), "clients", len(clients))
+	}
+}
+
+// disconnect closes a MongoDB client, logging failures since nobody waits for them
+func (m *ConnectionManager) disconnect(ctx context.Context, tenantName, role string, client *mongo.Client) {
+	if err := client.Disconnect(ctx); err != nil {
+		m.logger.WarnContext(ctx, "failed to disconnect MongoDB client",
+			core.LogKeyTenantName, tenantName, core.LogKeyRole, role, core.LogKeyError, err)
+	}
+}
+
+// RefreshAll closes every pool and client so they are recreated on next use
+func (m *ConnectionManager) RefreshAll(ctx context.Context) {
+	m.CloseAll(ctx)
+}
@@ -0,0 +1,73 @@
+package file
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+const DEFAULT_POLL_INTERVAL = 2 * time.Second
+
+// Config holds configuration for the file-backed tenant repository
+type Config struct {
+	// Path is a YAML/JSON tenant document or a directory of documents
+	Path string
+	// PollInterval controls how often the files are checked for changes;
+	// zero uses DEFAULT_POLL_INTERVAL and a negative value disables watching
+	PollInterval time.Duration
+	// Writable persists Create/Update/Delete back to the file instead of
+	// returning a read-only error. Only supported when Path is a single file.
+	Writable bool
+	// EncryptionKey decrypts DSNs stored in encrypted form
+	EncryptionKey []byte
+	// OnReload is called after every reload attempt with its result
+	OnReload func(err error)
+	// Logger receives the reload logs; defaults to slog.Default()
+	Logger *slog.Logger
+}
+
+// ParseDSN builds a Config from a plain path or a file:// URL.
+// The URL form accepts the query parameters writable and poll_interval,
+// e.g. file:///etc/tenants.yaml?writable=true&poll_interval=5s
+func ParseDSN(dsn string) (Config, error) {
+	if !strings.HasPrefix(dsn, "file://") {
+		if dsn == "" {
+			return Config{}, core.ErrConfigInvalid("DatabaseDSN", "tenant file path is required")
+		}
+		return Config{Path: dsn}, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return Config{}, core.ErrConfigInvalid("DatabaseDSN", fmt.Sprintf("invalid file DSN: %v", err))
+	}
+
+	config := Config{Path: u.Host + u.Path}
+	if config.Path == "" {
+		return Config{}, core.ErrConfigInvalid("DatabaseDSN", "tenant file path is required")
+	}
+
+	query := u.Query()
+	if value := query.Get("writable"); value != "" {
+		writable, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, core.ErrConfigInvalid("DatabaseDSN", fmt.Sprintf("invalid writable value: %s", value))
+		}
+		config.Writable = writable
+	}
+
+	if value := query.Get("poll_interval"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, core.ErrConfigInvalid("DatabaseDSN", fmt.Sprintf("invalid poll interval: %s", value))
+		}
+		config.PollInterval = interval
+	}
+
+	return config, nil
+}
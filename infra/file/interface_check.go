@@ -0,0 +1,12 @@
+package file
+
+import "github.com/victorximenis/multitenant/core"
+
+// Compile-time check to ensure the file-backed repository satisfies the core interface
+var _ core.TenantRepository = (*TenantRepository)(nil)
+
+// Compile-time check to ensure the file-backed repository supports metadata queries
+var _ core.MetadataQuerier = (*TenantRepository)(nil)
+
+// Compile-time check to ensure the file-backed repository supports label selectors
+var _ core.LabelLister = (*TenantRepository)(nil)
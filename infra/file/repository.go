@@ -0,0 +1,224 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/transfer"
+	"github.com/victorximenis/multitenant/infra/memory"
+)
+
+// state is an immutable snapshot of the tenants loaded from disk
+type state struct {
+	store       *memory.TenantRepository
+	fingerprint [32]byte
+	secrets     transfer.SecretMode
+}
+
+// TenantRepository implements core.TenantRepository on top of YAML/JSON tenant documents.
+// The files are polled for changes and reloaded atomically; an invalid update is rejected
+// and the last good state keeps being served.
+type TenantRepository struct {
+	config  Config
+	current atomic.Pointer[state]
+	logger  *slog.Logger
+
+	// mu serializes reloads and writes
+	mu       sync.Mutex
+	lastErr  error
+	rejected [32]byte
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTenantRepository loads the tenant documents and starts watching them for changes
+func NewTenantRepository(ctx context.Context, config Config) (*TenantRepository, error) {
+	if config.Path == "" {
+		return nil, core.ErrConfigInvalid("Path", "tenant file path is required")
+	}
+
+	info, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, core.ErrConfigInvalid("Path", fmt.Sprintf("cannot access %s", config.Path)).WithCause(err)
+	}
+
+	if config.Writable && info.IsDir() {
+		return nil, core.ErrConfigInvalid("Writable", "persisting changes requires a single tenant file, not a directory")
+	}
+
+	if config.PollInterval == 0 {
+		config.PollInterval = DEFAULT_POLL_INTERVAL
+	}
+
+	repo := &TenantRepository{
+		config: config,
+		logger: core.ComponentLogger(config.Logger, "file"),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if err := repo.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	if config.PollInterval > 0 {
+		go repo.watch()
+	} else {
+		close(repo.done)
+	}
+
+	return repo, nil
+}
+
+// Close stops watching the tenant files
+func (r *TenantRepository) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+// LastError returns the error of the most recent reload, or nil if it succeeded
+func (r *TenantRepository) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lastErr
+}
+
+// GetByName retrieves a tenant by name with all its datasources
+func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	return r.current.Load().store.GetByName(ctx, name)
+}
+
+// GetByID retrieves a tenant by ID with all its datasources
+func (r *TenantRepository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return r.current.Load().store.GetByID(ctx, id)
+}
+
+// List retrieves all tenants ordered by name
+func (r *TenantRepository) List(ctx context.Context) ([]core.Tenant, error) {
+	return r.current.Load().store.List(ctx)
+}
+
+// FindByMetadata returns the tenants whose metadata matches query, ordered by name
+func (r *TenantRepository) FindByMetadata(ctx context.Context, query core.MetadataQuery) ([]core.Tenant, error) {
+	return r.current.Load().store.FindByMetadata(ctx, query)
+}
+
+// ListByLabels returns the tenants whose labels match selector, ordered by name
+func (r *TenantRepository) ListByLabels(ctx context.Context, selector core.LabelSelector) ([]core.Tenant, error) {
+	return r.current.Load().store.ListByLabels(ctx, selector)
+}
+
+// Create adds a tenant to the file, or returns a read-only error
+func (r *TenantRepository) Create(ctx context.Context, tenant *core.Tenant) error {
+	return r.write(ctx, "create", func(store *memory.TenantRepository) error {
+		return store.Create(ctx, tenant)
+	})
+}
+
+// Update replaces a tenant in the file, or returns a read-only error
+func (r *TenantRepository) Update(ctx context.Context, tenant *core.Tenant) error {
+	return r.write(ctx, "update", func(store *memory.TenantRepository) error {
+		return store.Update(ctx, tenant)
+	})
+}
+
+// Delete removes a tenant by ID from the file, or returns a read-only error
+func (r *TenantRepository) Delete(ctx context.Context, id string) error {
+	return r.write(ctx, "delete", func(store *memory.TenantRepository) error {
+		return store.Delete(ctx, id)
+	})
+}
+
+// write applies a change to a copy of the current state, persists it and swaps it in
+func (r *TenantRepository) write(ctx context.Context, operation string, apply func(*memory.TenantRepository) error) error {
+	if !r.config.Writable {
+		return core.ErrReadOnly(operation)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.current.Load()
+
+	next, err := copyStore(ctx, current.store)
+	if err != nil {
+		return err
+	}
+
+	if err := apply(next); err != nil {
+		return err
+	}
+
+	data, err := r.encode(ctx, next, current.secrets)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(r.config.Path, data); err != nil {
+		return fmt.Errorf("failed to persist tenants to %s: %w", r.config.Path, err)
+	}
+
+	r.current.Store(&state{
+		store:       next,
+		fingerprint: fingerprint([]source{{path: r.config.Path, data: data}}),
+		secrets:     current.secrets,
+	})
+
+	return nil
+}
+
+// encode serializes the store in the format of the configured file
+func (r *TenantRepository) encode(ctx context.Context, store *memory.TenantRepository, secrets transfer.SecretMode) ([]byte, error) {
+	format, err := transfer.FormatFromPath(r.config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A redacted document cannot be loaded, so never write one back
+	if secrets != transfer.SecretsEncrypted {
+		secrets = transfer.SecretsPlain
+	}
+
+	doc, err := transfer.Export(ctx, store, transfer.ExportOptions{
+		Secrets:       secrets,
+		EncryptionKey: r.config.EncryptionKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Encode(&buf, format); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// copyStore creates an independent copy of an in-memory store
+func copyStore(ctx context.Context, store *memory.TenantRepository) (*memory.TenantRepository, error) {
+	tenants, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	next := memory.NewTenantRepository()
+	for i := range tenants {
+		if err := next.Create(ctx, &tenants[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return next, nil
+}
@@ -0,0 +1,251 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/transfer"
+	"github.com/victorximenis/multitenant/infra/memory"
+)
+
+// idNamespace is the UUIDv5 namespace of the IDs derived by assignStableIDs
+var idNamespace = uuid.MustParse("7e684517-fb6c-40f4-83cd-0e6c8a242e59")
+
+// source is the raw content of a single tenant document
+type source struct {
+	path string
+	data []byte
+}
+
+// Reload re-reads the tenant documents and swaps them in if they changed.
+// On error the previous state is kept and the error is returned.
+func (r *TenantRepository) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed, err := r.reload(ctx)
+	if !changed {
+		return err
+	}
+
+	if err != nil {
+		r.logger.WarnContext(ctx, "failed to reload tenant files, keeping the previous tenants",
+			"path", r.config.Path, core.LogKeyError, err)
+	} else {
+		r.logger.InfoContext(ctx, "loaded tenant files", "path", r.config.Path)
+	}
+	if r.config.OnReload != nil {
+		r.config.OnReload(err)
+	}
+
+	return err
+}
+
+// reload does the work of Reload and reports whether the files differed from the current state.
+// It must be called with r.mu held.
+func (r *TenantRepository) reload(ctx context.Context) (bool, error) {
+	sources, err := readSources(r.config.Path)
+	if err != nil {
+		r.lastErr = err
+		return true, err
+	}
+
+	sum := fingerprint(sources)
+	if current := r.current.Load(); current != nil && current.fingerprint == sum {
+		return false, nil
+	}
+	if r.lastErr != nil && r.rejected == sum {
+		// Already rejected this exact content, keep serving the last good state quietly
+		return false, r.lastErr
+	}
+
+	next, err := r.build(ctx, sources)
+	if err != nil {
+		r.lastErr = err
+		r.rejected = sum
+		return true, err
+	}
+
+	next.fingerprint = sum
+	r.current.Store(next)
+	r.lastErr = nil
+
+	return true, nil
+}
+
+// build decodes and validates every document into a fresh in-memory store
+func (r *TenantRepository) build(ctx context.Context, sources []source) (*state, error) {
+	next := &state{
+		store:   memory.NewTenantRepository(),
+		secrets: transfer.SecretsPlain,
+	}
+
+	for _, src := range sources {
+		format, err := transfer.FormatFromPath(src.path)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := transfer.Decode(bytes.NewReader(src.data), format)
+		if err != nil {
+			return nil, withPath(err, src.path)
+		}
+		if doc.Secrets != "" {
+			next.secrets = doc.Secrets
+		}
+
+		for _, spec := range doc.Tenants {
+			tenant := spec.ToTenant()
+			assignStableIDs(tenant, &spec)
+			if err := transfer.RestoreSecrets(tenant, r.config.EncryptionKey, false); err != nil {
+				return nil, withPath(err, src.path)
+			}
+
+			// The in-memory store validates tenants and enforces unique names across files
+			if err := next.store.Create(ctx, tenant); err != nil {
+				return nil, withPath(err, src.path)
+			}
+		}
+	}
+
+	return next, nil
+}
+
+// assignStableIDs derives the IDs of a tenant and datasources declared without one from
+// the tenant name, replacing the random IDs from ToTenant, so that they stay the same
+// across reloads and GetByID, Delete and cached ID lookups keep working
+func assignStableIDs(tenant *core.Tenant, spec *transfer.TenantSpec) {
+	if spec.ID == "" {
+		tenant.ID = uuid.NewSHA1(idNamespace, []byte(spec.Name)).String()
+	}
+
+	for i := range tenant.Datasources {
+		ds := &tenant.Datasources[i]
+		ds.TenantID = tenant.ID
+		if spec.Datasources[i].ID == "" {
+			ds.ID = uuid.NewSHA1(idNamespace, []byte(tenant.ID+"/"+strconv.Itoa(i))).String()
+		}
+	}
+}
+
+// watch polls the tenant files until the repository is closed
+func (r *TenantRepository) watch() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			// Errors are recorded in lastErr and reported through OnReload
+			_ = r.Reload(context.Background())
+		}
+	}
+}
+
+// readSources reads the tenant document at path, or every document in the directory at path
+func readSources(path string) ([]source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []source{{path: path, data: data}}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries are sorted by name, which keeps the fingerprint stable
+	sources := make([]source, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := filepath.Join(path, entry.Name())
+		if _, err := transfer.FormatFromPath(name); err != nil {
+			continue
+		}
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source{path: name, data: data})
+	}
+
+	return sources, nil
+}
+
+// fingerprint hashes the paths and contents of a set of documents
+func fingerprint(sources []source) [32]byte {
+	hash := sha256.New()
+	for _, src := range sources {
+		hash.Write([]byte(src.path))
+		hash.Write([]byte{0})
+		hash.Write(src.data)
+		hash.Write([]byte{0})
+	}
+
+	var sum [32]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// withPath records the offending file on structured errors
+func withPath(err error, path string) error {
+	if mtErr, ok := err.(*core.MultitenantError); ok {
+		return mtErr.WithDetail("path", path)
+	}
+	return err
+}
+
+// writeFileAtomic replaces path with data so readers never observe a partial file
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
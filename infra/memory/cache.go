@@ -0,0 +1,330 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+const DEFAULT_TTL = 5 * time.Minute
+
+// cacheEntry holds a cached tenant, the time it stops being fresh and the time it is
+// dropped. A nil tenant marks a name remembered as not found.
+type cacheEntry struct {
+	name       string
+	tenant     *core.Tenant
+	freshUntil time.Time
+	expiresAt  time.Time
+}
+
+// TenantCache implements core.TenantCache in process memory with per-entry TTLs.
+// Entries are keyed by name and indexed by tenant ID. With MaxEntries set, the least
+// recently used entry is evicted when the cache is full.
+type TenantCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	ids        map[string]string
+	lru        *list.List
+	ttl        time.Duration
+	staleTTL   time.Duration
+	maxEntries int
+	evictions  uint64
+	now        func() time.Time
+}
+
+// CacheConfig holds configuration for the in-memory tenant cache
+type CacheConfig struct {
+	TTL time.Duration
+	// MaxEntries bounds the number of cached tenants; 0 means unbounded
+	MaxEntries int
+	// StaleTTL keeps tenants this long after they expire, for GetEntry to serve stale
+	StaleTTL time.Duration
+}
+
+// NewTenantCache creates an in-memory tenant cache
+func NewTenantCache(config CacheConfig) *TenantCache {
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+
+	maxEntries := config.MaxEntries
+	if maxEntries < 0 {
+		maxEntries = 0
+	}
+
+	return &TenantCache{
+		entries:    make(map[string]*list.Element),
+		ids:        make(map[string]string),
+		lru:        list.New(),
+		ttl:        ttl,
+		staleTTL:   max(config.StaleTTL, 0),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// Get returns a fresh cached tenant, or a TENANT_NOT_FOUND error if it is missing or expired.
+// Names stored with SetNotFound return an error matched by core.IsCachedNotFound.
+func (c *TenantCache) Get(ctx context.Context, name string) (*core.Tenant, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !c.now().Before(entry.freshUntil) {
+		return nil, core.ErrTenantNotFound(name)
+	}
+
+	return core.CopyTenant(entry.tenant), nil
+}
+
+// GetEntry returns a cached tenant with its freshness, including stale tenants kept for
+// StaleTTL after they expired
+func (c *TenantCache) GetEntry(ctx context.Context, name string) (*core.CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &core.CacheEntry{Tenant: core.CopyTenant(entry.tenant), FreshUntil: entry.freshUntil}, nil
+}
+
+// lookup returns the unexpired entry for name and marks it as recently used; the caller
+// must hold the lock
+func (c *TenantCache) lookup(name string) (*cacheEntry, error) {
+	element, ok := c.entries[name]
+	if !ok {
+		return nil, core.ErrTenantNotFound(name)
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(name)
+		return nil, core.ErrTenantNotFound(name)
+	}
+
+	c.lru.MoveToFront(element)
+	if entry.tenant == nil {
+		return nil, core.ErrCachedNotFound(name)
+	}
+	return entry, nil
+}
+
+// GetByID returns a cached tenant by ID, or a TENANT_NOT_FOUND error if it is missing or expired
+func (c *TenantCache) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	c.mu.Lock()
+	name, ok := c.ids[id]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, core.ErrTenantNotFound(id)
+	}
+
+	tenant, err := c.Get(ctx, name)
+	if err != nil || tenant.ID != id {
+		return nil, core.ErrTenantNotFound(id)
+	}
+
+	return tenant, nil
+}
+
+// Set stores a tenant for ttl, falling back to the configured TTL when ttl <= 0
+func (c *TenantCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	if tenant == nil {
+		return core.ErrTenantInvalid("", "tenant cannot be nil")
+	}
+
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	freshUntil := c.now().Add(ttl)
+	c.store(tenant, freshUntil, freshUntil.Add(c.staleTTL))
+
+	return nil
+}
+
+// SetMany stores tenants for ttl, falling back to the configured TTL when ttl <= 0
+func (c *TenantCache) SetMany(ctx context.Context, tenants []*core.Tenant, ttl time.Duration) error {
+	for _, tenant := range tenants {
+		if tenant == nil {
+			return core.ErrTenantInvalid("", "tenant cannot be nil")
+		}
+	}
+
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	freshUntil := c.now().Add(ttl)
+	for _, tenant := range tenants {
+		c.store(tenant, freshUntil, freshUntil.Add(c.staleTTL))
+	}
+
+	return nil
+}
+
+// SetEntry stores a tenant reported fresh until entry.FreshUntil, as read from another
+// cache, and drops it after at most ttl. A zero FreshUntil is treated like Set.
+func (c *TenantCache) SetEntry(ctx context.Context, entry core.CacheEntry, ttl time.Duration) error {
+	if entry.Tenant == nil {
+		return core.ErrTenantInvalid("", "tenant cannot be nil")
+	}
+	if entry.FreshUntil.IsZero() {
+		return c.Set(ctx, entry.Tenant, ttl)
+	}
+
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := entry.FreshUntil.Add(c.staleTTL)
+	if limit := c.now().Add(ttl); limit.Before(expiresAt) {
+		expiresAt = limit
+	}
+	c.store(entry.Tenant, entry.FreshUntil, expiresAt)
+
+	return nil
+}
+
+// store replaces the entries of tenant; the caller must hold the lock
+func (c *TenantCache) store(tenant *core.Tenant, freshUntil, expiresAt time.Time) {
+	// Drop entries made stale by a rename or by another tenant taking over the name
+	if previous, ok := c.ids[tenant.ID]; ok && previous != tenant.Name {
+		c.remove(previous)
+	}
+	c.remove(tenant.Name)
+
+	c.entries[tenant.Name] = c.lru.PushFront(&cacheEntry{
+		name:       tenant.Name,
+		tenant:     core.CopyTenant(tenant),
+		freshUntil: freshUntil,
+		expiresAt:  expiresAt,
+	})
+	c.ids[tenant.ID] = tenant.Name
+	c.evict()
+}
+
+// SetNotFound remembers name as not found for ttl, falling back to the configured TTL when ttl <= 0
+func (c *TenantCache) SetNotFound(ctx context.Context, name string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(name)
+	expiresAt := c.now().Add(ttl)
+	c.entries[name] = c.lru.PushFront(&cacheEntry{
+		name:       name,
+		freshUntil: expiresAt,
+		expiresAt:  expiresAt,
+	})
+	c.evict()
+
+	return nil
+}
+
+// evict drops least recently used entries until MaxEntries is respected; the caller must hold the lock
+func (c *TenantCache) evict() {
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back().Value.(*cacheEntry).name)
+		c.evictions++
+	}
+}
+
+// Delete removes a tenant from the cache
+func (c *TenantCache) Delete(ctx context.Context, name string) error {
+	c.mu.Lock()
+	c.remove(name)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// DeleteAll removes all tenants from the cache
+func (c *TenantCache) DeleteAll(ctx context.Context) error {
+	c.mu.Lock()
+	c.entries = make(map[string]*list.Element)
+	c.ids = make(map[string]string)
+	c.lru.Init()
+	c.mu.Unlock()
+
+	return nil
+}
+
+// DeleteLocal removes all tenants from the cache, which only lives in this process
+func (c *TenantCache) DeleteLocal(ctx context.Context) error {
+	return c.DeleteAll(ctx)
+}
+
+// DeleteLocalName removes a tenant from the cache, which only lives in this process
+func (c *TenantCache) DeleteLocalName(ctx context.Context, name string) error {
+	return c.Delete(ctx, name)
+}
+
+// DeleteExpired removes every expired entry and returns how many were evicted
+func (c *TenantCache) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	evicted := 0
+	for name, element := range c.entries {
+		if !now.Before(element.Value.(*cacheEntry).expiresAt) {
+			c.remove(name)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// Len returns the number of entries currently held, including expired ones not yet evicted
+func (c *TenantCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Evictions returns how many entries were evicted to respect MaxEntries
+func (c *TenantCache) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.evictions
+}
+
+// remove deletes the entry for name and its ID index; the caller must hold the lock
+func (c *TenantCache) remove(name string) {
+	element, ok := c.entries[name]
+	if !ok {
+		return
+	}
+
+	entry := c.lru.Remove(element).(*cacheEntry)
+	delete(c.entries, name)
+	if entry.tenant != nil && c.ids[entry.tenant.ID] == name {
+		delete(c.ids, entry.tenant.ID)
+	}
+}
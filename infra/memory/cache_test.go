@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/conformance"
+)
+
+func TestTenantCache_SetAndGet(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{})
+	ctx := context.Background()
+
+	tenant := newTestTenant("acme")
+	require.NoError(t, cache.Set(ctx, tenant, 0))
+
+	result, err := cache.Get(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, tenant.ID, result.ID)
+
+	_, err = cache.Get(ctx, "missing")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	assert.Error(t, cache.Set(ctx, nil, 0))
+}
+
+func TestTenantCache_HonoursTTL(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(ctx, newTestTenant("acme"), 0))
+	require.NoError(t, cache.Set(ctx, newTestTenant("globex"), 10*time.Second))
+
+	now = now.Add(30 * time.Second)
+	_, err := cache.Get(ctx, "acme")
+	assert.NoError(t, err)
+	_, err = cache.Get(ctx, "globex")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	now = now.Add(time.Minute)
+	_, err = cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestTenantCache_DeleteExpired(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(ctx, newTestTenant("acme"), time.Second))
+	require.NoError(t, cache.Set(ctx, newTestTenant("globex"), time.Hour))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, 1, cache.DeleteExpired())
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestTenantCache_DeleteAndDeleteAll(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{})
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, newTestTenant("acme"), 0))
+	require.NoError(t, cache.Set(ctx, newTestTenant("globex"), 0))
+
+	require.NoError(t, cache.Delete(ctx, "acme"))
+	_, err := cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	require.NoError(t, cache.DeleteAll(ctx))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestTenantCache_CopiesIsolateCallers(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{})
+	ctx := context.Background()
+
+	tenant := newTestTenant("acme")
+	tenant.Metadata["plan"] = "pro"
+	require.NoError(t, cache.Set(ctx, tenant, 0))
+	tenant.Metadata["plan"] = "free"
+
+	result, err := cache.Get(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "pro", result.Metadata["plan"])
+}
+
+func TestTenantCache_RenameDropsPreviousName(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{})
+	ctx := context.Background()
+
+	tenant := newTestTenant("acme")
+	require.NoError(t, cache.Set(ctx, tenant, 0))
+
+	renamed := *tenant
+	renamed.Name = "acme-corp"
+	require.NoError(t, cache.Set(ctx, &renamed, 0))
+
+	_, err := cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	result, err := cache.GetByID(ctx, tenant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme-corp", result.Name)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestTenantCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{MaxEntries: 2})
+	ctx := context.Background()
+
+	acme, globex, initech := newTestTenant("acme"), newTestTenant("globex"), newTestTenant("initech")
+	require.NoError(t, cache.Set(ctx, acme, 0))
+	require.NoError(t, cache.Set(ctx, globex, 0))
+
+	// Reading acme makes globex the least recently used entry
+	_, err := cache.Get(ctx, "acme")
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, initech, 0))
+
+	_, err = cache.Get(ctx, "globex")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	_, err = cache.GetByID(ctx, globex.ID)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	_, err = cache.Get(ctx, "acme")
+	assert.NoError(t, err)
+	_, err = cache.Get(ctx, "initech")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, cache.Len())
+	assert.Equal(t, uint64(1), cache.Evictions())
+}
+
+func TestTenantCache_Conformance(t *testing.T) {
+	conformance.RunCacheTests(t, func(t *testing.T) core.TenantCache {
+		return NewTenantCache(CacheConfig{})
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		conformance.RunCacheTests(t, func(t *testing.T) core.TenantCache {
+			return NewTenantCache(CacheConfig{MaxEntries: 16})
+		})
+	})
+}
+
+func TestTenantCache_NotFoundEntries(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.SetNotFound(ctx, "ghost", 10*time.Second))
+	_, err := cache.Get(ctx, "ghost")
+	assert.True(t, core.IsCachedNotFound(err))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	_, err = cache.Get(ctx, "missing")
+	assert.False(t, core.IsCachedNotFound(err))
+
+	// Storing the tenant replaces the negative entry
+	tenant := newTestTenant("ghost")
+	require.NoError(t, cache.Set(ctx, tenant, 0))
+	result, err := cache.Get(ctx, "ghost")
+	require.NoError(t, err)
+	assert.Equal(t, tenant.ID, result.ID)
+
+	require.NoError(t, cache.SetNotFound(ctx, "phantom", 10*time.Second))
+	_, err = cache.GetByID(ctx, tenant.ID)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = cache.Get(ctx, "phantom")
+	assert.False(t, core.IsCachedNotFound(err))
+	assert.Equal(t, 1, cache.DeleteExpired())
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestTenantCache_StaleEntries(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{TTL: time.Minute, StaleTTL: time.Hour})
+	ctx := context.Background()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	tenant := newTestTenant("acme")
+	require.NoError(t, cache.Set(ctx, tenant, 0))
+
+	entry, err := cache.GetEntry(ctx, "acme")
+	require.NoError(t, err)
+	assert.True(t, entry.Fresh(now))
+	assert.Equal(t, now.Add(time.Minute), entry.FreshUntil)
+
+	// Expired tenants are only served by GetEntry, until StaleTTL runs out
+	now = now.Add(30 * time.Minute)
+	_, err = cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	entry, err = cache.GetEntry(ctx, "acme")
+	require.NoError(t, err)
+	assert.False(t, entry.Fresh(now))
+	assert.Equal(t, tenant.ID, entry.Tenant.ID)
+
+	now = now.Add(time.Hour)
+	_, err = cache.GetEntry(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestTenantCache_SetEntryKeepsFreshness(t *testing.T) {
+	cache := NewTenantCache(CacheConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	tenant := newTestTenant("acme")
+	require.NoError(t, cache.SetEntry(ctx, core.CacheEntry{Tenant: tenant, FreshUntil: now.Add(10 * time.Second)}, 0))
+	entry, err := cache.GetEntry(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(10*time.Second), entry.FreshUntil)
+
+	// The entry is dropped after ttl even when the source reports it fresh for longer
+	require.NoError(t, cache.SetEntry(ctx, core.CacheEntry{Tenant: tenant, FreshUntil: now.Add(time.Hour)}, time.Minute))
+	now = now.Add(2 * time.Minute)
+	_, err = cache.GetEntry(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	assert.Error(t, cache.SetEntry(ctx, core.CacheEntry{}, 0))
+}
+
+func TestNoopCache(t *testing.T) {
+	cache := NewNoopCache()
+	ctx := context.Background()
+
+	tenant := newTestTenant("acme")
+	require.NoError(t, cache.Set(ctx, tenant, 0))
+
+	_, err := cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	_, err = cache.GetByID(ctx, tenant.ID)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	assert.NoError(t, cache.Delete(ctx, "acme"))
+}
@@ -0,0 +1,16 @@
+package memory
+
+import "github.com/victorximenis/multitenant/core"
+
+// Compile-time checks to ensure the in-memory implementations satisfy the core interfaces
+var (
+	_ core.TenantRepository   = (*TenantRepository)(nil)
+	_ core.TemplateRepository = (*TenantRepository)(nil)
+	_ core.MetadataQuerier    = (*TenantRepository)(nil)
+	_ core.LabelLister        = (*TenantRepository)(nil)
+	_ core.TenantCache        = (*TenantCache)(nil)
+	_ core.NegativeCache      = (*TenantCache)(nil)
+	_ core.EntryCache         = (*TenantCache)(nil)
+	_ core.BatchCache         = (*TenantCache)(nil)
+	_ core.TenantCache        = (*NoopCache)(nil)
+)
@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// NoopCache implements core.TenantCache without storing anything, so every lookup reaches
+// the registry. It is used when caching is disabled.
+type NoopCache struct{}
+
+// NewNoopCache creates a cache that never holds a tenant
+func NewNoopCache() *NoopCache {
+	return &NoopCache{}
+}
+
+// Get always returns a TENANT_NOT_FOUND error
+func (c *NoopCache) Get(ctx context.Context, name string) (*core.Tenant, error) {
+	return nil, core.ErrTenantNotFound(name)
+}
+
+// GetByID always returns a TENANT_NOT_FOUND error
+func (c *NoopCache) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return nil, core.ErrTenantNotFound(id)
+}
+
+// Set discards the tenant
+func (c *NoopCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	return nil
+}
+
+// Delete has nothing to remove
+func (c *NoopCache) Delete(ctx context.Context, name string) error {
+	return nil
+}
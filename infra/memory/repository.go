@@ -0,0 +1,286 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// TenantRepository implements core.TenantRepository and core.TemplateRepository in memory.
+// Tenants are copied on the way in and out, so callers never share state with the store.
+type TenantRepository struct {
+	mu        sync.RWMutex
+	tenants   map[string]*core.Tenant // id -> tenant
+	names     map[string]string       // name -> id
+	templates map[string]*core.TenantTemplate
+}
+
+// NewTenantRepository creates an empty in-memory tenant repository
+func NewTenantRepository() *TenantRepository {
+	return &TenantRepository{
+		tenants:   make(map[string]*core.Tenant),
+		names:     make(map[string]string),
+		templates: make(map[string]*core.TenantTemplate),
+	}
+}
+
+// GetByName retrieves a tenant by name with all its datasources
+func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.names[name]
+	if !ok {
+		return nil, core.ErrTenantNotFound(name)
+	}
+
+	return core.CopyTenant(r.tenants[id]), nil
+}
+
+// GetByID retrieves a tenant by ID with all its datasources
+func (r *TenantRepository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant, ok := r.tenants[id]
+	if !ok {
+		return nil, core.ErrTenantNotFound(id)
+	}
+
+	return core.CopyTenant(tenant), nil
+}
+
+// List retrieves all tenants ordered by name
+func (r *TenantRepository) List(ctx context.Context) ([]core.Tenant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenants := make([]core.Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenants = append(tenants, *core.CopyTenant(tenant))
+	}
+
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].Name < tenants[j].Name
+	})
+
+	return tenants, nil
+}
+
+// FindByMetadata returns the tenants whose metadata matches query, ordered by name
+func (r *TenantRepository) FindByMetadata(ctx context.Context, query core.MetadataQuery) ([]core.Tenant, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	tenants, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.Filter(tenants), nil
+}
+
+// ListByLabels returns the tenants whose labels match selector, ordered by name
+func (r *TenantRepository) ListByLabels(ctx context.Context, selector core.LabelSelector) ([]core.Tenant, error) {
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+
+	tenants, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return selector.Filter(tenants), nil
+}
+
+// Create stores a new tenant, enforcing unique IDs and names
+func (r *TenantRepository) Create(ctx context.Context, tenant *core.Tenant) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := tenant.Validate(); err != nil {
+		return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.names[tenant.Name]; exists {
+		return core.ErrTenantExists(tenant.Name)
+	}
+	if _, exists := r.tenants[tenant.ID]; exists {
+		return core.ErrTenantExists(tenant.Name).WithDetail("tenant_id", tenant.ID)
+	}
+
+	now := time.Now()
+	tenant.CreatedAt = now
+	tenant.UpdatedAt = now
+
+	for i := range tenant.Datasources {
+		ds := &tenant.Datasources[i]
+		ds.TenantID = tenant.ID
+		ds.CreatedAt = now
+		ds.UpdatedAt = now
+
+		if err := ds.Validate(); err != nil {
+			return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
+		}
+	}
+
+	r.tenants[tenant.ID] = core.CopyTenant(tenant)
+	r.names[tenant.Name] = tenant.ID
+
+	return nil
+}
+
+// Update replaces an existing tenant and its datasources
+func (r *TenantRepository) Update(ctx context.Context, tenant *core.Tenant) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := tenant.Validate(); err != nil {
+		return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.tenants[tenant.ID]
+	if !exists {
+		return core.ErrTenantNotFound(tenant.Name)
+	}
+
+	if ownerID, taken := r.names[tenant.Name]; taken && ownerID != tenant.ID {
+		return core.ErrTenantExists(tenant.Name)
+	}
+
+	now := time.Now()
+	tenant.UpdatedAt = now
+
+	for i := range tenant.Datasources {
+		ds := &tenant.Datasources[i]
+		ds.TenantID = tenant.ID
+		ds.UpdatedAt = now
+		if ds.CreatedAt.IsZero() {
+			ds.CreatedAt = now
+		}
+
+		if err := ds.Validate(); err != nil {
+			return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
+		}
+	}
+
+	// Like the SQL backend, the creation timestamp is never overwritten by updates
+	stored := core.CopyTenant(tenant)
+	stored.CreatedAt = current.CreatedAt
+
+	delete(r.names, current.Name)
+	r.tenants[tenant.ID] = stored
+	r.names[tenant.Name] = tenant.ID
+
+	return nil
+}
+
+// Delete removes a tenant by ID
+func (r *TenantRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant, exists := r.tenants[id]
+	if !exists {
+		return core.ErrTenantNotFound(id)
+	}
+
+	delete(r.names, tenant.Name)
+	delete(r.tenants, id)
+
+	return nil
+}
+
+// GetTemplate retrieves a tenant template by name
+func (r *TenantRepository) GetTemplate(ctx context.Context, name string) (*core.TenantTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return nil, core.ErrTemplateNotFound(name)
+	}
+
+	return core.CopyTemplate(tmpl), nil
+}
+
+// ListTemplates retrieves all tenant templates ordered by name
+func (r *TenantRepository) ListTemplates(ctx context.Context) ([]core.TenantTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	templates := make([]core.TenantTemplate, 0, len(r.templates))
+	for _, tmpl := range r.templates {
+		templates = append(templates, *core.CopyTemplate(tmpl))
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates, nil
+}
+
+// SaveTemplate creates or replaces a tenant template identified by its name
+func (r *TenantRepository) SaveTemplate(ctx context.Context, tmpl *core.TenantTemplate) error {
+	if err := tmpl.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.templates[tmpl.Name]; ok {
+		tmpl.ID = existing.ID
+		tmpl.CreatedAt = existing.CreatedAt
+	} else if tmpl.CreatedAt.IsZero() {
+		tmpl.CreatedAt = now
+	}
+	tmpl.UpdatedAt = now
+
+	r.templates[tmpl.Name] = core.CopyTemplate(tmpl)
+	return nil
+}
+
+// DeleteTemplate removes a tenant template by name
+func (r *TenantRepository) DeleteTemplate(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[name]; !ok {
+		return core.ErrTemplateNotFound(name)
+	}
+
+	delete(r.templates, name)
+	return nil
+}
@@ -0,0 +1,56 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// writeConflictCode is returned when concurrent writes touch the same document
+const writeConflictCode = 112
+
+// mapMongoError maps MongoDB driver errors to *core.MultitenantError. name identifies the
+// tenant for duplicate key errors; errors that are already structured are returned unchanged.
+func mapMongoError(err error, name string) error {
+	if err == nil {
+		return nil
+	}
+
+	var mtErr *core.MultitenantError
+	if errors.As(err, &mtErr) {
+		return err
+	}
+
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		return core.ErrTenantExists(name).WithCause(err)
+	case mongo.IsTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return core.ErrDatabaseTimeout(err)
+	case isTransientTransactionError(err):
+		return core.ErrDatabaseSerialization(err)
+	case mongo.IsNetworkError(err), errors.Is(err, mongo.ErrClientDisconnected),
+		errors.As(err, new(topology.ServerSelectionError)):
+		return connectionError(err)
+	default:
+		return core.NewError(core.ErrCodeDatabaseQuery, "database query failed").WithCause(err)
+	}
+}
+
+// isTransientTransactionError reports write conflicts and other errors the server marks
+// as safe to retry as a whole
+func isTransientTransactionError(err error) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	return serverErr.HasErrorLabel("TransientTransactionError") || serverErr.HasErrorCode(writeConflictCode)
+}
+
+// connectionError reports a lost or refused MongoDB connection
+func connectionError(cause error) error {
+	return core.NewError(core.ErrCodeDatabaseConnection, "database connection failed").WithCause(cause)
+}
@@ -4,3 +4,12 @@ import "github.com/victorximenis/multitenant/core"
 
 // Compile-time check to ensure TenantRepository implements core.TenantRepository interface
 var _ core.TenantRepository = (*TenantRepository)(nil)
+
+// Compile-time check to ensure TenantRepository implements core.TemplateRepository interface
+var _ core.TemplateRepository = (*TenantRepository)(nil)
+
+// Compile-time check to ensure TenantRepository implements core.MetadataQuerier interface
+var _ core.MetadataQuerier = (*TenantRepository)(nil)
+
+// Compile-time check to ensure TenantRepository implements core.LabelLister interface
+var _ core.LabelLister = (*TenantRepository)(nil)
@@ -0,0 +1,71 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// FIELD_LABELS is the document field holding the tenant labels
+const FIELD_LABELS = "labels"
+
+// ListByLabels returns the tenants matching selector ordered by name, using the
+// wildcard index on labels
+func (r *TenantRepository) ListByLabels(ctx context.Context, selector core.LabelSelector) ([]core.Tenant, error) {
+	filter, err := labelFilter(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []core.Tenant
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, mapMongoError(err, "")
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &tenants); err != nil {
+		return nil, mapMongoError(err, "")
+	}
+
+	return tenants, nil
+}
+
+// labelFilter translates selector into a MongoDB filter document. Label keys never
+// contain dots, so every key maps to a single field below labels; $ne and $nin also
+// match documents without the field, as selectors require.
+func labelFilter(selector core.LabelSelector) (bson.M, error) {
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+
+	clauses := make(bson.A, 0, len(selector.Requirements))
+	for _, requirement := range selector.Requirements {
+		var expression bson.M
+		switch requirement.Operator {
+		case core.SelectorEquals:
+			expression = bson.M{"$eq": requirement.Values[0]}
+		case core.SelectorNotEquals:
+			expression = bson.M{"$ne": requirement.Values[0]}
+		case core.SelectorIn:
+			expression = bson.M{"$in": requirement.Values}
+		case core.SelectorNotIn:
+			expression = bson.M{"$nin": requirement.Values}
+		case core.SelectorExists:
+			expression = bson.M{"$exists": true}
+		case core.SelectorDoesNotExist:
+			expression = bson.M{"$exists": false}
+		}
+		clauses = append(clauses, bson.M{FIELD_LABELS + "." + requirement.Key: expression})
+	}
+
+	if len(clauses) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": clauses}, nil
+}
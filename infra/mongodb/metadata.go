@@ -0,0 +1,145 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// FindByMetadata returns the tenants matching query ordered by name. Conditions become
+// dot-path filters on the metadata subdocument; Options.MetadataIndexes indexes the
+// paths queried most often. Equality compares whole values like the other backends, so
+// an array only equals the same array and an object the same keys in any order.
+func (r *TenantRepository) FindByMetadata(ctx context.Context, query core.MetadataQuery) ([]core.Tenant, error) {
+	filter, err := metadataFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []core.Tenant
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, mapMongoError(err, "")
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &tenants); err != nil {
+		return nil, mapMongoError(err, "")
+	}
+
+	return tenants, nil
+}
+
+// metadataFilter translates query into a MongoDB filter document
+func metadataFilter(query core.MetadataQuery) (bson.M, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	operators := map[core.MetadataOperator]string{
+		core.MetadataOpGt:  "$gt",
+		core.MetadataOpGte: "$gte",
+		core.MetadataOpLt:  "$lt",
+		core.MetadataOpLte: "$lte",
+	}
+
+	clauses := make(bson.A, 0, len(query.Conditions))
+	for _, condition := range query.Conditions {
+		field := metadataField(condition.Path)
+		switch condition.Operator {
+		case core.MetadataOpEq:
+			clauses = append(clauses, metadataEquals(condition.Path, condition.Value))
+		case core.MetadataOpNe:
+			clauses = append(clauses, bson.M{"$nor": bson.A{metadataEquals(condition.Path, condition.Value)}})
+		case core.MetadataOpIn:
+			alternatives := make(bson.A, len(condition.Values))
+			for i, value := range condition.Values {
+				alternatives[i] = metadataEquals(condition.Path, value)
+			}
+			clauses = append(clauses, bson.M{"$or": alternatives})
+		case core.MetadataOpExists:
+			clauses = append(clauses, bson.M{field: bson.M{"$exists": true}})
+		default:
+			clauses = append(clauses, bson.M{field: bson.M{operators[condition.Operator]: condition.Value}})
+		}
+	}
+
+	if len(clauses) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": clauses}, nil
+}
+
+// metadataEquals matches documents whose value at path equals value exactly. A plain
+// {field: value} filter also matches arrays holding value and compares embedded
+// documents in key order, so objects are compared key by key and other values again
+// with $expr once the plain filter has narrowed the documents through the indexes.
+func metadataEquals(path string, value interface{}) bson.M {
+	value = normalizeMetadata(value)
+	field := "$" + metadataField(path)
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return bson.M{"$and": bson.A{
+			bson.M{metadataField(path): value},
+			bson.M{"$expr": bson.M{"$eq": bson.A{field, value}}},
+		}}
+	}
+
+	// $objectToArray fails on other types, so the size is only taken of objects
+	isObject := bson.M{"$eq": bson.A{bson.M{"$type": field}, "object"}}
+	size := bson.M{"$size": bson.M{"$objectToArray": bson.M{"$cond": bson.A{isObject, field, bson.M{}}}}}
+	clauses := bson.A{bson.M{"$expr": bson.M{"$and": bson.A{isObject, bson.M{"$eq": bson.A{size, len(object)}}}}}}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		clauses = append(clauses, metadataEquals(path+"."+key, object[key]))
+	}
+	return bson.M{"$and": clauses}
+}
+
+// normalizeMetadata converts maps, slices and structs to their JSON form, so objects
+// of any map type are compared key by key
+func normalizeMetadata(value interface{}) interface{} {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		var normalized interface{}
+		if err := json.Unmarshal(data, &normalized); err != nil {
+			return value
+		}
+		return normalized
+	default:
+		return value
+	}
+}
+
+// metadataField returns the document field for a metadata path
+func metadataField(path string) string {
+	return "metadata." + path
+}
+
+// metadataIndexes returns an index model for every configured metadata path
+func metadataIndexes(paths []string) []mongo.IndexModel {
+	models := make([]mongo.IndexModel, len(paths))
+	for i, path := range paths {
+		models[i] = mongo.IndexModel{Keys: bson.D{{Key: metadataField(path), Value: 1}}}
+	}
+	return models
+}
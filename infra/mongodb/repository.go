@@ -2,6 +2,8 @@ package mongodb
 
 import (
 	"context"
+	"log/slog"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,55 +14,182 @@ import (
 )
 
 const (
-	DATABASE_NAME   = "multitenant"
-	COLLECTION_NAME = "tenants"
+	DATABASE_NAME             = "multitenant"
+	COLLECTION_NAME           = "tenants"
+	TEMPLATES_COLLECTION_NAME = "tenant_templates"
 )
 
 type TenantRepository struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	templates  *mongo.Collection
+	// logger is the configured logger, shared with the watchers of the repository
+	logger *slog.Logger
 }
 
+// Options holds optional settings for the MongoDB tenant repository
+type Options struct {
+	// Database defaults to DATABASE_NAME
+	Database string `json:"database,omitempty"`
+	// Collection defaults to COLLECTION_NAME
+	Collection string `json:"collection,omitempty"`
+	// TemplatesCollection defaults to TEMPLATES_COLLECTION_NAME
+	TemplatesCollection string `json:"templates_collection,omitempty"`
+	// SkipIndexes disables creating the secondary indexes on startup, for deployments that
+	// manage indexes themselves. The unique name indexes are always created, as they
+	// enforce unique tenant and template names.
+	SkipIndexes bool `json:"skip_indexes,omitempty"`
+	// MaxPoolSize and MinPoolSize size the driver connection pool (driver defaults when zero)
+	MaxPoolSize uint64 `json:"max_pool_size,omitempty"`
+	MinPoolSize uint64 `json:"min_pool_size,omitempty"`
+	// MetadataIndexes lists metadata paths, such as "region" or "billing.plan", to index
+	// for FindByMetadata
+	MetadataIndexes []string `json:"metadata_indexes,omitempty"`
+	// Logger receives the index and change stream logs; defaults to slog.Default()
+	Logger *slog.Logger `json:"-"`
+}
+
+// withDefaults fills in the default database and collection names
+func (o Options) withDefaults() Options {
+	if o.Database == "" {
+		o.Database = DATABASE_NAME
+	}
+	if o.Collection == "" {
+		o.Collection = COLLECTION_NAME
+	}
+	if o.TemplatesCollection == "" {
+		o.TemplatesCollection = TEMPLATES_COLLECTION_NAME
+	}
+	return o
+}
+
+// Validate checks the database and collection names and pool sizes
+func (o Options) Validate() error {
+	o = o.withDefaults()
+
+	if strings.ContainsAny(o.Database, `/\. "$`) {
+		return core.ErrConfigInvalid("Database", "invalid MongoDB database name: "+o.Database)
+	}
+
+	for _, name := range []string{o.Collection, o.TemplatesCollection} {
+		if strings.Contains(name, "$") || strings.HasPrefix(name, "system.") {
+			return core.ErrConfigInvalid("Collection", "invalid MongoDB collection name: "+name)
+		}
+	}
+
+	if o.Collection == o.TemplatesCollection {
+		return core.ErrConfigInvalid("TemplatesCollection", "tenants and templates must use different collections")
+	}
+
+	for _, path := range o.MetadataIndexes {
+		if err := core.MetadataExists(path).Validate(); err != nil {
+			return core.ErrConfigInvalid("MetadataIndexes", "invalid metadata index path: "+path)
+		}
+	}
+
+	if o.MaxPoolSize > 0 && o.MinPoolSize > o.MaxPoolSize {
+		return core.ErrConfigInvalid("MinPoolSize", "min pool size cannot exceed max pool size")
+	}
+
+	return nil
+}
+
+// NewTenantRepository creates a MongoDB tenant repository using the default names
 func NewTenantRepository(ctx context.Context, uri string) (*TenantRepository, error) {
+	return NewTenantRepositoryWithOptions(ctx, uri, Options{})
+}
+
+// NewTenantRepositoryWithOptions creates a MongoDB tenant repository with custom options
+func NewTenantRepositoryWithOptions(ctx context.Context, uri string, opts Options) (*TenantRepository, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+
 	clientOptions := options.Client().ApplyURI(uri)
+	if opts.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(opts.MinPoolSize)
+	}
+
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		return nil, err
+		return nil, connectionError(err)
 	}
 
 	// Verify connection
 	if err := client.Ping(ctx, nil); err != nil {
-		return nil, err
+		client.Disconnect(ctx)
+		return nil, connectionError(err)
 	}
 
-	collection := client.Database(DATABASE_NAME).Collection(COLLECTION_NAME)
+	database := client.Database(opts.Database)
+	repo := &TenantRepository{
+		client:     client,
+		collection: database.Collection(opts.Collection),
+		templates:  database.Collection(opts.TemplatesCollection),
+		logger:     opts.Logger,
+	}
 
-	// Create indexes
-	indexModels := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "name", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
+	if err := repo.createIndexes(ctx, opts); err != nil {
+		client.Disconnect(ctx)
+		return nil, mapMongoError(err, "")
+	}
+	core.ComponentLogger(opts.Logger, "mongodb").DebugContext(ctx, "ensured registry indexes",
+		"collection", opts.Collection, "secondary", !opts.SkipIndexes, "metadata_indexes", len(opts.MetadataIndexes))
+
+	return repo, nil
+}
+
+// createIndexes creates the indexes of the tenant and template collections
+func (r *TenantRepository) createIndexes(ctx context.Context, opts Options) error {
+	if _, err := r.collection.Indexes().CreateMany(ctx, tenantIndexes(opts)); err != nil {
+		return err
+	}
+
+	_, err := r.templates.Indexes().CreateOne(ctx, uniqueNameIndex())
+	return err
+}
+
+// uniqueNameIndex is the only guard against duplicate names, and the duplicate key
+// errors it raises are reported as ErrTenantExists
+func uniqueNameIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+}
+
+// tenantIndexes returns the unique name index and, unless SkipIndexes is set, the
+// secondary indexes used by the tenant queries
+func tenantIndexes(opts Options) []mongo.IndexModel {
+	indexModels := []mongo.IndexModel{uniqueNameIndex()}
+	if opts.SkipIndexes {
+		return indexModels
+	}
+
+	indexModels = append(indexModels,
+		mongo.IndexModel{
 			Keys: bson.D{{Key: "datasources.id", Value: 1}},
 		},
-		{
+		mongo.IndexModel{
 			Keys: bson.D{{Key: "id", Value: 1}},
 		},
-		{
+		mongo.IndexModel{
 			Keys: bson.D{{Key: "is_active", Value: 1}},
 		},
-	}
-
-	_, err = collection.Indexes().CreateMany(ctx, indexModels)
-	if err != nil {
-		return nil, err
-	}
+		mongo.IndexModel{
+			Keys: bson.D{{Key: FIELD_LABELS + ".$**", Value: 1}},
+		},
+	)
+	return append(indexModels, metadataIndexes(opts.MetadataIndexes)...)
+}
 
-	return &TenantRepository{
-		client:     client,
-		collection: collection,
-	}, nil
+// Close disconnects the MongoDB client
+func (r *TenantRepository) Close(ctx context.Context) error {
+	return mapMongoError(r.client.Disconnect(ctx), "")
 }
 
 func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
@@ -71,13 +200,29 @@ func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Te
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, core.TenantNotFoundError{Name: name}
+			return nil, core.ErrTenantNotFound(name)
 		}
-		return nil, err
+		return nil, mapMongoError(err, name)
 	}
 
 	if !tenant.IsActive {
-		return nil, core.TenantInactiveError{Name: name}
+		return nil, core.ErrTenantInactive(name)
+	}
+
+	return &tenant, nil
+}
+
+// GetByID retrieves a tenant by ID. Unlike GetByName it also returns inactive tenants,
+// so administrative operations such as deletes can address them.
+func (r *TenantRepository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	var tenant core.Tenant
+
+	err := r.collection.FindOne(ctx, bson.M{"id": id}).Decode(&tenant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, core.ErrTenantNotFound(id)
+		}
+		return nil, mapMongoError(err, "")
 	}
 
 	return &tenant, nil
@@ -86,28 +231,37 @@ func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Te
 func (r *TenantRepository) List(ctx context.Context) ([]core.Tenant, error) {
 	var tenants []core.Tenant
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
 	if err != nil {
-		return nil, err
+		return nil, mapMongoError(err, "")
 	}
 	defer cursor.Close(ctx)
 
 	if err := cursor.All(ctx, &tenants); err != nil {
-		return nil, err
+		return nil, mapMongoError(err, "")
 	}
 
 	return tenants, nil
 }
 
 func (r *TenantRepository) Create(ctx context.Context, tenant *core.Tenant) error {
+	if err := tenant.Validate(); err != nil {
+		return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
+	}
+
 	tenant.CreatedAt = time.Now()
 	tenant.UpdatedAt = time.Now()
 
 	_, err := r.collection.InsertOne(ctx, tenant)
-	return err
+	return mapMongoError(err, tenant.Name)
 }
 
 func (r *TenantRepository) Update(ctx context.Context, tenant *core.Tenant) error {
+	if err := tenant.Validate(); err != nil {
+		return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
+	}
+
 	tenant.UpdatedAt = time.Now()
 
 	filter := bson.M{"id": tenant.ID}
@@ -115,11 +269,11 @@ func (r *TenantRepository) Update(ctx context.Context, tenant *core.Tenant) erro
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return mapMongoError(err, tenant.Name)
 	}
 
 	if result.MatchedCount == 0 {
-		return core.TenantNotFoundError{Name: tenant.Name}
+		return core.ErrTenantNotFound(tenant.Name)
 	}
 
 	return nil
@@ -130,11 +284,11 @@ func (r *TenantRepository) Delete(ctx context.Context, id string) error {
 
 	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
-		return err
+		return mapMongoError(err, "")
 	}
 
 	if result.DeletedCount == 0 {
-		return core.TenantNotFoundError{Name: ""}
+		return core.ErrTenantNotFound(id)
 	}
 
 	return nil
@@ -150,11 +304,11 @@ func (r *TenantRepository) AddDatasource(ctx context.Context, tenantID string, d
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return mapMongoError(err, "")
 	}
 
 	if result.MatchedCount == 0 {
-		return core.TenantNotFoundError{Name: ""}
+		return core.ErrTenantNotFound(tenantID)
 	}
 
 	return nil
@@ -170,11 +324,11 @@ func (r *TenantRepository) RemoveDatasource(ctx context.Context, tenantID, datas
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return mapMongoError(err, "")
 	}
 
 	if result.MatchedCount == 0 {
-		return core.TenantNotFoundError{Name: ""}
+		return core.ErrTenantNotFound(tenantID)
 	}
 
 	return nil
@@ -195,11 +349,11 @@ func (r *TenantRepository) UpdateDatasource(ctx context.Context, tenantID string
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return mapMongoError(err, "")
 	}
 
 	if result.MatchedCount == 0 {
-		return core.TenantNotFoundError{Name: ""}
+		return core.ErrTenantNotFound(tenantID)
 	}
 
 	return nil
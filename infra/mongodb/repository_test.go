@@ -12,6 +12,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 
 	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/conformance"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -84,7 +85,7 @@ func TestMongoDBTenantRepository(t *testing.T) {
 
 	_, err = repo.GetByName(ctx, "test-tenant")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantInactiveError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantInactive))
 }
 
 func TestTenantRepository_GetByNameNotFound(t *testing.T) {
@@ -96,7 +97,7 @@ func TestTenantRepository_GetByNameNotFound(t *testing.T) {
 	// Test getting non-existent tenant
 	_, err := repo.GetByName(ctx, "non-existent")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_List(t *testing.T) {
@@ -167,7 +168,7 @@ func TestTenantRepository_Update(t *testing.T) {
 	// Verify the update
 	_, err = repo.GetByName(ctx, "test-tenant")
 	assert.Error(t, err) // Should error because tenant is inactive
-	assert.IsType(t, core.TenantInactiveError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantInactive))
 }
 
 func TestTenantRepository_UpdateNotFound(t *testing.T) {
@@ -185,7 +186,7 @@ func TestTenantRepository_UpdateNotFound(t *testing.T) {
 
 	err := repo.Update(ctx, tenant)
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_Delete(t *testing.T) {
@@ -211,7 +212,7 @@ func TestTenantRepository_Delete(t *testing.T) {
 	// Verify deletion
 	_, err = repo.GetByName(ctx, "test-tenant")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_DeleteNotFound(t *testing.T) {
@@ -223,7 +224,7 @@ func TestTenantRepository_DeleteNotFound(t *testing.T) {
 	// Try to delete non-existent tenant
 	err := repo.Delete(ctx, uuid.New().String())
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_CreateDuplicate(t *testing.T) {
@@ -493,15 +494,24 @@ func TestTenantRepository_DatasourceOperationsNotFound(t *testing.T) {
 	// Test AddDatasource with non-existent tenant
 	err := repo.AddDatasource(ctx, nonExistentID, datasource)
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 
 	// Test RemoveDatasource with non-existent tenant
 	err = repo.RemoveDatasource(ctx, nonExistentID, datasource.ID)
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 
 	// Test UpdateDatasource with non-existent tenant
 	err = repo.UpdateDatasource(ctx, nonExistentID, datasource)
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+}
+
+func TestTenantRepository_Conformance(t *testing.T) {
+	repo, cleanup := setupTestMongoDB(t)
+	defer cleanup()
+
+	conformance.RunRepositoryTests(t, func(t *testing.T) core.TenantRepository {
+		return repo
+	})
 }
@@ -0,0 +1,147 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func TestOptions_Defaults(t *testing.T) {
+	opts := Options{}.withDefaults()
+	assert.Equal(t, DATABASE_NAME, opts.Database)
+	assert.Equal(t, COLLECTION_NAME, opts.Collection)
+	assert.Equal(t, TEMPLATES_COLLECTION_NAME, opts.TemplatesCollection)
+
+	opts = Options{Database: "platform", Collection: "mt_tenants"}.withDefaults()
+	assert.Equal(t, "platform", opts.Database)
+	assert.Equal(t, "mt_tenants", opts.Collection)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	assert.NoError(t, Options{}.Validate())
+	assert.NoError(t, Options{Database: "platform", Collection: "mt_tenants", MaxPoolSize: 10, MinPoolSize: 2}.Validate())
+	assert.Error(t, Options{Database: "bad.name"}.Validate())
+	assert.Error(t, Options{Collection: "system.tenants"}.Validate())
+	assert.Error(t, Options{Collection: "shared", TemplatesCollection: "shared"}.Validate())
+	assert.Error(t, Options{MaxPoolSize: 2, MinPoolSize: 5}.Validate())
+	assert.NoError(t, Options{MetadataIndexes: []string{"region", "billing.plan"}}.Validate())
+	assert.Error(t, Options{MetadataIndexes: []string{"$where"}}.Validate())
+}
+
+func TestTenantIndexes(t *testing.T) {
+	indexes := tenantIndexes(Options{MetadataIndexes: []string{"region"}})
+	assert.Len(t, indexes, 6)
+	assert.Equal(t, bson.D{{Key: "name", Value: 1}}, indexes[0].Keys)
+	assert.True(t, *indexes[0].Options.Unique)
+
+	// Skipping the secondary indexes keeps the unique name index
+	indexes = tenantIndexes(Options{SkipIndexes: true, MetadataIndexes: []string{"region"}})
+	require.Len(t, indexes, 1)
+	assert.Equal(t, bson.D{{Key: "name", Value: 1}}, indexes[0].Keys)
+	assert.True(t, *indexes[0].Options.Unique)
+}
+
+func TestMetadataFilter(t *testing.T) {
+	filter, err := metadataFilter(core.NewMetadataQuery())
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{}, filter)
+
+	filter, err = metadataFilter(core.NewMetadataQuery(
+		core.MetadataEquals("region", "eu"),
+		core.MetadataNotEquals("tier", 1),
+		core.MetadataIn("billing.plan", "pro", "enterprise"),
+		core.MetadataExists("owner"),
+		core.MetadataGreaterOrEqual("seats", 10),
+	))
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{"$and": bson.A{
+			bson.M{"metadata.region": "eu"},
+			bson.M{"$expr": bson.M{"$eq": bson.A{"$metadata.region", "eu"}}},
+		}},
+		bson.M{"$nor": bson.A{bson.M{"$and": bson.A{
+			bson.M{"metadata.tier": 1},
+			bson.M{"$expr": bson.M{"$eq": bson.A{"$metadata.tier", 1}}},
+		}}}},
+		bson.M{"$or": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"metadata.billing.plan": "pro"},
+				bson.M{"$expr": bson.M{"$eq": bson.A{"$metadata.billing.plan", "pro"}}},
+			}},
+			bson.M{"$and": bson.A{
+				bson.M{"metadata.billing.plan": "enterprise"},
+				bson.M{"$expr": bson.M{"$eq": bson.A{"$metadata.billing.plan", "enterprise"}}},
+			}},
+		}},
+		bson.M{"metadata.owner": bson.M{"$exists": true}},
+		bson.M{"metadata.seats": bson.M{"$gte": 10}},
+	}}, filter)
+
+	// Objects are compared key by key, so stored key order does not matter
+	filter, err = metadataFilter(core.NewMetadataQuery(
+		core.MetadataEquals("billing", map[string]string{"plan": "pro"}),
+	))
+	require.NoError(t, err)
+	isObject := bson.M{"$eq": bson.A{bson.M{"$type": "$metadata.billing"}, "object"}}
+	assert.Equal(t, bson.M{"$and": bson.A{bson.M{"$and": bson.A{
+		bson.M{"$expr": bson.M{"$and": bson.A{isObject, bson.M{"$eq": bson.A{
+			bson.M{"$size": bson.M{"$objectToArray": bson.M{"$cond": bson.A{isObject, "$metadata.billing", bson.M{}}}}}, 1,
+		}}}}},
+		bson.M{"$and": bson.A{
+			bson.M{"metadata.billing.plan": "pro"},
+			bson.M{"$expr": bson.M{"$eq": bson.A{"$metadata.billing.plan", "pro"}}},
+		}},
+	}}}}, filter)
+
+	_, err = metadataFilter(core.NewMetadataQuery(core.MetadataEquals("$where", "1")))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeValidationFailed))
+}
+
+func TestLabelFilter(t *testing.T) {
+	filter, err := labelFilter(core.LabelSelector{})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{}, filter)
+
+	filter, err = labelFilter(core.MustParseLabelSelector("tier=gold,env!=dev,region in (eu,us),zone notin (a),beta,!legacy"))
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{"labels.tier": bson.M{"$eq": "gold"}},
+		bson.M{"labels.env": bson.M{"$ne": "dev"}},
+		bson.M{"labels.region": bson.M{"$in": []string{"eu", "us"}}},
+		bson.M{"labels.zone": bson.M{"$nin": []string{"a"}}},
+		bson.M{"labels.beta": bson.M{"$exists": true}},
+		bson.M{"labels.legacy": bson.M{"$exists": false}},
+	}}, filter)
+
+	_, err = labelFilter(core.LabelSelector{Requirements: []core.LabelRequirement{{Key: "$where", Operator: core.SelectorExists}}})
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeValidationFailed))
+}
+
+func TestMapMongoError(t *testing.T) {
+	assert.NoError(t, mapMongoError(nil, "acme"))
+
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}}}
+	exists := mapMongoError(duplicate, "acme")
+	assert.True(t, core.IsErrorCode(exists, core.ErrCodeTenantExists))
+	assert.Contains(t, exists.Error(), "tenant already exists: acme")
+
+	timeout := mapMongoError(fmt.Errorf("find: %w", context.DeadlineExceeded), "acme")
+	assert.True(t, core.IsErrorCode(timeout, core.ErrCodeDatabaseTimeout))
+	assert.True(t, errors.Is(timeout, context.DeadlineExceeded))
+
+	conflict := mapMongoError(mongo.CommandError{Code: writeConflictCode, Name: "WriteConflict"}, "acme")
+	assert.True(t, core.IsErrorCode(conflict, core.ErrCodeDatabaseSerialization))
+
+	disconnected := mapMongoError(mongo.ErrClientDisconnected, "acme")
+	assert.True(t, core.IsErrorCode(disconnected, core.ErrCodeDatabaseConnection))
+
+	assert.True(t, core.IsErrorCode(mapMongoError(assert.AnError, "acme"), core.ErrCodeDatabaseQuery))
+}
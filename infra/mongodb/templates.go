@@ -0,0 +1,84 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// GetTemplate retrieves a tenant template by name
+func (r *TenantRepository) GetTemplate(ctx context.Context, name string) (*core.TenantTemplate, error) {
+	var tmpl core.TenantTemplate
+
+	err := r.templates.FindOne(ctx, bson.M{"name": name}).Decode(&tmpl)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, core.ErrTemplateNotFound(name)
+		}
+		return nil, mapMongoError(err, "")
+	}
+
+	return &tmpl, nil
+}
+
+// ListTemplates retrieves all tenant templates ordered by name
+func (r *TenantRepository) ListTemplates(ctx context.Context) ([]core.TenantTemplate, error) {
+	var templates []core.TenantTemplate
+
+	cursor, err := r.templates.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, mapMongoError(err, "")
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, mapMongoError(err, "")
+	}
+
+	return templates, nil
+}
+
+// SaveTemplate creates or replaces a tenant template identified by its name
+func (r *TenantRepository) SaveTemplate(ctx context.Context, tmpl *core.TenantTemplate) error {
+	if err := tmpl.Validate(); err != nil {
+		return core.ErrValidationFailed("template", err.Error()).WithCause(err)
+	}
+
+	// Keep the identity of an existing template with the same name
+	var existing core.TenantTemplate
+	err := r.templates.FindOne(ctx, bson.M{"name": tmpl.Name}).Decode(&existing)
+	if err == nil {
+		tmpl.ID = existing.ID
+		tmpl.CreatedAt = existing.CreatedAt
+	} else if err != mongo.ErrNoDocuments {
+		return mapMongoError(err, "")
+	}
+
+	now := time.Now()
+	if tmpl.CreatedAt.IsZero() {
+		tmpl.CreatedAt = now
+	}
+	tmpl.UpdatedAt = now
+
+	_, err = r.templates.ReplaceOne(ctx, bson.M{"name": tmpl.Name}, tmpl, options.Replace().SetUpsert(true))
+	return mapMongoError(err, "")
+}
+
+// DeleteTemplate removes a tenant template by name
+func (r *TenantRepository) DeleteTemplate(ctx context.Context, name string) error {
+	result, err := r.templates.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return mapMongoError(err, "")
+	}
+
+	if result.DeletedCount == 0 {
+		return core.ErrTemplateNotFound(name)
+	}
+
+	return nil
+}
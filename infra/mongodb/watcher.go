@@ -0,0 +1,380 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+const (
+	RESUME_TOKENS_COLLECTION_NAME = "tenant_resume_tokens"
+	DEFAULT_WATCHER_NAME          = "default"
+	DEFAULT_RECONNECT_DELAY       = time.Second
+	DEFAULT_MAX_RECONNECT_DELAY   = 30 * time.Second
+
+	// changeStreamHistoryLost is returned when the resume token fell off the oplog
+	changeStreamHistoryLost = 286
+)
+
+// ResumeTokenStore persists the position of a change stream so a watcher continues
+// where it stopped after a restart
+type ResumeTokenStore interface {
+	// Load returns the saved token, or nil when there is none
+	Load(ctx context.Context) (bson.Raw, error)
+	// Save stores token; a nil token clears the saved position
+	Save(ctx context.Context, token bson.Raw) error
+}
+
+// WatcherConfig holds configuration for the tenant change stream watcher
+type WatcherConfig struct {
+	// Name identifies the watcher in the token store, defaults to DEFAULT_WATCHER_NAME.
+	// Processes that must each see every change need distinct names.
+	Name string
+	// TokenStore defaults to a store in the RESUME_TOKENS_COLLECTION_NAME collection
+	// of the registry database
+	TokenStore ResumeTokenStore
+	// ReconnectDelay is the initial wait before reopening the stream, doubled up to MaxReconnectDelay
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// PreImages enables pre-images on the tenants collection (MongoDB 6.0+), so deletes and
+	// renames made while the watcher was stopped still report the previous name
+	PreImages bool
+	// OnError is called for stream failures and token store errors, which are also logged
+	// with the logger of the repository
+	OnError func(error)
+}
+
+// changeStream is the subset of *mongo.ChangeStream used by the watcher
+type changeStream interface {
+	Next(ctx context.Context) bool
+	Decode(val interface{}) error
+	ResumeToken() bson.Raw
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// tenantRef is the part of a tenant document the watcher needs
+type tenantRef struct {
+	ObjectID bson.RawValue `bson:"_id"`
+	ID       string        `bson:"id"`
+	Name     string        `bson:"name"`
+}
+
+// changeEvent is a change stream event on the tenants collection
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ObjectID bson.RawValue `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             *tenantRef `bson:"fullDocument"`
+	FullDocumentBeforeChange *tenantRef `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields bson.Raw `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// Watcher follows the change stream of the tenants collection. Change streams require
+// a replica set or sharded cluster.
+type Watcher struct {
+	config WatcherConfig
+	open   func(ctx context.Context, resumeAfter bson.Raw) (changeStream, error)
+	scan   func(ctx context.Context) ([]tenantRef, error)
+	sleep  func(ctx context.Context, d time.Duration) error
+	logger *slog.Logger
+
+	// known maps document keys to tenants, since delete events only carry the key
+	known map[string]tenantRef
+}
+
+// NewWatcher creates a change stream watcher on the collection of repo; call Run to start watching
+func NewWatcher(repo *TenantRepository, config WatcherConfig) (*Watcher, error) {
+	if repo == nil {
+		return nil, core.ErrConfigInvalid("Repository", "watcher repository is required")
+	}
+
+	if config.Name == "" {
+		config.Name = DEFAULT_WATCHER_NAME
+	}
+	if config.TokenStore == nil {
+		tokens := repo.collection.Database().Collection(RESUME_TOKENS_COLLECTION_NAME)
+		config.TokenStore = NewCollectionTokenStore(tokens, repo.collection.Name()+":"+config.Name)
+	}
+	if config.ReconnectDelay <= 0 {
+		config.ReconnectDelay = DEFAULT_RECONNECT_DELAY
+	}
+	if config.MaxReconnectDelay < config.ReconnectDelay {
+		config.MaxReconnectDelay = DEFAULT_MAX_RECONNECT_DELAY
+		if config.MaxReconnectDelay < config.ReconnectDelay {
+			config.MaxReconnectDelay = config.ReconnectDelay
+		}
+	}
+
+	collection := repo.collection
+	preImages := config.PreImages
+	return &Watcher{
+		config: config,
+		open: func(ctx context.Context, resumeAfter bson.Raw) (changeStream, error) {
+			opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+			if preImages {
+				enable := bson.D{
+					{Key: "collMod", Value: collection.Name()},
+					{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+				}
+				if err := collection.Database().RunCommand(ctx, enable).Err(); err != nil {
+					return nil, err
+				}
+				opts.SetFullDocumentBeforeChange(options.WhenAvailable)
+			}
+			if resumeAfter != nil {
+				opts.SetResumeAfter(resumeAfter)
+			}
+			return collection.Watch(ctx, mongo.Pipeline{}, opts)
+		},
+		scan: func(ctx context.Context) ([]tenantRef, error) {
+			projection := options.Find().SetProjection(bson.M{"_id": 1, "id": 1, "name": 1})
+			cursor, err := collection.Find(ctx, bson.M{}, projection)
+			if err != nil {
+				return nil, err
+			}
+			var refs []tenantRef
+			if err := cursor.All(ctx, &refs); err != nil {
+				return nil, err
+			}
+			return refs, nil
+		},
+		sleep:  sleepContext,
+		logger: core.ComponentLogger(repo.logger, "mongodb.watcher"),
+		known:  make(map[string]tenantRef),
+	}, nil
+}
+
+// Run watches for tenant changes and calls handler for each one until ctx is done.
+// Handler receives a core.ChangeResync when a reconnect could not resume from a saved
+// position, because changes may have been missed.
+func (w *Watcher) Run(ctx context.Context, handler func(context.Context, core.TenantChange)) error {
+	delay := w.config.ReconnectDelay
+	connected := false
+
+	for {
+		err := w.watch(ctx, func(resumed bool) {
+			// Without a saved position the changes made while disconnected are lost
+			if connected && !resumed {
+				w.logger.InfoContext(ctx, "reopened the change stream without a saved position, resyncing tenant changes")
+				handler(ctx, core.TenantChange{Op: core.ChangeResync})
+			}
+			connected = true
+			delay = w.config.ReconnectDelay
+		}, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if isHistoryLost(err) {
+			// The saved position is gone; start over from now and resync once reopened
+			w.reportError(ctx, w.config.TokenStore.Save(ctx, nil))
+			connected = true
+			continue
+		}
+
+		w.reportError(ctx, mapMongoError(err, ""))
+		if err := w.sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay *= 2
+		if delay > w.config.MaxReconnectDelay {
+			delay = w.config.MaxReconnectDelay
+		}
+	}
+}
+
+// watch follows one change stream until it fails, calling ready once it is open
+func (w *Watcher) watch(ctx context.Context, ready func(resumed bool), handler func(context.Context, core.TenantChange)) error {
+	token, err := w.config.TokenStore.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := w.open(ctx, token)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	// Index the current tenants after opening the stream so no change falls in between
+	refs, err := w.scan(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		w.known[ref.ObjectID.String()] = ref
+	}
+	ready(token != nil)
+
+	for stream.Next(ctx) {
+		var event changeEvent
+		if err := stream.Decode(&event); err != nil {
+			w.reportError(ctx, core.NewError(core.ErrCodeValidationFailed, "malformed tenant change event").WithCause(err))
+			continue
+		}
+
+		if event.OperationType == "invalidate" {
+			// The collection was dropped or renamed; the stream cannot be resumed and
+			// reopening it without a position triggers a resync
+			w.reportError(ctx, w.config.TokenStore.Save(ctx, nil))
+			return errors.New("tenant change stream invalidated")
+		}
+
+		if change, ok := w.decode(event); ok {
+			handler(ctx, change)
+		}
+		w.reportError(ctx, w.config.TokenStore.Save(ctx, stream.ResumeToken()))
+	}
+
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// decode turns a change stream event into a tenant change and keeps the key index current
+func (w *Watcher) decode(event changeEvent) (core.TenantChange, bool) {
+	key := event.DocumentKey.ObjectID.String()
+	previous, seen := w.known[key]
+	if event.FullDocumentBeforeChange != nil {
+		previous, seen = *event.FullDocumentBeforeChange, true
+	}
+
+	switch event.OperationType {
+	case "insert":
+		if event.FullDocument == nil {
+			return core.TenantChange{}, false
+		}
+		w.known[key] = *event.FullDocument
+		return core.TenantChange{Op: core.ChangeInsert, ID: event.FullDocument.ID, Name: event.FullDocument.Name}, true
+
+	case "update", "replace":
+		change := core.TenantChange{Op: core.ChangeUpdate, ID: previous.ID, Name: previous.Name}
+		if event.FullDocument != nil {
+			w.known[key] = *event.FullDocument
+			change.ID = event.FullDocument.ID
+			change.Name = event.FullDocument.Name
+		}
+		if seen && previous.Name != change.Name {
+			change.OldName = previous.Name
+		} else if event.OperationType == "update" && onlyDatasources(event) {
+			change.Op = core.ChangeDatasource
+		}
+		return change, change.ID != "" || change.Name != ""
+
+	case "delete":
+		delete(w.known, key)
+		if !seen {
+			// A tenant deleted before it was indexed cannot be named; drop everything
+			return core.TenantChange{Op: core.ChangeResync}, true
+		}
+		return core.TenantChange{Op: core.ChangeDelete, ID: previous.ID, Name: previous.Name}, true
+
+	default:
+		return core.TenantChange{}, false
+	}
+}
+
+// onlyDatasources reports whether an update touched nothing but datasources and timestamps
+func onlyDatasources(event changeEvent) bool {
+	fields := append([]string(nil), event.UpdateDescription.RemovedFields...)
+	elements, _ := event.UpdateDescription.UpdatedFields.Elements()
+	for _, element := range elements {
+		fields = append(fields, element.Key())
+	}
+
+	datasources := false
+	for _, field := range fields {
+		switch {
+		case field == "datasources" || strings.HasPrefix(field, "datasources."):
+			datasources = true
+		case field == "updatedat" || field == "updated_at":
+		default:
+			return false
+		}
+	}
+	return datasources
+}
+
+// isHistoryLost reports whether the stream could not resume because the oplog moved on
+func isHistoryLost(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(changeStreamHistoryLost)
+}
+
+// reportError logs err and forwards it to the configured error callback
+func (w *Watcher) reportError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	w.logger.WarnContext(ctx, "tenant change stream failed", core.LogKeyError, err)
+	if w.config.OnError != nil {
+		w.config.OnError(err)
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// CollectionTokenStore keeps resume tokens in a MongoDB collection, one document per key
+type CollectionTokenStore struct {
+	collection *mongo.Collection
+	key        string
+}
+
+// NewCollectionTokenStore creates a token store saving under key in collection
+func NewCollectionTokenStore(collection *mongo.Collection, key string) *CollectionTokenStore {
+	return &CollectionTokenStore{collection: collection, key: key}
+}
+
+// Load returns the saved token, or nil when there is none
+func (s *CollectionTokenStore) Load(ctx context.Context) (bson.Raw, error) {
+	var document struct {
+		Token bson.Raw `bson:"token"`
+	}
+	err := s.collection.FindOne(ctx, bson.M{"_id": s.key}).Decode(&document)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, mapMongoError(err, "")
+	}
+	return document.Token, nil
+}
+
+// Save stores token, or removes the saved token when it is nil
+func (s *CollectionTokenStore) Save(ctx context.Context, token bson.Raw) error {
+	if token == nil {
+		_, err := s.collection.DeleteOne(ctx, bson.M{"_id": s.key})
+		return mapMongoError(err, "")
+	}
+
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": s.key},
+		bson.M{"$set": bson.M{"token": token, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true))
+	return mapMongoError(err, "")
+}
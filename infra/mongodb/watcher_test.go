@@ -0,0 +1,105 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// setupReplicaSet starts a single-node replica set, which change streams require
+func setupReplicaSet(t *testing.T) (*TenantRepository, func()) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx,
+		"mongo:6",
+		mongodb.WithReplicaSet("rs0"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("Waiting for connections"),
+		),
+	)
+	require.NoError(t, err)
+
+	connectionString, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	repo, err := NewTenantRepository(ctx, connectionString+"&directConnection=true")
+	require.NoError(t, err)
+
+	cleanup := func() {
+		mongoContainer.Terminate(ctx)
+	}
+
+	return repo, cleanup
+}
+
+// runWatcher starts a watcher and returns a channel with its changes and a stop func
+func runWatcher(t *testing.T, repo *TenantRepository, name string) (<-chan core.TenantChange, func()) {
+	watcher, err := NewWatcher(repo, WatcherConfig{Name: name, PreImages: true})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan core.TenantChange, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Run(ctx, func(ctx context.Context, change core.TenantChange) {
+			changes <- change
+		})
+	}()
+
+	// Give the stream time to open before making changes
+	time.Sleep(500 * time.Millisecond)
+
+	return changes, func() {
+		cancel()
+		<-done
+	}
+}
+
+func nextChange(t *testing.T, changes <-chan core.TenantChange) core.TenantChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a tenant change")
+		return core.TenantChange{}
+	}
+}
+
+func TestWatcher_ChangeStream(t *testing.T) {
+	repo, cleanup := setupReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	changes, stop := runWatcher(t, repo, "test")
+
+	tenant := core.NewTenant("acme")
+	require.NoError(t, repo.Create(ctx, tenant))
+	assert.Equal(t, core.TenantChange{Op: core.ChangeInsert, ID: tenant.ID, Name: "acme"}, nextChange(t, changes))
+
+	require.NoError(t, repo.AddDatasource(ctx, tenant.ID, core.Datasource{
+		ID: uuid.New().String(), TenantID: tenant.ID, DSN: "postgres://localhost/acme", Role: "rw", PoolSize: 5,
+	}))
+	assert.Equal(t, core.TenantChange{Op: core.ChangeDatasource, ID: tenant.ID, Name: "acme"}, nextChange(t, changes))
+
+	tenant.Name = "acme-corp"
+	require.NoError(t, repo.Update(ctx, tenant))
+	assert.Equal(t, core.TenantChange{Op: core.ChangeUpdate, ID: tenant.ID, Name: "acme-corp", OldName: "acme"}, nextChange(t, changes))
+	stop()
+
+	// Changes made while stopped are delivered after a restart from the saved token
+	require.NoError(t, repo.Delete(ctx, tenant.ID))
+	changes, stop = runWatcher(t, repo, "test")
+	defer stop()
+
+	assert.Equal(t, core.TenantChange{Op: core.ChangeDelete, ID: tenant.ID, Name: "acme-corp"}, nextChange(t, changes))
+}
@@ -0,0 +1,220 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// fakeStream replays events, then fails with err or ends when ctx is done
+type fakeStream struct {
+	events  []bson.M
+	current bson.Raw
+	err     error
+	resumed int
+}
+
+func (s *fakeStream) Next(ctx context.Context) bool {
+	if len(s.events) == 0 {
+		if s.err == nil {
+			<-ctx.Done()
+		}
+		return false
+	}
+	s.current, _ = bson.Marshal(s.events[0])
+	s.events = s.events[1:]
+	s.resumed++
+	return true
+}
+
+func (s *fakeStream) Decode(val interface{}) error {
+	return bson.Unmarshal(s.current, val)
+}
+
+func (s *fakeStream) ResumeToken() bson.Raw {
+	token, _ := bson.Marshal(bson.M{"_data": s.resumed})
+	return token
+}
+
+func (s *fakeStream) Err() error {
+	return s.err
+}
+
+func (s *fakeStream) Close(ctx context.Context) error {
+	return nil
+}
+
+// memoryTokenStore keeps the resume token in memory
+type memoryTokenStore struct {
+	token bson.Raw
+}
+
+func (s *memoryTokenStore) Load(ctx context.Context) (bson.Raw, error) {
+	return s.token, nil
+}
+
+func (s *memoryTokenStore) Save(ctx context.Context, token bson.Raw) error {
+	s.token = token
+	return nil
+}
+
+func newTestWatcher(existing []tenantRef, streams ...*fakeStream) (*Watcher, *memoryTokenStore, *[]bson.Raw) {
+	tokens := &memoryTokenStore{}
+	var opened []bson.Raw
+
+	watcher := &Watcher{
+		config: WatcherConfig{
+			TokenStore:        tokens,
+			ReconnectDelay:    10 * time.Millisecond,
+			MaxReconnectDelay: 10 * time.Millisecond,
+		},
+		open: func(ctx context.Context, resumeAfter bson.Raw) (changeStream, error) {
+			opened = append(opened, resumeAfter)
+			if len(streams) == 0 {
+				return nil, errors.New("no stream")
+			}
+			stream := streams[0]
+			streams = streams[1:]
+			return stream, nil
+		},
+		scan: func(ctx context.Context) ([]tenantRef, error) {
+			return existing, nil
+		},
+		sleep: func(ctx context.Context, d time.Duration) error {
+			return ctx.Err()
+		},
+		logger: slog.New(slog.DiscardHandler),
+		known:  make(map[string]tenantRef),
+	}
+
+	return watcher, tokens, &opened
+}
+
+func objectKey(id primitive.ObjectID) bson.M {
+	return bson.M{"_id": id}
+}
+
+func refFor(t *testing.T, id primitive.ObjectID, tenantID, name string) tenantRef {
+	raw, err := bson.Marshal(bson.M{"_id": id, "id": tenantID, "name": name})
+	require.NoError(t, err)
+	var ref tenantRef
+	require.NoError(t, bson.Unmarshal(raw, &ref))
+	return ref
+}
+
+func TestWatcher_DecodesEvents(t *testing.T) {
+	acmeKey, betaKey := primitive.NewObjectID(), primitive.NewObjectID()
+	stream := &fakeStream{events: []bson.M{
+		{"operationType": "insert", "documentKey": objectKey(betaKey),
+			"fullDocument": bson.M{"_id": betaKey, "id": "t2", "name": "beta"}},
+		{"operationType": "update", "documentKey": objectKey(acmeKey),
+			"fullDocument":      bson.M{"_id": acmeKey, "id": "t1", "name": "acme-corp"},
+			"updateDescription": bson.M{"updatedFields": bson.M{"name": "acme-corp"}, "removedFields": bson.A{}}},
+		{"operationType": "update", "documentKey": objectKey(acmeKey),
+			"fullDocument":      bson.M{"_id": acmeKey, "id": "t1", "name": "acme-corp"},
+			"updateDescription": bson.M{"updatedFields": bson.M{"datasources.0.dsn": "x", "updated_at": 1}, "removedFields": bson.A{}}},
+		{"operationType": "delete", "documentKey": objectKey(betaKey)},
+		{"operationType": "delete", "documentKey": objectKey(primitive.NewObjectID())},
+	}}
+	watcher, tokens, _ := newTestWatcher([]tenantRef{refFor(t, acmeKey, "t1", "acme")}, stream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var changes []core.TenantChange
+	err := watcher.Run(ctx, func(ctx context.Context, change core.TenantChange) {
+		changes = append(changes, change)
+		if len(changes) == 5 {
+			cancel()
+		}
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []core.TenantChange{
+		{Op: core.ChangeInsert, ID: "t2", Name: "beta"},
+		{Op: core.ChangeUpdate, ID: "t1", Name: "acme-corp", OldName: "acme"},
+		{Op: core.ChangeDatasource, ID: "t1", Name: "acme-corp"},
+		{Op: core.ChangeDelete, ID: "t2", Name: "beta"},
+		{Op: core.ChangeResync},
+	}, changes)
+	assert.NotNil(t, tokens.token)
+}
+
+func TestWatcher_UsesPreImages(t *testing.T) {
+	key := primitive.NewObjectID()
+	stream := &fakeStream{events: []bson.M{
+		{"operationType": "delete", "documentKey": objectKey(key),
+			"fullDocumentBeforeChange": bson.M{"_id": key, "id": "t1", "name": "acme"}},
+	}}
+	watcher, _, _ := newTestWatcher(nil, stream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var changes []core.TenantChange
+	err := watcher.Run(ctx, func(ctx context.Context, change core.TenantChange) {
+		changes = append(changes, change)
+		cancel()
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []core.TenantChange{{Op: core.ChangeDelete, ID: "t1", Name: "acme"}}, changes)
+}
+
+func TestWatcher_ResumesFromSavedToken(t *testing.T) {
+	key := primitive.NewObjectID()
+	first := &fakeStream{
+		events: []bson.M{{"operationType": "insert", "documentKey": objectKey(key),
+			"fullDocument": bson.M{"_id": key, "id": "t1", "name": "acme"}}},
+		err: errors.New("connection reset"),
+	}
+	second := &fakeStream{}
+	watcher, tokens, opened := newTestWatcher(nil, first, second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ops []core.ChangeOp
+	watcher.scan = func(ctx context.Context) ([]tenantRef, error) {
+		if len(*opened) == 2 {
+			cancel()
+		}
+		return nil, nil
+	}
+
+	err := watcher.Run(ctx, func(ctx context.Context, change core.TenantChange) {
+		ops = append(ops, change.Op)
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []core.ChangeOp{core.ChangeInsert}, ops)
+	require.Len(t, *opened, 2)
+	assert.Nil(t, (*opened)[0])
+	assert.Equal(t, tokens.token, (*opened)[1])
+}
+
+func TestWatcher_ResyncsWhenHistoryIsLost(t *testing.T) {
+	lost := &fakeStream{err: mongo.CommandError{Code: changeStreamHistoryLost, Message: "resume point no longer in oplog"}}
+	watcher, tokens, _ := newTestWatcher(nil, lost, &fakeStream{})
+	tokens.token, _ = bson.Marshal(bson.M{"_data": "stale"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ops []core.ChangeOp
+	err := watcher.Run(ctx, func(ctx context.Context, change core.TenantChange) {
+		ops = append(ops, change.Op)
+		cancel()
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []core.ChangeOp{core.ChangeResync}, ops)
+	assert.Nil(t, tokens.token)
+}
+
+func TestNewWatcher_RequiresRepository(t *testing.T) {
+	_, err := NewWatcher(nil, WatcherConfig{})
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeConfigInvalid))
+}
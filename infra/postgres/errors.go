@@ -1,10 +1,15 @@
 package postgres
 
 import (
+	"context"
 	"errors"
+	"net"
+	"regexp"
 	"strings"
 
 	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/victorximenis/multitenant/core"
 )
 
 // PostgreSQL error codes
@@ -17,60 +22,131 @@ const (
 	CheckViolationCode = "23514"
 	// Not null violation
 	NotNullViolationCode = "23502"
+	// Statement cancelled, e.g. by statement_timeout
+	QueryCanceledCode = "57014"
+	// Server shutting down or terminating the connection
+	AdminShutdownCode = "57P01"
+	// Too many connections
+	TooManyConnectionsCode = "53300"
+	// Serialization failure under repeatable read or serializable isolation
+	SerializationFailureCode = "40001"
+	// Deadlock detected
+	DeadlockDetectedCode = "40P01"
+	// Relation does not exist
+	UndefinedTableCode = "42P01"
+	// Class 08: connection exceptions
+	connectionExceptionClass = "08"
 )
 
-// mapPostgreSQLError maps PostgreSQL errors to application-level errors
+// keyValuePattern extracts the conflicting value from a unique violation detail,
+// e.g. `Key (name)=(acme) already exists.`
+var keyValuePattern = regexp.MustCompile(`^Key \([^)]*\)=\((.*)\) already exists`)
+
+// mapPostgreSQLError maps driver and PostgreSQL errors to *core.MultitenantError.
+// Errors that are already structured are returned unchanged.
 func mapPostgreSQLError(err error) error {
 	if err == nil {
 		return nil
 	}
 
+	var mtErr *core.MultitenantError
+	if errors.As(err, &mtErr) {
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return core.ErrDatabaseTimeout(err)
+	}
+
 	var pgErr *pgconn.PgError
 	if !errors.As(err, &pgErr) {
-		// Not a PostgreSQL error, return as-is
-		return err
+		return mapDriverError(err)
 	}
 
-	switch pgErr.Code {
-	case UniqueViolationCode:
+	switch {
+	case pgErr.Code == UniqueViolationCode:
 		return mapUniqueViolationError(pgErr)
-	case ForeignKeyViolationCode:
+	case pgErr.Code == ForeignKeyViolationCode:
 		return mapForeignKeyViolationError(pgErr)
-	case CheckViolationCode:
+	case pgErr.Code == CheckViolationCode:
 		return mapCheckViolationError(pgErr)
-	case NotNullViolationCode:
+	case pgErr.Code == NotNullViolationCode:
 		return mapNotNullViolationError(pgErr)
+	case pgErr.Code == QueryCanceledCode:
+		return core.ErrDatabaseTimeout(pgErr)
+	case pgErr.Code == SerializationFailureCode, pgErr.Code == DeadlockDetectedCode:
+		return core.ErrDatabaseSerialization(pgErr)
+	case pgErr.Code == AdminShutdownCode, pgErr.Code == TooManyConnectionsCode,
+		strings.HasPrefix(pgErr.Code, connectionExceptionClass):
+		return connectionError(pgErr)
 	default:
-		// Return the original error with additional context
-		return errors.New("database error: " + pgErr.Message)
+		return core.NewError(core.ErrCodeDatabaseQuery, "database error: "+pgErr.Message).
+			WithDetail("sqlstate", pgErr.Code).
+			WithCause(pgErr)
 	}
 }
 
+// mapDriverError maps errors raised by the driver rather than the server
+func mapDriverError(err error) error {
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return connectionError(err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return core.ErrDatabaseTimeout(err)
+		}
+		return connectionError(err)
+	}
+
+	return core.NewError(core.ErrCodeDatabaseQuery, "database query failed").WithCause(err)
+}
+
+// connectionError reports a lost or refused PostgreSQL connection
+func connectionError(cause error) error {
+	return core.NewError(core.ErrCodeDatabaseConnection, "database connection failed").WithCause(cause)
+}
+
 // mapUniqueViolationError maps unique constraint violations
 func mapUniqueViolationError(pgErr *pgconn.PgError) error {
-	if strings.Contains(pgErr.Detail, "tenants_name_key") {
-		return errors.New("tenant name already exists")
+	value := ""
+	if match := keyValuePattern.FindStringSubmatch(pgErr.Detail); match != nil {
+		value = match[1]
+	}
+
+	switch {
+	case strings.HasSuffix(pgErr.ConstraintName, "tenants_name_key"),
+		strings.HasSuffix(pgErr.ConstraintName, "tenants_pkey"),
+		strings.Contains(pgErr.Detail, "tenants_name_key"):
+		return core.ErrTenantExists(value).WithCause(pgErr)
+	case strings.HasSuffix(pgErr.ConstraintName, "datasources_pkey"):
+		return core.ErrValidationFailed("datasource", "datasource ID already exists: "+value).WithCause(pgErr)
+	default:
+		return core.NewError(core.ErrCodeTenantExists, "unique constraint violation: "+pgErr.Detail).
+			WithDetail("constraint", pgErr.ConstraintName).
+			WithCause(pgErr)
 	}
-	return errors.New("unique constraint violation: " + pgErr.Detail)
 }
 
 // mapForeignKeyViolationError maps foreign key constraint violations
 func mapForeignKeyViolationError(pgErr *pgconn.PgError) error {
 	if strings.Contains(pgErr.Detail, "tenant_id") {
-		return errors.New("referenced tenant does not exist")
+		return core.ErrValidationFailed("datasource", "referenced tenant does not exist").WithCause(pgErr)
 	}
-	return errors.New("foreign key constraint violation: " + pgErr.Detail)
+	return core.ErrValidationFailed("datasource", "foreign key constraint violation: "+pgErr.Detail).WithCause(pgErr)
 }
 
 // mapCheckViolationError maps check constraint violations
 func mapCheckViolationError(pgErr *pgconn.PgError) error {
-	if strings.Contains(pgErr.Detail, "role") {
-		return errors.New("invalid datasource role: must be one of 'read', 'write', 'rw'")
+	if strings.Contains(pgErr.Detail, "role") || strings.Contains(pgErr.ConstraintName, "role") {
+		return core.ErrValidationFailed("datasource", "invalid datasource role: must be one of 'read', 'write', 'rw'").WithCause(pgErr)
 	}
-	return errors.New("check constraint violation: " + pgErr.Detail)
+	return core.ErrValidationFailed("tenant", "check constraint violation: "+pgErr.Detail).WithCause(pgErr)
 }
 
 // mapNotNullViolationError maps not null constraint violations
 func mapNotNullViolationError(pgErr *pgconn.PgError) error {
-	return errors.New("required field cannot be null: " + pgErr.ColumnName)
+	return core.ErrValidationFailed(pgErr.TableName, "required field cannot be null: "+pgErr.ColumnName).WithCause(pgErr)
 }
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Compile-time check to ensure TenantRepository implements core.LabelLister interface
+var _ core.LabelLister = (*TenantRepository)(nil)
+
+// ListByLabels returns the tenants matching selector ordered by name. Every requirement
+// is a JSONB containment or key existence test served by the GIN index on labels.
+func (r *TenantRepository) ListByLabels(ctx context.Context, selector core.LabelSelector) ([]core.Tenant, error) {
+	where, args, err := labelFilter(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, r.sql(`
+		SELECT id, name, is_active, metadata, labels, created_at, updated_at
+		FROM {tenants}
+		WHERE `+where+`
+		ORDER BY name
+	`), args...)
+	if err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+
+	tenants, err := scanTenants(rows)
+	if err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+
+	if err := r.loadDatasources(ctx, tenants); err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+
+	return tenants, nil
+}
+
+// labelFilter renders selector as a WHERE clause with positional arguments
+func labelFilter(selector core.LabelSelector) (string, []interface{}, error) {
+	if err := selector.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	anyOf := func(key string, values []string) (string, error) {
+		clauses := make([]string, len(values))
+		for i, value := range values {
+			document, err := json.Marshal(map[string]string{key: value})
+			if err != nil {
+				return "", core.ErrValidationFailed("label selector", err.Error()).WithCause(err)
+			}
+			clauses[i] = "labels @> " + arg(string(document)) + "::jsonb"
+		}
+		if len(clauses) == 1 {
+			return clauses[0], nil
+		}
+		return "(" + strings.Join(clauses, " OR ") + ")", nil
+	}
+
+	clauses := make([]string, 0, len(selector.Requirements))
+	for _, requirement := range selector.Requirements {
+		switch requirement.Operator {
+		case core.SelectorExists:
+			clauses = append(clauses, "labels ? "+arg(requirement.Key))
+		case core.SelectorDoesNotExist:
+			clauses = append(clauses, "NOT labels ? "+arg(requirement.Key))
+		case core.SelectorEquals, core.SelectorIn:
+			clause, err := anyOf(requirement.Key, requirement.Values)
+			if err != nil {
+				return "", nil, err
+			}
+			clauses = append(clauses, clause)
+		case core.SelectorNotEquals, core.SelectorNotIn:
+			clause, err := anyOf(requirement.Key, requirement.Values)
+			if err != nil {
+				return "", nil, err
+			}
+			clauses = append(clauses, "NOT "+clause)
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "TRUE", nil, nil
+	}
+	return strings.Join(clauses, " AND "), args, nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func TestLabelFilter(t *testing.T) {
+	where, args, err := labelFilter(core.MustParseLabelSelector("tier=gold,region in (eu,us),env!=dev,zone notin (a),beta,!legacy"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "labels @> $1::jsonb"+
+		" AND (labels @> $2::jsonb OR labels @> $3::jsonb)"+
+		" AND NOT labels @> $4::jsonb"+
+		" AND NOT labels @> $5::jsonb"+
+		" AND labels ? $6"+
+		" AND NOT labels ? $7",
+		where)
+	assert.Equal(t, []interface{}{
+		`{"tier":"gold"}`,
+		`{"region":"eu"}`,
+		`{"region":"us"}`,
+		`{"env":"dev"}`,
+		`{"zone":"a"}`,
+		"beta",
+		"legacy",
+	}, args)
+
+	where, args, err = labelFilter(core.LabelSelector{})
+	require.NoError(t, err)
+	assert.Equal(t, "TRUE", where)
+	assert.Empty(t, args)
+
+	_, _, err = labelFilter(core.LabelSelector{Requirements: []core.LabelRequirement{
+		{Key: "tier'; DROP TABLE tenants; --", Operator: core.SelectorExists},
+	}})
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeValidationFailed))
+}
+
+func TestTenantRepository_ListByLabels(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &TenantRepository{pool: mock}
+	tenantID := "123e4567-e89b-12d3-a456-426614174000"
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM tenants WHERE labels @> $1::jsonb ORDER BY name")).
+		WithArgs(`{"tier":"gold"}`).
+		WillReturnRows(mock.NewRows([]string{"id", "name", "is_active", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(tenantID, "acme", true, nil, []byte(`{"tier":"gold"}`), nil, nil))
+	mock.ExpectQuery("FROM datasources WHERE tenant_id = ANY").
+		WithArgs([]string{tenantID}).
+		WillReturnRows(mock.NewRows([]string{"tenant_id", "id", "dsn", "role", "pool_size", "metadata", "created_at", "updated_at"}))
+
+	tenants, err := repo.ListByLabels(context.Background(), core.MustParseLabelSelector("tier=gold"))
+	require.NoError(t, err)
+	require.Len(t, tenants, 1)
+	assert.Equal(t, map[string]string{"tier": "gold"}, tenants[0].Labels)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
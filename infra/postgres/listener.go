@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Default reconnect backoff of the change listener
+const (
+	DefaultReconnectDelay    = time.Second
+	DefaultMaxReconnectDelay = 30 * time.Second
+)
+
+// ListenerConfig holds configuration for the tenant change listener
+type ListenerConfig struct {
+	// DSN of the registry database; the listener holds one dedicated connection
+	DSN string
+	// Options select the schema and table prefix, and therefore the channel name, and
+	// the logger
+	Options Options
+	// ReconnectDelay is the initial wait before reconnecting, doubled up to MaxReconnectDelay
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// OnError is called for connection failures and malformed notifications, which are
+	// also logged
+	OnError func(error)
+}
+
+// listenConn is the subset of *pgx.Conn used by the listener
+type listenConn interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// Listener receives the NOTIFY messages sent by the registry triggers and reconnects
+// automatically when the connection is lost
+type Listener struct {
+	config  ListenerConfig
+	channel string
+	connect func(ctx context.Context) (listenConn, error)
+	sleep   func(ctx context.Context, d time.Duration) error
+	logger  *slog.Logger
+}
+
+// NewListener creates a tenant change listener; call Run to start listening
+func NewListener(config ListenerConfig) (*Listener, error) {
+	if config.DSN == "" {
+		return nil, core.ErrConfigInvalid("DSN", "listener DSN is required")
+	}
+	if err := config.Options.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := pgx.ParseConfig(config.DSN); err != nil {
+		return nil, core.ErrConfigInvalid("DSN", err.Error()).WithCause(err)
+	}
+
+	if config.ReconnectDelay <= 0 {
+		config.ReconnectDelay = DefaultReconnectDelay
+	}
+	if config.MaxReconnectDelay < config.ReconnectDelay {
+		config.MaxReconnectDelay = DefaultMaxReconnectDelay
+		if config.MaxReconnectDelay < config.ReconnectDelay {
+			config.MaxReconnectDelay = config.ReconnectDelay
+		}
+	}
+
+	return &Listener{
+		config:  config,
+		channel: newNaming(config.Options.Schema, config.Options.TablePrefix).channel,
+		connect: func(ctx context.Context) (listenConn, error) {
+			return pgx.Connect(ctx, config.DSN)
+		},
+		sleep:  sleepContext,
+		logger: core.ComponentLogger(config.Options.Logger, "postgres.listener"),
+	}, nil
+}
+
+// Channel returns the NOTIFY channel the listener subscribes to
+func (l *Listener) Channel() string {
+	return l.channel
+}
+
+// Run listens for tenant changes and calls handler for each one until ctx is done.
+// After every reconnect handler receives a core.ChangeResync, because changes made
+// while disconnected were not delivered.
+func (l *Listener) Run(ctx context.Context, handler func(context.Context, core.TenantChange)) error {
+	delay := l.config.ReconnectDelay
+	connected := false
+
+	for {
+		err := l.listen(ctx, func() {
+			if connected {
+				l.logger.InfoContext(ctx, "reconnected to the registry, resyncing tenant changes", "channel", l.channel)
+				handler(ctx, core.TenantChange{Op: core.ChangeResync})
+			}
+			connected = true
+			delay = l.config.ReconnectDelay
+		}, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		l.reportError(ctx, mapPostgreSQLError(err))
+		if err := l.sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay *= 2
+		if delay > l.config.MaxReconnectDelay {
+			delay = l.config.MaxReconnectDelay
+		}
+	}
+}
+
+// listen holds one connection until it fails, calling ready once LISTEN succeeded
+func (l *Listener) listen(ctx context.Context, ready func(), handler func(context.Context, core.TenantChange)) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+l.channel); err != nil {
+		return err
+	}
+	ready()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var change core.TenantChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			l.reportError(ctx, core.NewError(core.ErrCodeValidationFailed, "malformed tenant change notification").
+				WithDetail("payload", notification.Payload).
+				WithCause(err))
+			continue
+		}
+
+		handler(ctx, change)
+	}
+}
+
+// reportError logs err and forwards it to the configured error callback
+func (l *Listener) reportError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	l.logger.WarnContext(ctx, "tenant change listener failed", "channel", l.channel, core.LogKeyError, err)
+	if l.config.OnError != nil {
+		l.config.OnError(err)
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
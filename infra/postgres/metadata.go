@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Compile-time check to ensure TenantRepository implements core.MetadataQuerier interface
+var _ core.MetadataQuerier = (*TenantRepository)(nil)
+
+// FindByMetadata returns the tenants matching query ordered by name. Equality and in
+// conditions compare the JSONB value at the path exactly, behind a containment check
+// that lets the GIN index on metadata narrow the rows; exists on top-level keys uses
+// the ? operator, which the index also serves.
+func (r *TenantRepository) FindByMetadata(ctx context.Context, query core.MetadataQuery) ([]core.Tenant, error) {
+	where, args, err := metadataFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, r.sql(`
+		SELECT id, name, is_active, metadata, labels, created_at, updated_at
+		FROM {tenants}
+		WHERE `+where+`
+		ORDER BY name
+	`), args...)
+	if err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+
+	tenants, err := scanTenants(rows)
+	if err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+
+	if err := r.loadDatasources(ctx, tenants); err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+
+	return tenants, nil
+}
+
+// metadataFilter renders query as a WHERE clause with positional arguments
+func metadataFilter(query core.MetadataQuery) (string, []interface{}, error) {
+	if err := query.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	encode := func(value interface{}) (string, error) {
+		document, err := json.Marshal(value)
+		if err != nil {
+			return "", core.ErrValidationFailed("metadata query", err.Error()).WithCause(err)
+		}
+		return string(document), nil
+	}
+	// equals compares the value at path exactly, as core.MetadataCondition.Matches
+	// does; containment alone would also match objects and arrays holding value
+	equals := func(path []string, value interface{}) (string, error) {
+		nested, err := encode(nestMetadata(path, value))
+		if err != nil {
+			return "", err
+		}
+		document, err := encode(value)
+		if err != nil {
+			return "", err
+		}
+		return "(metadata @> " + arg(nested) + "::jsonb AND metadata #> " + arg(path) + "::text[] = " + arg(document) + "::jsonb)", nil
+	}
+
+	clauses := make([]string, 0, len(query.Conditions))
+	for _, condition := range query.Conditions {
+		path := condition.Segments()
+
+		var clause string
+		switch condition.Operator {
+		case core.MetadataOpEq:
+			c, err := equals(path, condition.Value)
+			if err != nil {
+				return "", nil, err
+			}
+			clause = c
+		case core.MetadataOpNe:
+			document, err := encode(condition.Value)
+			if err != nil {
+				return "", nil, err
+			}
+			// A tenant without the path does not equal anything
+			clause = "NOT COALESCE(metadata #> " + arg(path) + "::text[] = " + arg(document) + "::jsonb, FALSE)"
+		case core.MetadataOpIn:
+			alternatives := make([]string, len(condition.Values))
+			for i, value := range condition.Values {
+				c, err := equals(path, value)
+				if err != nil {
+					return "", nil, err
+				}
+				alternatives[i] = c
+			}
+			clause = "(" + strings.Join(alternatives, " OR ") + ")"
+		case core.MetadataOpExists:
+			if len(path) == 1 {
+				clause = "metadata ? " + arg(path[0])
+			} else {
+				clause = "metadata #> " + arg(path) + "::text[] IS NOT NULL"
+			}
+		default:
+			clause = compareMetadata(path, condition, arg)
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if len(clauses) == 0 {
+		return "TRUE", nil, nil
+	}
+	return strings.Join(clauses, " AND "), args, nil
+}
+
+// compareMetadata renders an ordering comparison that only matches values of the same
+// JSON type as the operand; CASE keeps the cast from running on other types
+func compareMetadata(path []string, condition core.MetadataCondition, arg func(interface{}) string) string {
+	operators := map[core.MetadataOperator]string{
+		core.MetadataOpGt:  ">",
+		core.MetadataOpGte: ">=",
+		core.MetadataOpLt:  "<",
+		core.MetadataOpLte: "<=",
+	}
+
+	p := arg(path)
+	if value, ok := condition.Value.(string); ok {
+		return fmt.Sprintf(`CASE WHEN jsonb_typeof(metadata #> %[1]s::text[]) = 'string' `+
+			`THEN (metadata #>> %[1]s::text[]) COLLATE "C" %[2]s %[3]s ELSE FALSE END`,
+			p, operators[condition.Operator], arg(value))
+	}
+
+	return fmt.Sprintf(`CASE WHEN jsonb_typeof(metadata #> %[1]s::text[]) = 'number' `+
+		`THEN (metadata #>> %[1]s::text[])::numeric %[2]s %[3]s::numeric ELSE FALSE END`,
+		p, operators[condition.Operator], arg(condition.Value))
+}
+
+// nestMetadata builds the document {"a": {"b": value}} for the path a.b
+func nestMetadata(path []string, value interface{}) map[string]interface{} {
+	document := map[string]interface{}{path[len(path)-1]: value}
+	for i := len(path) - 2; i >= 0; i-- {
+		document = map[string]interface{}{path[i]: document}
+	}
+	return document
+}
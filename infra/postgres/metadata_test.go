@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func TestMetadataFilter(t *testing.T) {
+	where, args, err := metadataFilter(core.NewMetadataQuery(
+		core.MetadataEquals("region", "eu"),
+		core.MetadataIn("billing.plan", "pro", "enterprise"),
+		core.MetadataExists("owner"),
+		core.MetadataExists("billing.seats"),
+		core.MetadataNotEquals("tier", 1),
+		core.MetadataGreaterThan("billing.seats", 10),
+		core.MetadataLessOrEqual("since", "2024-01-01"),
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, "(metadata @> $1::jsonb AND metadata #> $2::text[] = $3::jsonb)"+
+		" AND ((metadata @> $4::jsonb AND metadata #> $5::text[] = $6::jsonb)"+
+		" OR (metadata @> $7::jsonb AND metadata #> $8::text[] = $9::jsonb))"+
+		" AND metadata ? $10"+
+		" AND metadata #> $11::text[] IS NOT NULL"+
+		" AND NOT COALESCE(metadata #> $12::text[] = $13::jsonb, FALSE)"+
+		" AND CASE WHEN jsonb_typeof(metadata #> $14::text[]) = 'number' THEN (metadata #>> $14::text[])::numeric > $15::numeric ELSE FALSE END"+
+		` AND CASE WHEN jsonb_typeof(metadata #> $16::text[]) = 'string' THEN (metadata #>> $16::text[]) COLLATE "C" <= $17 ELSE FALSE END`,
+		where)
+	assert.Equal(t, []interface{}{
+		`{"region":"eu"}`, []string{"region"}, `"eu"`,
+		`{"billing":{"plan":"pro"}}`, []string{"billing", "plan"}, `"pro"`,
+		`{"billing":{"plan":"enterprise"}}`, []string{"billing", "plan"}, `"enterprise"`,
+		"owner",
+		[]string{"billing", "seats"},
+		[]string{"tier"}, `1`,
+		[]string{"billing", "seats"}, 10,
+		[]string{"since"}, "2024-01-01",
+	}, args)
+
+	where, args, err = metadataFilter(core.NewMetadataQuery(core.MetadataEquals("tags", []interface{}{"a", "b"})))
+	require.NoError(t, err)
+	assert.Equal(t, "(metadata @> $1::jsonb AND metadata #> $2::text[] = $3::jsonb)", where)
+	assert.Equal(t, []interface{}{`{"tags":["a","b"]}`, []string{"tags"}, `["a","b"]`}, args)
+
+	where, args, err = metadataFilter(core.MetadataQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, "TRUE", where)
+	assert.Empty(t, args)
+
+	_, _, err = metadataFilter(core.NewMetadataQuery(core.MetadataEquals("region'); DROP TABLE tenants; --", "eu")))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeValidationFailed))
+}
+
+func TestTenantRepository_FindByMetadata(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &TenantRepository{pool: mock}
+	tenantID := "123e4567-e89b-12d3-a456-426614174000"
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM tenants WHERE (metadata @> $1::jsonb AND metadata #> $2::text[] = $3::jsonb) ORDER BY name")).
+		WithArgs(`{"region":"eu"}`, []string{"region"}, `"eu"`).
+		WillReturnRows(mock.NewRows([]string{"id", "name", "is_active", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(tenantID, "acme", true, []byte(`{"region":"eu"}`), []byte(`{}`), nil, nil))
+	mock.ExpectQuery("FROM datasources WHERE tenant_id = ANY").
+		WithArgs([]string{tenantID}).
+		WillReturnRows(mock.NewRows([]string{"tenant_id", "id", "dsn", "role", "pool_size", "metadata", "created_at", "updated_at"}))
+
+	tenants, err := repo.FindByMetadata(context.Background(), core.NewMetadataQuery(core.MetadataEquals("region", "eu")))
+	require.NoError(t, err)
+	require.Len(t, tenants, 1)
+	assert.Equal(t, "acme", tenants[0].Name)
+	assert.Equal(t, "eu", tenants[0].Metadata["region"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
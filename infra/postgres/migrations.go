@@ -0,0 +1,450 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// migrationLockKey identifies the advisory lock held while migrating the registry schema
+const migrationLockKey int64 = 0x6d74656e616e74 // "mtenant"
+
+// Migration is a versioned, reversible change to the registry schema. Table names are
+// written as {table} placeholders and index names start with {prefix}, so the same
+// migration can target any schema and table prefix.
+type Migration struct {
+	Version     int
+	Description string
+	Up          string
+	// Indexes holds the secondary indexes created with Up unless Options.SkipIndexes is set
+	Indexes string
+	Down    string
+}
+
+// migrations lists every schema change in version order. Released migrations must never be
+// edited; add a new version instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create tenants and datasources",
+		Up: `
+CREATE TABLE IF NOT EXISTS {tenants} (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  is_active BOOLEAN DEFAULT TRUE,
+  metadata JSONB,
+  created_at TIMESTAMP DEFAULT now(),
+  updated_at TIMESTAMP DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS {datasources} (
+  id UUID PRIMARY KEY,
+  tenant_id UUID NOT NULL REFERENCES {tenants}(id) ON DELETE CASCADE,
+  dsn TEXT NOT NULL,
+  role TEXT CHECK (role IN ('read', 'write', 'rw')) NOT NULL,
+  pool_size INTEGER DEFAULT 10,
+  metadata JSONB,
+  created_at TIMESTAMP DEFAULT now(),
+  updated_at TIMESTAMP DEFAULT now()
+);
+`,
+		Indexes: `
+CREATE INDEX IF NOT EXISTS {prefix}idx_tenants_name ON {tenants}(name);
+CREATE INDEX IF NOT EXISTS {prefix}idx_tenants_active ON {tenants}(is_active);
+CREATE INDEX IF NOT EXISTS {prefix}idx_datasources_tenant_id ON {datasources}(tenant_id);
+CREATE INDEX IF NOT EXISTS {prefix}idx_datasources_role ON {datasources}(role);
+`,
+		Down: `
+DROP TABLE IF EXISTS {datasources};
+DROP TABLE IF EXISTS {tenants};
+`,
+	},
+	{
+		Version:     2,
+		Description: "create tenant templates",
+		Up: `
+CREATE TABLE IF NOT EXISTS {tenant_templates} (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  definition JSONB NOT NULL,
+  created_at TIMESTAMP DEFAULT now(),
+  updated_at TIMESTAMP DEFAULT now()
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS {tenant_templates};
+`,
+	},
+	{
+		Version:     3,
+		Description: "notify tenant changes",
+		Up: `
+CREATE OR REPLACE FUNCTION {notify_tenant_change}() RETURNS trigger AS $$
+DECLARE
+  changed RECORD;
+  payload JSONB;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    changed := OLD;
+  ELSE
+    changed := NEW;
+  END IF;
+
+  IF TG_ARGV[0] = 'tenants' THEN
+    payload := jsonb_build_object('op', TG_OP, 'id', changed.id, 'name', changed.name);
+    IF TG_OP = 'UPDATE' THEN
+      IF OLD.name IS DISTINCT FROM NEW.name THEN
+        payload := payload || jsonb_build_object('old_name', OLD.name);
+      END IF;
+    END IF;
+  ELSE
+    -- A datasource change is an update of its tenant
+    payload := jsonb_build_object('op', 'UPDATE', 'id', changed.tenant_id,
+      'name', (SELECT name FROM {tenants} WHERE id = changed.tenant_id));
+  END IF;
+
+  PERFORM pg_notify('{channel}', payload::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS {prefix}tenants_notify_change ON {tenants};
+CREATE TRIGGER {prefix}tenants_notify_change
+  AFTER INSERT OR UPDATE OR DELETE ON {tenants}
+  FOR EACH ROW EXECUTE PROCEDURE {notify_tenant_change}('tenants');
+
+DROP TRIGGER IF EXISTS {prefix}datasources_notify_change ON {datasources};
+CREATE TRIGGER {prefix}datasources_notify_change
+  AFTER INSERT OR UPDATE OR DELETE ON {datasources}
+  FOR EACH ROW EXECUTE PROCEDURE {notify_tenant_change}('datasources');
+`,
+		Down: `
+DROP TRIGGER IF EXISTS {prefix}datasources_notify_change ON {datasources};
+DROP TRIGGER IF EXISTS {prefix}tenants_notify_change ON {tenants};
+DROP FUNCTION IF EXISTS {notify_tenant_change}();
+`,
+	},
+	{
+		Version:     4,
+		Description: "index tenant metadata",
+		Up: `
+COMMENT ON COLUMN {tenants}.metadata IS 'Tenant metadata, queried by JSONB containment';
+`,
+		Indexes: `
+CREATE INDEX IF NOT EXISTS {prefix}idx_tenants_metadata ON {tenants} USING GIN (metadata);
+`,
+		Down: `
+DROP INDEX IF EXISTS {qualifier}{prefix}idx_tenants_metadata;
+COMMENT ON COLUMN {tenants}.metadata IS NULL;
+`,
+	},
+	{
+		Version:     5,
+		Description: "add tenant labels",
+		Up: `
+ALTER TABLE {tenants} ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+`,
+		Indexes: `
+CREATE INDEX IF NOT EXISTS {prefix}idx_tenants_labels ON {tenants} USING GIN (labels);
+`,
+		Down: `
+DROP INDEX IF EXISTS {qualifier}{prefix}idx_tenants_labels;
+ALTER TABLE {tenants} DROP COLUMN IF EXISTS labels;
+`,
+	},
+	{
+		Version:     6,
+		Description: "notify datasource changes as DATASOURCE",
+		Up:          notifyTenantChangeFunction(core.ChangeDatasource),
+		Down:        notifyTenantChangeFunction(core.ChangeUpdate),
+	},
+}
+
+// notifyTenantChangeFunction replaces the trigger function installed by migration 3,
+// reporting datasource changes with datasourceOp
+func notifyTenantChangeFunction(datasourceOp core.ChangeOp) string {
+	return `
+CREATE OR REPLACE FUNCTION {notify_tenant_change}() RETURNS trigger AS $$
+DECLARE
+  changed RECORD;
+  payload JSONB;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    changed := OLD;
+  ELSE
+    changed := NEW;
+  END IF;
+
+  IF TG_ARGV[0] = 'tenants' THEN
+    payload := jsonb_build_object('op', TG_OP, 'id', changed.id, 'name', changed.name);
+    IF TG_OP = 'UPDATE' THEN
+      IF OLD.name IS DISTINCT FROM NEW.name THEN
+        payload := payload || jsonb_build_object('old_name', OLD.name);
+      END IF;
+    END IF;
+  ELSE
+    payload := jsonb_build_object('op', '` + string(datasourceOp) + `', 'id', changed.tenant_id,
+      'name', (SELECT name FROM {tenants} WHERE id = changed.tenant_id));
+  END IF;
+
+  PERFORM pg_notify('{channel}', payload::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+`
+}
+
+// createMigrationsTableSQL creates the table that records applied migrations
+const createMigrationsTableSQL = `
+CREATE TABLE IF NOT EXISTS {schema_migrations} (
+  version INTEGER PRIMARY KEY,
+  description TEXT NOT NULL,
+  applied_at TIMESTAMP DEFAULT now()
+)`
+
+// MigrationConn is the subset of a single database connection used to run migrations.
+// Advisory locks are session scoped, so this must not be a pool.
+type MigrationConn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Migrator applies the registry schema migrations for a given schema and table prefix
+type Migrator struct {
+	schema      string
+	names       *naming
+	skipIndexes bool
+	logger      *slog.Logger
+}
+
+// defaultMigrator targets the unqualified tables of the original schema
+var defaultMigrator = &Migrator{names: defaultNaming}
+
+// NewMigrator creates a migrator for the tables described by opts
+func NewMigrator(opts Options) (*Migrator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		schema:      opts.Schema,
+		names:       newNaming(opts.Schema, opts.TablePrefix),
+		skipIndexes: opts.SkipIndexes,
+		logger:      opts.Logger,
+	}, nil
+}
+
+// Migrations returns the registry schema migrations in version order
+func Migrations() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+// LatestSchemaVersion returns the version the registry schema is migrated to by MigrateUp
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the currently applied schema version of the default tables
+func SchemaVersion(ctx context.Context, db MigrationConn) (int, error) {
+	return defaultMigrator.Version(ctx, db)
+}
+
+// MigrateUp applies every pending migration to the default tables
+func MigrateUp(ctx context.Context, db MigrationConn) error {
+	return defaultMigrator.Up(ctx, db)
+}
+
+// MigrateDown reverts the most recent steps migrations of the default tables
+func MigrateDown(ctx context.Context, db MigrationConn, steps int) error {
+	return defaultMigrator.Down(ctx, db, steps)
+}
+
+// MigrateTo migrates the default tables up or down to the given version
+func MigrateTo(ctx context.Context, db MigrationConn, version int) error {
+	return defaultMigrator.To(ctx, db, version)
+}
+
+// Version returns the currently applied schema version, or 0 for an empty database
+func (m *Migrator) Version(ctx context.Context, db MigrationConn) (int, error) {
+	if err := m.ensureMigrationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+	return m.currentVersion(ctx, db)
+}
+
+// checkVersion fails when the applied schema is older than LatestSchemaVersion. Unlike
+// Version it creates nothing, so it suits deployments that skip migrations on startup.
+func (m *Migrator) checkVersion(ctx context.Context, db MigrationConn) error {
+	version, err := m.currentVersion(ctx, db)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == UndefinedTableCode {
+		version, err = 0, nil
+	}
+	if err != nil {
+		return mapPostgreSQLError(err)
+	}
+
+	if version < LatestSchemaVersion() {
+		return core.ErrConfigInvalid("SkipMigrations", fmt.Sprintf(
+			"registry schema is at version %d but version %d is required (run MigrateUp or multitenant migrate)",
+			version, LatestSchemaVersion()))
+	}
+	return nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context, db MigrationConn) error {
+	return m.To(ctx, db, LatestSchemaVersion())
+}
+
+// Down reverts the most recent steps migrations
+func (m *Migrator) Down(ctx context.Context, db MigrationConn, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+
+	return m.withLock(ctx, db, func() error {
+		current, err := m.currentVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		target := 0
+		applied := 0
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if migrations[i].Version > current {
+				continue
+			}
+			if applied == steps {
+				target = migrations[i].Version
+				break
+			}
+			applied++
+		}
+
+		return m.migrate(ctx, db, current, target)
+	})
+}
+
+// To migrates the schema up or down to the given version
+func (m *Migrator) To(ctx context.Context, db MigrationConn, version int) error {
+	if version < 0 || version > LatestSchemaVersion() {
+		return fmt.Errorf("unknown schema version %d (latest is %d)", version, LatestSchemaVersion())
+	}
+
+	return m.withLock(ctx, db, func() error {
+		current, err := m.currentVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, db, current, version)
+	})
+}
+
+// withLock runs fn while holding the migration advisory lock, so that concurrent
+// startups apply each migration exactly once
+func (m *Migrator) withLock(ctx context.Context, db MigrationConn, fn func() error) error {
+	if _, err := db.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	// Unlock even if ctx was cancelled, otherwise the lock lives as long as the session
+	defer db.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	if err := m.ensureMigrationsTable(ctx, db); err != nil {
+		return err
+	}
+
+	return fn()
+}
+
+// ensureMigrationsTable creates the configured schema and the migrations table if needed
+func (m *Migrator) ensureMigrationsTable(ctx context.Context, db MigrationConn) error {
+	if m.schema != "" {
+		if _, err := db.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+m.schema); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec(ctx, m.names.sql(createMigrationsTableSQL))
+	return err
+}
+
+// currentVersion reads the highest applied migration version
+func (m *Migrator) currentVersion(ctx context.Context, db MigrationConn) (int, error) {
+	var version int
+	err := db.QueryRow(ctx, m.names.sql("SELECT COALESCE(MAX(version), 0) FROM {schema_migrations}")).Scan(&version)
+	return version, err
+}
+
+// migrate applies or reverts migrations between current and target, one transaction each
+func (m *Migrator) migrate(ctx context.Context, db MigrationConn, current, target int) error {
+	if target >= current {
+		for _, migration := range migrations {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+			if err := m.run(ctx, db, m.upSQL(migration),
+				"INSERT INTO {schema_migrations} (version, description) VALUES ($1, $2)",
+				migration.Version, migration.Description); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+			}
+			m.log().InfoContext(ctx, "applied registry migration",
+				"version", migration.Version, "description", migration.Description)
+		}
+		return nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+		if err := m.run(ctx, db, m.names.sql(migration.Down),
+			"DELETE FROM {schema_migrations} WHERE version = $1", migration.Version); err != nil {
+			return fmt.Errorf("reverting migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+		m.log().InfoContext(ctx, "reverted registry migration",
+			"version", migration.Version, "description", migration.Description)
+	}
+	return nil
+}
+
+// log returns the migration logger, resolving slog.Default() when none was configured
+func (m *Migrator) log() *slog.Logger {
+	return core.ComponentLogger(m.logger, "postgres.migrations")
+}
+
+// upSQL renders the statements applied by a migration, including its indexes unless skipped
+func (m *Migrator) upSQL(migration Migration) string {
+	statement := migration.Up
+	if migration.Indexes != "" && !m.skipIndexes {
+		statement += migration.Indexes
+	}
+	return m.names.sql(statement)
+}
+
+// run executes a migration statement and records it in the same transaction
+func (m *Migrator) run(ctx context.Context, db MigrationConn, statement, record string, args ...interface{}) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, statement); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, m.names.sql(record), args...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
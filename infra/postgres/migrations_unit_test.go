@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func expectMigrationLock(mock pgxmock.PgxConnIface, current int) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).
+		WithArgs(migrationLockKey).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+		WillReturnRows(mock.NewRows([]string{"version"}).AddRow(current))
+}
+
+func expectMigrationUnlock(mock pgxmock.PgxConnIface) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(migrationLockKey).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+}
+
+func TestMigrateUp_FromEmptyDatabase(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	expectMigrationLock(mock, 0)
+	for _, m := range migrations {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(defaultMigrator.upSQL(m))).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(m.Version, m.Description).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+	}
+	expectMigrationUnlock(mock)
+
+	require.NoError(t, MigrateUp(context.Background(), mock))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateUp_AlreadyCurrent(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	expectMigrationLock(mock, LatestSchemaVersion())
+	expectMigrationUnlock(mock)
+
+	require.NoError(t, MigrateUp(context.Background(), mock))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateUp_FailureRollsBack(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	expectMigrationLock(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(defaultMigrator.upSQL(migrations[1]))).WillReturnError(errors.New("permission denied"))
+	mock.ExpectRollback()
+	expectMigrationUnlock(mock)
+
+	err = MigrateUp(context.Background(), mock)
+	assert.ErrorContains(t, err, "migration 2")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateDown_RevertsSteps(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	latest := migrations[len(migrations)-1]
+
+	expectMigrationLock(mock, latest.Version)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(defaultNaming.sql(latest.Down))).WillReturnResult(pgxmock.NewResult("DROP TABLE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).
+		WithArgs(latest.Version).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+	expectMigrationUnlock(mock)
+
+	require.NoError(t, MigrateDown(context.Background(), mock, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateTo_UnknownVersion(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	assert.Error(t, MigrateTo(context.Background(), mock, LatestSchemaVersion()+1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_CheckVersion(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	versionQuery := regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	mock.ExpectQuery(versionQuery).
+		WillReturnRows(mock.NewRows([]string{"version"}).AddRow(LatestSchemaVersion()))
+	mock.ExpectQuery(versionQuery).
+		WillReturnRows(mock.NewRows([]string{"version"}).AddRow(LatestSchemaVersion() - 1))
+	mock.ExpectQuery(versionQuery).
+		WillReturnError(&pgconn.PgError{Code: UndefinedTableCode})
+	mock.ExpectQuery(versionQuery).
+		WillReturnError(&pgconn.PgError{Code: "08006"})
+
+	ctx := context.Background()
+	assert.NoError(t, defaultMigrator.checkVersion(ctx, mock))
+
+	outdated := defaultMigrator.checkVersion(ctx, mock)
+	assert.True(t, core.IsErrorCode(outdated, core.ErrCodeConfigInvalid))
+	assert.Contains(t, outdated.Error(),
+		fmt.Sprintf("version %d but version %d is required", LatestSchemaVersion()-1, LatestSchemaVersion()))
+
+	unmigrated := defaultMigrator.checkVersion(ctx, mock)
+	assert.True(t, core.IsErrorCode(unmigrated, core.ErrCodeConfigInvalid))
+
+	assert.True(t, core.IsErrorCode(defaultMigrator.checkVersion(ctx, mock), core.ErrCodeDatabaseConnection))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrations_AreOrdered(t *testing.T) {
+	for i, m := range Migrations() {
+		assert.Equal(t, i+1, m.Version)
+		assert.NotEmpty(t, m.Up)
+		assert.NotEmpty(t, m.Down)
+	}
+}
+
+func TestMigrator_SchemaAndPrefix(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	migrator, err := NewMigrator(Options{Schema: "platform", TablePrefix: "mt_", SkipIndexes: true})
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).
+		WithArgs(migrationLockKey).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE SCHEMA IF NOT EXISTS platform")).
+		WillReturnResult(pgxmock.NewResult("CREATE SCHEMA", 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS platform.mt_schema_migrations")).
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM platform.mt_schema_migrations")).
+		WillReturnRows(mock.NewRows([]string{"version"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS platform.mt_tenant_templates")).
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO platform.mt_schema_migrations")).
+		WithArgs(2, migrations[1].Description).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE OR REPLACE FUNCTION platform.mt_notify_tenant_change()")).
+		WillReturnResult(pgxmock.NewResult("CREATE TRIGGER", 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO platform.mt_schema_migrations")).
+		WithArgs(3, migrations[2].Description).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("COMMENT ON COLUMN platform.mt_tenants.metadata")).
+		WillReturnResult(pgxmock.NewResult("COMMENT", 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO platform.mt_schema_migrations")).
+		WithArgs(4, migrations[3].Description).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE platform.mt_tenants ADD COLUMN IF NOT EXISTS labels JSONB")).
+		WillReturnResult(pgxmock.NewResult("ALTER TABLE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO platform.mt_schema_migrations")).
+		WithArgs(5, migrations[4].Description).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE OR REPLACE FUNCTION platform.mt_notify_tenant_change()")).
+		WillReturnResult(pgxmock.NewResult("CREATE FUNCTION", 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO platform.mt_schema_migrations")).
+		WithArgs(6, migrations[5].Description).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	expectMigrationUnlock(mock)
+
+	require.NoError(t, migrator.Up(context.Background(), mock))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Datasource changes are reported as DATASOURCE from migration 6 on
+	assert.Contains(t, migrator.upSQL(migrations[2]), "'op', 'UPDATE', 'id', changed.tenant_id")
+	assert.Contains(t, migrator.upSQL(migrations[5]), "'op', 'DATASOURCE', 'id', changed.tenant_id")
+	assert.Contains(t, migrator.names.sql(migrations[5].Down), "'op', 'UPDATE', 'id', changed.tenant_id")
+
+	statement := migrator.upSQL(migrations[0])
+	assert.Contains(t, statement, "REFERENCES platform.mt_tenants(id)")
+	assert.NotContains(t, statement, "CREATE INDEX")
+
+	withIndexes, err := NewMigrator(Options{TablePrefix: "mt_"})
+	require.NoError(t, err)
+	assert.Contains(t, withIndexes.upSQL(migrations[0]), "CREATE INDEX IF NOT EXISTS mt_idx_tenants_name ON mt_tenants(name)")
+
+	notify := migrator.upSQL(migrations[2])
+	assert.Contains(t, notify, "pg_notify('platform_mt_tenant_changes'")
+	assert.Contains(t, notify, "AFTER INSERT OR UPDATE OR DELETE ON platform.mt_tenants")
+	assert.Contains(t, notify, "EXECUTE PROCEDURE platform.mt_notify_tenant_change('datasources')")
+
+	assert.NotContains(t, migrator.upSQL(migrations[3]), "USING GIN")
+	assert.Contains(t, withIndexes.upSQL(migrations[3]), "CREATE INDEX IF NOT EXISTS mt_idx_tenants_metadata ON mt_tenants USING GIN (metadata)")
+	assert.Contains(t, migrator.names.sql(migrations[3].Down), "DROP INDEX IF EXISTS platform.mt_idx_tenants_metadata")
+	assert.Contains(t, withIndexes.upSQL(migrations[4]), "CREATE INDEX IF NOT EXISTS mt_idx_tenants_labels ON mt_tenants USING GIN (labels)")
+}
+
+func TestOptions_Validate(t *testing.T) {
+	assert.NoError(t, Options{}.Validate())
+	assert.NoError(t, Options{Schema: "platform", TablePrefix: "mt_", MaxConns: 10, MinConns: 2}.Validate())
+	assert.Error(t, Options{Schema: "public; DROP TABLE tenants"}.Validate())
+	assert.Error(t, Options{TablePrefix: "mt-"}.Validate())
+	assert.Error(t, Options{MaxConns: 2, MinConns: 5}.Validate())
+	assert.Error(t, Options{MaxConns: -1}.Validate())
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Default connection pool sizes for the registry
+const (
+	DefaultMaxConns int32 = 30
+	DefaultMinConns int32 = 5
+)
+
+// Options holds optional settings for the PostgreSQL tenant repository
+type Options struct {
+	// Schema places the registry tables in the given schema instead of the search path
+	Schema string `json:"schema,omitempty"`
+	// TablePrefix is prepended to every registry table and index name
+	TablePrefix string `json:"table_prefix,omitempty"`
+	// SkipMigrations disables applying schema migrations on startup, for deployments
+	// that run them explicitly with MigrateUp. The repository still refuses to start on a
+	// schema older than LatestSchemaVersion.
+	SkipMigrations bool `json:"skip_migrations,omitempty"`
+	// SkipIndexes leaves out the secondary indexes when migrating, for deployments that
+	// manage indexes themselves
+	SkipIndexes bool `json:"skip_indexes,omitempty"`
+	// MaxConns and MinConns size the registry connection pool (defaults 30 and 5)
+	MaxConns int32 `json:"max_conns,omitempty"`
+	MinConns int32 `json:"min_conns,omitempty"`
+	// Logger receives the migration and change listener logs; defaults to slog.Default()
+	Logger *slog.Logger `json:"-"`
+}
+
+// identifierPattern restricts schema names and table prefixes to plain SQL identifiers,
+// so they can be interpolated into statements without quoting
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks that the options can be used to build SQL statements
+func (o Options) Validate() error {
+	if o.Schema != "" && !identifierPattern.MatchString(o.Schema) {
+		return core.ErrConfigInvalid("Schema", "schema must be a plain SQL identifier: "+o.Schema)
+	}
+
+	if o.TablePrefix != "" && !identifierPattern.MatchString(o.TablePrefix) {
+		return core.ErrConfigInvalid("TablePrefix", "table prefix must be a plain SQL identifier: "+o.TablePrefix)
+	}
+
+	if o.MaxConns < 0 || o.MinConns < 0 {
+		return core.ErrConfigInvalid("MaxConns", "pool sizes cannot be negative")
+	}
+
+	if o.MaxConns > 0 && o.MinConns > o.MaxConns {
+		return core.ErrConfigInvalid("MinConns", "min conns cannot exceed max conns")
+	}
+
+	return nil
+}
+
+// registryTables lists the logical table and function names that may appear as {name}
+// placeholders in SQL
+var registryTables = []string{"tenants", "datasources", "tenant_templates", "schema_migrations", "notify_tenant_change"}
+
+// changeChannel is the NOTIFY channel used for tenant changes, before schema and prefix
+const changeChannel = "tenant_changes"
+
+// naming rewrites {table} placeholders in SQL into the configured qualified table names
+type naming struct {
+	replacer *strings.Replacer
+	// channel is the NOTIFY channel for tenant changes, also available as {channel}
+	channel string
+}
+
+// defaultNaming uses the unqualified table names of the original schema
+var defaultNaming = newNaming("", "")
+
+// newNaming builds the placeholder replacer for a schema and table prefix
+func newNaming(schema, prefix string) *naming {
+	qualifier := ""
+	if schema != "" {
+		qualifier = schema + "."
+	}
+
+	// Channels are database wide, so the schema becomes part of the name
+	channel := prefix + changeChannel
+	if schema != "" {
+		channel = schema + "_" + channel
+	}
+
+	// {qualifier} schema-qualifies names that are not tables, such as indexes
+	pairs := []string{"{prefix}", prefix, "{qualifier}", qualifier, "{channel}", channel}
+	for _, table := range registryTables {
+		pairs = append(pairs, "{"+table+"}", qualifier+prefix+table)
+	}
+
+	return &naming{replacer: strings.NewReplacer(pairs...), channel: channel}
+}
+
+// sql replaces the table placeholders in query
+func (n *naming) sql(query string) string {
+	return n.replacer.Replace(query)
+}
+
+// sql renders a query for the tables this repository was configured with
+func (r *TenantRepository) sql(query string) string {
+	if r.names == nil {
+		return defaultNaming.sql(query)
+	}
+	return r.names.sql(query)
+}
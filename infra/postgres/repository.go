@@ -5,19 +5,34 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/victorximenis/multitenant/core"
 )
 
+// DefaultListPageSize is the number of tenants fetched per page by ListEach
+const DefaultListPageSize = 500
+
 // TenantRepository implements the core.TenantRepository interface using PostgreSQL
 type TenantRepository struct {
-	pool PoolInterface
+	pool  PoolInterface
+	names *naming
 }
 
 // NewTenantRepository creates a new PostgreSQL tenant repository
 func NewTenantRepository(ctx context.Context, dsn string) (*TenantRepository, error) {
+	return NewTenantRepositoryWithOptions(ctx, dsn, Options{})
+}
+
+// NewTenantRepositoryWithOptions creates a new PostgreSQL tenant repository with custom options
+func NewTenantRepositoryWithOptions(ctx context.Context, dsn string, opts Options) (*TenantRepository, error) {
+	migrator, err := NewMigrator(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse and configure the connection pool
 	config, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
@@ -25,8 +40,17 @@ func NewTenantRepository(ctx context.Context, dsn string) (*TenantRepository, er
 	}
 
 	// Configure pool settings for optimal performance
-	config.MaxConns = 30
-	config.MinConns = 5
+	config.MaxConns = DefaultMaxConns
+	if opts.MaxConns > 0 {
+		config.MaxConns = opts.MaxConns
+	}
+	config.MinConns = DefaultMinConns
+	if opts.MinConns > 0 {
+		config.MinConns = opts.MinConns
+	}
+	if config.MinConns > config.MaxConns {
+		config.MinConns = config.MaxConns
+	}
 	config.MaxConnLifetime = time.Hour
 	config.MaxConnIdleTime = time.Minute * 30
 	config.HealthCheckPeriod = time.Minute
@@ -34,29 +58,40 @@ func NewTenantRepository(ctx context.Context, dsn string) (*TenantRepository, er
 	// Create the connection pool
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
-		return nil, err
+		return nil, connectionError(err)
 	}
 
 	// Verify connection
 	if err := pool.Ping(ctx); err != nil {
 		pool.Close()
-		return nil, err
+		return nil, connectionError(err)
+	}
+
+	repo := &TenantRepository{pool: pool, names: migrator.names}
+
+	if opts.SkipMigrations {
+		// Every query reads columns added by later migrations, so fail fast on an old schema
+		if err := migrator.checkVersion(ctx, pool); err != nil {
+			pool.Close()
+			return nil, err
+		}
+		return repo, nil
 	}
 
 	// Setup schema
 	conn, err := pool.Acquire(ctx)
 	if err != nil {
 		pool.Close()
-		return nil, err
+		return nil, connectionError(err)
 	}
 	defer conn.Release()
 
-	if err := SetupSchema(ctx, conn.Conn()); err != nil {
+	if err := migrator.Up(ctx, conn.Conn()); err != nil {
 		pool.Close()
 		return nil, err
 	}
 
-	return &TenantRepository{pool: pool}, nil
+	return repo, nil
 }
 
 // Close closes the connection pool
@@ -66,51 +101,49 @@ func (r *TenantRepository) Close() {
 
 // GetByName retrieves a tenant by name with all its datasources
 func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Tenant, error) {
+	return r.getTenant(ctx, "name", name)
+}
+
+// GetByID retrieves a tenant by ID with all its datasources
+func (r *TenantRepository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	// A malformed ID cannot match the UUID column and would fail the query instead
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, core.ErrTenantNotFound(id)
+	}
+	return r.getTenant(ctx, "id", id)
+}
+
+// getTenant retrieves the tenant whose column equals value; column is a fixed identifier, never user input
+func (r *TenantRepository) getTenant(ctx context.Context, column, value string) (*core.Tenant, error) {
 	tenant := &core.Tenant{}
 
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return nil, mapPostgreSQLError(err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Get tenant
-	row := tx.QueryRow(ctx, `
-		SELECT id, name, is_active, metadata, created_at, updated_at 
-		FROM tenants WHERE name = $1
-	`, name)
+	row := tx.QueryRow(ctx, r.sql(`
+		SELECT id, name, is_active, metadata, labels, created_at, updated_at 
+		FROM {tenants} WHERE `+column+` = $1
+	`), value)
 
-	var metadataBytes []byte
-	err = row.Scan(
-		&tenant.ID,
-		&tenant.Name,
-		&tenant.IsActive,
-		&metadataBytes,
-		&tenant.CreatedAt,
-		&tenant.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanTenant(row, tenant); err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, core.TenantNotFoundError{Name: name}
-		}
-		return nil, err
-	}
-
-	// Parse metadata
-	if len(metadataBytes) > 0 {
-		if err := json.Unmarshal(metadataBytes, &tenant.Metadata); err != nil {
-			return nil, err
+			return nil, core.ErrTenantNotFound(value)
 		}
+		return nil, mapPostgreSQLError(err)
 	}
 
 	// Get datasources
-	rows, err := tx.Query(ctx, `
+	rows, err := tx.Query(ctx, r.sql(`
 		SELECT id, dsn, role, pool_size, metadata, created_at, updated_at
-		FROM datasources WHERE tenant_id = $1
+		FROM {datasources} WHERE tenant_id = $1
 		ORDER BY created_at
-	`, tenant.ID)
+	`), tenant.ID)
 	if err != nil {
-		return nil, err
+		return nil, mapPostgreSQLError(err)
 	}
 	defer rows.Close()
 
@@ -127,12 +160,12 @@ func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Te
 			&ds.CreatedAt,
 			&ds.UpdatedAt,
 		); err != nil {
-			return nil, err
+			return nil, mapPostgreSQLError(err)
 		}
 
 		if len(metadataBytes) > 0 {
 			if err := json.Unmarshal(metadataBytes, &ds.Metadata); err != nil {
-				return nil, err
+				return nil, mapPostgreSQLError(err)
 			}
 		}
 
@@ -140,80 +173,93 @@ func (r *TenantRepository) GetByName(ctx context.Context, name string) (*core.Te
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, mapPostgreSQLError(err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return nil, err
+		return nil, mapPostgreSQLError(err)
 	}
 
 	return tenant, nil
 }
 
-// List retrieves all tenants with optional filtering
+// List retrieves all tenants with their datasources in two round trips
 func (r *TenantRepository) List(ctx context.Context) ([]core.Tenant, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT id, name, is_active, metadata, created_at, updated_at 
-		FROM tenants 
+	rows, err := r.pool.Query(ctx, r.sql(`
+		SELECT id, name, is_active, metadata, labels, created_at, updated_at 
+		FROM {tenants} 
 		ORDER BY name
-	`)
+	`))
 	if err != nil {
-		return nil, err
+		return nil, mapPostgreSQLError(err)
 	}
-	defer rows.Close()
 
-	var tenants []core.Tenant
-	for rows.Next() {
-		tenant := core.Tenant{}
-		var metadataBytes []byte
+	tenants, err := scanTenants(rows)
+	if err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
 
-		if err := rows.Scan(
-			&tenant.ID,
-			&tenant.Name,
-			&tenant.IsActive,
-			&metadataBytes,
-			&tenant.CreatedAt,
-			&tenant.UpdatedAt,
-		); err != nil {
-			return nil, err
+	if err := r.loadDatasources(ctx, tenants); err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+
+	return tenants, nil
+}
+
+// ListEach streams all tenants ordered by name to fn, loading them in pages of
+// DefaultListPageSize so the whole registry is never held in memory. Returning an
+// error from fn stops the iteration and returns that error.
+func (r *TenantRepository) ListEach(ctx context.Context, fn func(*core.Tenant) error) error {
+	return r.listEach(ctx, DefaultListPageSize, fn)
+}
+
+// listEach walks the tenants table with keyset pagination on the unique name column
+func (r *TenantRepository) listEach(ctx context.Context, pageSize int, fn func(*core.Tenant) error) error {
+	after := ""
+	for {
+		rows, err := r.pool.Query(ctx, r.sql(`
+			SELECT id, name, is_active, metadata, labels, created_at, updated_at
+			FROM {tenants}
+			WHERE name > $1
+			ORDER BY name
+			LIMIT $2
+		`), after, pageSize)
+		if err != nil {
+			return mapPostgreSQLError(err)
 		}
 
-		// Parse metadata
-		if len(metadataBytes) > 0 {
-			if err := json.Unmarshal(metadataBytes, &tenant.Metadata); err != nil {
-				return nil, err
-			}
+		page, err := scanTenants(rows)
+		if err != nil {
+			return mapPostgreSQLError(err)
 		}
 
-		tenants = append(tenants, tenant)
-	}
+		if err := r.loadDatasources(ctx, page); err != nil {
+			return mapPostgreSQLError(err)
+		}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return err
+			}
+		}
 
-	// Load datasources for each tenant
-	for i := range tenants {
-		datasources, err := r.getDatasourcesByTenantID(ctx, tenants[i].ID)
-		if err != nil {
-			return nil, err
+		if len(page) < pageSize {
+			return nil
 		}
-		tenants[i].Datasources = datasources
+		after = page[len(page)-1].Name
 	}
-
-	return tenants, nil
 }
 
 // Create creates a new tenant with its datasources
 func (r *TenantRepository) Create(ctx context.Context, tenant *core.Tenant) error {
 	// Validate tenant before creating
 	if err := tenant.Validate(); err != nil {
-		return err
+		return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
 	}
 
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return err
+		return mapPostgreSQLError(err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -222,20 +268,25 @@ func (r *TenantRepository) Create(ctx context.Context, tenant *core.Tenant) erro
 	if tenant.Metadata != nil {
 		metadataBytes, err = json.Marshal(tenant.Metadata)
 		if err != nil {
-			return err
+			return mapPostgreSQLError(err)
 		}
 	}
 
+	labelsBytes, err := marshalLabels(tenant.Labels)
+	if err != nil {
+		return mapPostgreSQLError(err)
+	}
+
 	// Set timestamps
 	now := time.Now()
 	tenant.CreatedAt = now
 	tenant.UpdatedAt = now
 
 	// Insert tenant
-	_, err = tx.Exec(ctx, `
-		INSERT INTO tenants (id, name, is_active, metadata, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, tenant.ID, tenant.Name, tenant.IsActive, metadataBytes, tenant.CreatedAt, tenant.UpdatedAt)
+	_, err = tx.Exec(ctx, r.sql(`
+		INSERT INTO {tenants} (id, name, is_active, metadata, labels, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`), tenant.ID, tenant.Name, tenant.IsActive, metadataBytes, labelsBytes, tenant.CreatedAt, tenant.UpdatedAt)
 	if err != nil {
 		return mapPostgreSQLError(err)
 	}
@@ -248,50 +299,50 @@ func (r *TenantRepository) Create(ctx context.Context, tenant *core.Tenant) erro
 		ds.UpdatedAt = now
 
 		if err := ds.Validate(); err != nil {
-			return err
+			return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
 		}
 
 		var dsMetadataBytes []byte
 		if ds.Metadata != nil {
 			dsMetadataBytes, err = json.Marshal(ds.Metadata)
 			if err != nil {
-				return err
+				return mapPostgreSQLError(err)
 			}
 		}
 
-		_, err = tx.Exec(ctx, `
-			INSERT INTO datasources (id, tenant_id, dsn, role, pool_size, metadata, created_at, updated_at)
+		_, err = tx.Exec(ctx, r.sql(`
+			INSERT INTO {datasources} (id, tenant_id, dsn, role, pool_size, metadata, created_at, updated_at)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, ds.ID, ds.TenantID, ds.DSN, ds.Role, ds.PoolSize, dsMetadataBytes, ds.CreatedAt, ds.UpdatedAt)
+		`), ds.ID, ds.TenantID, ds.DSN, ds.Role, ds.PoolSize, dsMetadataBytes, ds.CreatedAt, ds.UpdatedAt)
 		if err != nil {
 			return mapPostgreSQLError(err)
 		}
 	}
 
-	return tx.Commit(ctx)
+	return mapPostgreSQLError(tx.Commit(ctx))
 }
 
 // Update updates an existing tenant and its datasources
 func (r *TenantRepository) Update(ctx context.Context, tenant *core.Tenant) error {
 	// Validate tenant before updating
 	if err := tenant.Validate(); err != nil {
-		return err
+		return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
 	}
 
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return err
+		return mapPostgreSQLError(err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Check if tenant exists
 	var exists bool
-	err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM tenants WHERE id = $1)", tenant.ID).Scan(&exists)
+	err = tx.QueryRow(ctx, r.sql("SELECT EXISTS(SELECT 1 FROM {tenants} WHERE id = $1)"), tenant.ID).Scan(&exists)
 	if err != nil {
-		return err
+		return mapPostgreSQLError(err)
 	}
 	if !exists {
-		return core.TenantNotFoundError{Name: tenant.Name}
+		return core.ErrTenantNotFound(tenant.Name)
 	}
 
 	// Serialize metadata
@@ -299,25 +350,30 @@ func (r *TenantRepository) Update(ctx context.Context, tenant *core.Tenant) erro
 	if tenant.Metadata != nil {
 		metadataBytes, err = json.Marshal(tenant.Metadata)
 		if err != nil {
-			return err
+			return mapPostgreSQLError(err)
 		}
 	}
 
+	labelsBytes, err := marshalLabels(tenant.Labels)
+	if err != nil {
+		return mapPostgreSQLError(err)
+	}
+
 	// Update tenant
 	tenant.UpdatedAt = time.Now()
-	_, err = tx.Exec(ctx, `
-		UPDATE tenants 
-		SET name = $2, is_active = $3, metadata = $4, updated_at = $5
+	_, err = tx.Exec(ctx, r.sql(`
+		UPDATE {tenants} 
+		SET name = $2, is_active = $3, metadata = $4, labels = $5, updated_at = $6
 		WHERE id = $1
-	`, tenant.ID, tenant.Name, tenant.IsActive, metadataBytes, tenant.UpdatedAt)
+	`), tenant.ID, tenant.Name, tenant.IsActive, metadataBytes, labelsBytes, tenant.UpdatedAt)
 	if err != nil {
 		return mapPostgreSQLError(err)
 	}
 
 	// Delete existing datasources
-	_, err = tx.Exec(ctx, "DELETE FROM datasources WHERE tenant_id = $1", tenant.ID)
+	_, err = tx.Exec(ctx, r.sql("DELETE FROM {datasources} WHERE tenant_id = $1"), tenant.ID)
 	if err != nil {
-		return err
+		return mapPostgreSQLError(err)
 	}
 
 	// Insert new datasources
@@ -330,78 +386,138 @@ func (r *TenantRepository) Update(ctx context.Context, tenant *core.Tenant) erro
 		}
 
 		if err := ds.Validate(); err != nil {
-			return err
+			return core.ErrTenantInvalid(tenant.Name, err.Error()).WithCause(err)
 		}
 
 		var dsMetadataBytes []byte
 		if ds.Metadata != nil {
 			dsMetadataBytes, err = json.Marshal(ds.Metadata)
 			if err != nil {
-				return err
+				return mapPostgreSQLError(err)
 			}
 		}
 
-		_, err = tx.Exec(ctx, `
-			INSERT INTO datasources (id, tenant_id, dsn, role, pool_size, metadata, created_at, updated_at)
+		_, err = tx.Exec(ctx, r.sql(`
+			INSERT INTO {datasources} (id, tenant_id, dsn, role, pool_size, metadata, created_at, updated_at)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, ds.ID, ds.TenantID, ds.DSN, ds.Role, ds.PoolSize, dsMetadataBytes, ds.CreatedAt, ds.UpdatedAt)
+		`), ds.ID, ds.TenantID, ds.DSN, ds.Role, ds.PoolSize, dsMetadataBytes, ds.CreatedAt, ds.UpdatedAt)
 		if err != nil {
 			return mapPostgreSQLError(err)
 		}
 	}
 
-	return tx.Commit(ctx)
+	return mapPostgreSQLError(tx.Commit(ctx))
 }
 
-// Delete removes a tenant and all its datasources
-func (r *TenantRepository) Delete(ctx context.Context, name string) error {
-	tx, err := r.pool.Begin(ctx)
-	if err != nil {
-		return err
+// Delete removes a tenant and all its datasources by tenant ID
+func (r *TenantRepository) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return core.ErrTenantNotFound(id)
 	}
-	defer tx.Rollback(ctx)
 
-	// Check if tenant exists
-	var tenantID string
-	err = tx.QueryRow(ctx, "SELECT id FROM tenants WHERE name = $1", name).Scan(&tenantID)
+	// Datasources are deleted by CASCADE
+	result, err := r.pool.Exec(ctx, r.sql("DELETE FROM {tenants} WHERE id = $1"), id)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return core.TenantNotFoundError{Name: name}
+		return mapPostgreSQLError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return core.ErrTenantNotFound(id)
+	}
+
+	return nil
+}
+
+// scanTenants reads tenant rows without their datasources and closes rows
+func scanTenants(rows pgx.Rows) ([]core.Tenant, error) {
+	defer rows.Close()
+
+	var tenants []core.Tenant
+	for rows.Next() {
+		tenant := core.Tenant{}
+		if err := scanTenant(rows, &tenant); err != nil {
+			return nil, err
 		}
-		return err
+		tenants = append(tenants, tenant)
 	}
 
-	// Delete tenant (datasources will be deleted by CASCADE)
-	result, err := tx.Exec(ctx, "DELETE FROM tenants WHERE name = $1", name)
-	if err != nil {
+	return tenants, rows.Err()
+}
+
+// scanTenant reads a tenant row selected with the columns id, name, is_active,
+// metadata, labels, created_at and updated_at
+func scanTenant(row pgx.Row, tenant *core.Tenant) error {
+	var metadataBytes, labelsBytes []byte
+
+	if err := row.Scan(
+		&tenant.ID,
+		&tenant.Name,
+		&tenant.IsActive,
+		&metadataBytes,
+		&labelsBytes,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	); err != nil {
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return core.TenantNotFoundError{Name: name}
+	// Parse metadata
+	if len(metadataBytes) > 0 {
+		if err := json.Unmarshal(metadataBytes, &tenant.Metadata); err != nil {
+			return err
+		}
 	}
 
-	return tx.Commit(ctx)
+	// Parse labels; empty label sets are left nil
+	if len(labelsBytes) > 0 {
+		if err := json.Unmarshal(labelsBytes, &tenant.Labels); err != nil {
+			return err
+		}
+		if len(tenant.Labels) == 0 {
+			tenant.Labels = nil
+		}
+	}
+
+	return nil
 }
 
-// getDatasourcesByTenantID is a helper method to get datasources for a tenant
-func (r *TenantRepository) getDatasourcesByTenantID(ctx context.Context, tenantID string) ([]core.Datasource, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT id, dsn, role, pool_size, metadata, created_at, updated_at
-		FROM datasources WHERE tenant_id = $1
-		ORDER BY created_at
-	`, tenantID)
+// marshalLabels serializes labels for the labels column, which is never NULL
+func marshalLabels(labels map[string]string) ([]byte, error) {
+	if labels == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(labels)
+}
+
+// loadDatasources fetches the datasources of all given tenants with a single query
+func (r *TenantRepository) loadDatasources(ctx context.Context, tenants []core.Tenant) error {
+	if len(tenants) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(tenants))
+	byID := make(map[string]*core.Tenant, len(tenants))
+	for i := range tenants {
+		ids[i] = tenants[i].ID
+		byID[tenants[i].ID] = &tenants[i]
+	}
+
+	rows, err := r.pool.Query(ctx, r.sql(`
+		SELECT tenant_id, id, dsn, role, pool_size, metadata, created_at, updated_at
+		FROM {datasources} WHERE tenant_id = ANY($1::text[]::uuid[])
+		ORDER BY tenant_id, created_at
+	`), ids)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var datasources []core.Datasource
 	for rows.Next() {
-		ds := core.Datasource{TenantID: tenantID}
+		ds := core.Datasource{}
 		var metadataBytes []byte
 
 		if err := rows.Scan(
+			&ds.TenantID,
 			&ds.ID,
 			&ds.DSN,
 			&ds.Role,
@@ -410,17 +526,19 @@ func (r *TenantRepository) getDatasourcesByTenantID(ctx context.Context, tenantI
 			&ds.CreatedAt,
 			&ds.UpdatedAt,
 		); err != nil {
-			return nil, err
+			return err
 		}
 
 		if len(metadataBytes) > 0 {
 			if err := json.Unmarshal(metadataBytes, &ds.Metadata); err != nil {
-				return nil, err
+				return err
 			}
 		}
 
-		datasources = append(datasources, ds)
+		if tenant, ok := byID[ds.TenantID]; ok {
+			tenant.Datasources = append(tenant.Datasources, ds)
+		}
 	}
 
-	return datasources, rows.Err()
+	return rows.Err()
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 
 	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/conformance"
 )
 
 // setupTestDB creates a PostgreSQL container and returns a repository instance
@@ -111,7 +112,7 @@ func TestTenantRepository_CreateDuplicate(t *testing.T) {
 	tenant2 := core.NewTenant("duplicate-tenant")
 	err = repo.Create(ctx, tenant2)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "tenant name already exists")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantExists))
 }
 
 func TestTenantRepository_GetByName(t *testing.T) {
@@ -123,7 +124,7 @@ func TestTenantRepository_GetByName(t *testing.T) {
 	// Test getting non-existent tenant
 	_, err := repo.GetByName(ctx, "non-existent")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 
 	// Create and retrieve tenant
 	tenant := core.NewTenant("get-test-tenant")
@@ -220,7 +221,7 @@ func TestTenantRepository_UpdateNonExistent(t *testing.T) {
 	tenant := core.NewTenant("non-existent")
 	err := repo.Update(ctx, tenant)
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_Delete(t *testing.T) {
@@ -242,13 +243,13 @@ func TestTenantRepository_Delete(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Delete tenant
-	err = repo.Delete(ctx, "delete-tenant")
+	err = repo.Delete(ctx, tenant.ID)
 	assert.NoError(t, err)
 
 	// Verify tenant is deleted
 	_, err = repo.GetByName(ctx, "delete-tenant")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_DeleteNonExistent(t *testing.T) {
@@ -260,7 +261,7 @@ func TestTenantRepository_DeleteNonExistent(t *testing.T) {
 	// Try to delete non-existent tenant
 	err := repo.Delete(ctx, "non-existent")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_TransactionRollback(t *testing.T) {
@@ -290,7 +291,7 @@ func TestTenantRepository_TransactionRollback(t *testing.T) {
 	// Verify tenant was not created (transaction rolled back)
 	_, err = repo.GetByName(ctx, "rollback-tenant")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantRepository_ConcurrentAccess(t *testing.T) {
@@ -366,3 +367,12 @@ func TestTenantRepository_SchemaSetup(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, tenant.ID, retrieved.ID)
 }
+
+func TestTenantRepository_Conformance(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	conformance.RunRepositoryTests(t, func(t *testing.T) core.TenantRepository {
+		return repo
+	})
+}
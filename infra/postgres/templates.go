@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// Compile-time check to ensure TenantRepository implements core.TemplateRepository interface
+var _ core.TemplateRepository = (*TenantRepository)(nil)
+
+// GetTemplate retrieves a tenant template by name
+func (r *TenantRepository) GetTemplate(ctx context.Context, name string) (*core.TenantTemplate, error) {
+	var definition []byte
+	tmpl := &core.TenantTemplate{}
+
+	err := r.pool.QueryRow(ctx, r.sql(`
+		SELECT id, name, definition, created_at, updated_at
+		FROM {tenant_templates} WHERE name = $1
+	`), name).Scan(&tmpl.ID, &tmpl.Name, &definition, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, core.ErrTemplateNotFound(name)
+		}
+		return nil, mapPostgreSQLError(err)
+	}
+
+	if err := decodeTemplate(definition, tmpl); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// ListTemplates retrieves all tenant templates ordered by name
+func (r *TenantRepository) ListTemplates(ctx context.Context) ([]core.TenantTemplate, error) {
+	rows, err := r.pool.Query(ctx, r.sql(`
+		SELECT id, name, definition, created_at, updated_at
+		FROM {tenant_templates}
+		ORDER BY name
+	`))
+	if err != nil {
+		return nil, mapPostgreSQLError(err)
+	}
+	defer rows.Close()
+
+	var templates []core.TenantTemplate
+	for rows.Next() {
+		var definition []byte
+		tmpl := core.TenantTemplate{}
+
+		if err := rows.Scan(&tmpl.ID, &tmpl.Name, &definition, &tmpl.CreatedAt, &tmpl.UpdatedAt); err != nil {
+			return nil, mapPostgreSQLError(err)
+		}
+
+		if err := decodeTemplate(definition, &tmpl); err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, tmpl)
+	}
+
+	return templates, rows.Err()
+}
+
+// SaveTemplate creates or replaces a tenant template identified by its name
+func (r *TenantRepository) SaveTemplate(ctx context.Context, tmpl *core.TenantTemplate) error {
+	if err := tmpl.Validate(); err != nil {
+		return core.ErrValidationFailed("template", err.Error()).WithCause(err)
+	}
+
+	definition, err := json.Marshal(tmpl)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if tmpl.CreatedAt.IsZero() {
+		tmpl.CreatedAt = now
+	}
+	tmpl.UpdatedAt = now
+
+	// Keep the identity of an existing template with the same name
+	err = r.pool.QueryRow(ctx, r.sql(`
+		INSERT INTO {tenant_templates} (id, name, definition, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE
+		SET definition = EXCLUDED.definition, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`), tmpl.ID, tmpl.Name, definition, tmpl.CreatedAt, tmpl.UpdatedAt).Scan(&tmpl.ID, &tmpl.CreatedAt)
+	if err != nil {
+		return mapPostgreSQLError(err)
+	}
+
+	return nil
+}
+
+// DeleteTemplate removes a tenant template by name
+func (r *TenantRepository) DeleteTemplate(ctx context.Context, name string) error {
+	result, err := r.pool.Exec(ctx, r.sql("DELETE FROM {tenant_templates} WHERE name = $1"), name)
+	if err != nil {
+		return mapPostgreSQLError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return core.ErrTemplateNotFound(name)
+	}
+
+	return nil
+}
+
+// decodeTemplate fills the template body from its JSONB definition, keeping the row columns authoritative
+func decodeTemplate(definition []byte, tmpl *core.TenantTemplate) error {
+	id, name, createdAt, updatedAt := tmpl.ID, tmpl.Name, tmpl.CreatedAt, tmpl.UpdatedAt
+
+	if err := json.Unmarshal(definition, tmpl); err != nil {
+		return err
+	}
+
+	tmpl.ID, tmpl.Name, tmpl.CreatedAt, tmpl.UpdatedAt = id, name, createdAt, updatedAt
+	return nil
+}
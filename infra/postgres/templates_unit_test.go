@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func TestTenantRepository_GetTemplate_Success(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &TenantRepository{pool: mock}
+
+	tmpl := core.NewTenantTemplate("standard")
+	tmpl.Plan = "pro"
+	tmpl.Datasources = append(tmpl.Datasources, core.DatasourceTemplate{
+		DSN: "postgres://app:pass@db:5432/{{.TenantName}}", Role: "rw", PoolSize: 5,
+	})
+	definition, _ := json.Marshal(tmpl)
+	now := time.Now()
+
+	rows := mock.NewRows([]string{"id", "name", "definition", "created_at", "updated_at"}).
+		AddRow(tmpl.ID, tmpl.Name, definition, now, now)
+	mock.ExpectQuery("SELECT id, name, definition, created_at, updated_at FROM tenant_templates WHERE name = \\$1").
+		WithArgs("standard").
+		WillReturnRows(rows)
+
+	result, err := repo.GetTemplate(context.Background(), "standard")
+	require.NoError(t, err)
+	assert.Equal(t, tmpl.ID, result.ID)
+	assert.Equal(t, "pro", result.Plan)
+	require.Len(t, result.Datasources, 1)
+	assert.Equal(t, "postgres://app:pass@db:5432/{{.TenantName}}", result.Datasources[0].DSN)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantRepository_GetTemplate_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &TenantRepository{pool: mock}
+
+	mock.ExpectQuery("SELECT id, name, definition, created_at, updated_at FROM tenant_templates WHERE name = \\$1").
+		WithArgs("missing").
+		WillReturnError(pgx.ErrNoRows)
+
+	result, err := repo.GetTemplate(context.Background(), "missing")
+	assert.Nil(t, result)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTemplateNotFound))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantRepository_SaveTemplate_KeepsExistingIdentity(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &TenantRepository{pool: mock}
+
+	tmpl := core.NewTenantTemplate("standard")
+	existingID := "123e4567-e89b-12d3-a456-426614174000"
+	createdAt := time.Now().Add(-time.Hour)
+
+	mock.ExpectQuery("INSERT INTO tenant_templates").
+		WithArgs(tmpl.ID, tmpl.Name, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(existingID, createdAt))
+
+	require.NoError(t, repo.SaveTemplate(context.Background(), tmpl))
+	assert.Equal(t, existingID, tmpl.ID)
+	assert.Equal(t, createdAt, tmpl.CreatedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantRepository_DeleteTemplate_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &TenantRepository{pool: mock}
+
+	mock.ExpectExec("DELETE FROM tenant_templates WHERE name = \\$1").
+		WithArgs("missing").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	err = repo.DeleteTemplate(context.Background(), "missing")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTemplateNotFound))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantRepository_GetTemplate_UsesConfiguredTables(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &TenantRepository{pool: mock, names: newNaming("platform", "mt_")}
+
+	mock.ExpectQuery("FROM platform.mt_tenant_templates WHERE name = \\$1").
+		WithArgs("missing").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = repo.GetTemplate(context.Background(), "missing")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTemplateNotFound))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
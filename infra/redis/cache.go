@@ -2,8 +2,9 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,109 +13,304 @@ import (
 )
 
 const (
-	DEFAULT_TTL = 5 * time.Minute
-	KEY_PREFIX  = "multitenant:tenants:"
+	DEFAULT_TTL   = 5 * time.Minute
+	KEY_PREFIX    = "multitenant:tenants:"
+	ID_KEY_PREFIX = "multitenant:tenant-ids:"
+	// NOT_FOUND_MARKER is stored under a tenant name key to remember that the name
+	// does not exist. It is not valid JSON, so it can never collide with a tenant.
+	NOT_FOUND_MARKER = "!not-found"
 )
 
 type TenantCache struct {
-	client *redis.Client
-	ttl    time.Duration
+	client    redis.UniversalClient
+	ttl       time.Duration
+	staleTTL  time.Duration
+	codec     Codec
+	compress  bool
+	keyPrefix string
+	logger    *slog.Logger
 }
 
 type Config struct {
 	RedisURL string
-	TTL      time.Duration
+	// Client selects Redis Cluster or Sentinel and sets credentials and TLS
+	Client ClientOptions
+	TTL    time.Duration
+	// StaleTTL keeps tenants this long after they expire, for GetEntry to serve stale
+	StaleTTL time.Duration
+	// Codec serializes stored tenants (default JSONCodec). Entries written with any
+	// built-in codec or with this one are readable.
+	Codec Codec
+	// Compress gzips stored tenants of at least COMPRESS_MIN_SIZE bytes
+	Compress bool
+	// KeyPrefix namespaces the cache keys, so environments or applications can share a
+	// Redis; "prod" stores tenants under "prod:multitenant:tenants:<name>"
+	KeyPrefix string
+	// Logger receives the cache logs; defaults to slog.Default()
+	Logger *slog.Logger
 }
 
 func NewTenantCache(ctx context.Context, config Config) (*TenantCache, error) {
-	opts, err := redis.ParseURL(config.RedisURL)
+	client, err := connect(ctx, config.RedisURL, config.Client)
 	if err != nil {
 		return nil, err
 	}
 
-	client := redis.NewClient(opts)
-
-	// Verify connection
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, err
-	}
-
 	ttl := config.TTL
 	if ttl <= 0 {
 		ttl = DEFAULT_TTL
 	}
 
+	codec := config.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
 	return &TenantCache{
-		client: client,
-		ttl:    ttl,
+		client:    client,
+		ttl:       ttl,
+		staleTTL:  max(config.StaleTTL, 0),
+		codec:     codec,
+		compress:  config.Compress,
+		keyPrefix: namespace(config.KeyPrefix),
+		logger:    core.ComponentLogger(config.Logger, "redis.cache"),
 	}, nil
 }
 
+// namespace turns a key prefix into the start of the keys it namespaces
+func namespace(prefix string) string {
+	if prefix == "" || strings.HasSuffix(prefix, ":") {
+		return prefix
+	}
+	return prefix + ":"
+}
+
 func (c *TenantCache) tenantKey(name string) string {
-	return fmt.Sprintf("%s%s", KEY_PREFIX, name)
+	return c.keyPrefix + KEY_PREFIX + name
+}
+
+// tenantIDKey returns the key of the copy indexed by tenant ID
+func (c *TenantCache) tenantIDKey(id string) string {
+	return c.keyPrefix + ID_KEY_PREFIX + id
+}
+
+// Close closes the Redis connections of the cache
+func (c *TenantCache) Close() error {
+	return c.client.Close()
 }
 
 func (c *TenantCache) Get(ctx context.Context, name string) (*core.Tenant, error) {
-	key := c.tenantKey(name)
+	return c.getFresh(ctx, c.tenantKey(name), name)
+}
 
-	data, err := c.client.Get(ctx, key).Bytes()
+// GetByID returns a cached tenant by ID
+func (c *TenantCache) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return c.getFresh(ctx, c.tenantIDKey(id), id)
+}
+
+// GetEntry returns a cached tenant with its freshness, including stale tenants kept for
+// StaleTTL after they expired
+func (c *TenantCache) GetEntry(ctx context.Context, name string) (*core.CacheEntry, error) {
+	return c.getEntry(ctx, c.tenantKey(name), name)
+}
+
+// getFresh returns the tenant stored at key unless it is only kept as stale
+func (c *TenantCache) getFresh(ctx context.Context, key, ref string) (*core.Tenant, error) {
+	if c.staleTTL == 0 {
+		return c.get(ctx, key, ref)
+	}
+
+	entry, err := c.getEntry(ctx, key, ref)
 	if err != nil {
-		if err == redis.Nil {
-			return nil, core.TenantNotFoundError{Name: name}
-		}
 		return nil, err
 	}
+	if !entry.Fresh(time.Now()) {
+		return nil, core.ErrTenantNotFound(ref)
+	}
+	return entry.Tenant, nil
+}
+
+// getEntry reads the tenant stored at key along with its remaining TTL, of which the
+// last StaleTTL is the stale grace period
+func (c *TenantCache) getEntry(ctx context.Context, key, ref string) (*core.CacheEntry, error) {
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, mapRedisError(err, ref)
+	}
 
-	var tenant core.Tenant
-	if err := json.Unmarshal(data, &tenant); err != nil {
+	tenant, err := c.decode(get.Val(), ref)
+	if err != nil {
 		return nil, err
 	}
 
-	return &tenant, nil
+	entry := &core.CacheEntry{Tenant: tenant}
+	// Keys without an expiry report a negative TTL and are always fresh
+	if remaining := pttl.Val(); remaining > 0 {
+		entry.FreshUntil = time.Now().Add(remaining - c.staleTTL)
+	}
+	return entry, nil
+}
+
+// get reads and decodes the tenant stored at key; ref identifies it in not found errors
+func (c *TenantCache) get(ctx context.Context, key, ref string) (*core.Tenant, error) {
+	data, err := c.client.Get(ctx, key).Result()
+	if err != nil {
+		return nil, mapRedisError(err, ref)
+	}
+
+	return c.decode(data, ref)
+}
+
+// decode parses a stored tenant; ref identifies it in not found errors. Entries written
+// by a newer release or with an unknown codec are reported as misses, so the tenant is
+// reloaded from the registry and rewritten in a format this release reads.
+func (c *TenantCache) decode(data, ref string) (*core.Tenant, error) {
+	if data == NOT_FOUND_MARKER {
+		return nil, core.ErrCachedNotFound(ref)
+	}
+
+	tenant, err := decodeEntry([]byte(data), c.codec)
+	if err != nil {
+		var unreadable errUnreadableEntry
+		if errors.As(err, &unreadable) {
+			c.logger.Debug("ignoring unreadable cache entry", core.LogKeyTenantName, ref, core.LogKeyError, err)
+			return nil, core.ErrTenantNotFound(ref)
+		}
+		return nil, mapRedisError(err, ref)
+	}
+
+	return tenant, nil
 }
 
 func (c *TenantCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
 	if tenant == nil {
-		return fmt.Errorf("tenant cannot be nil")
+		return core.ErrTenantInvalid("", "tenant cannot be nil")
 	}
 
 	key := c.tenantKey(tenant.Name)
 
-	data, err := json.Marshal(tenant)
+	data, err := encodeEntry(c.codec, c.compress, tenant)
 	if err != nil {
-		return err
+		return core.NewError(core.ErrCodeCacheOperation, "failed to encode tenant").WithCause(err)
+	}
+
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	// Keep the tenant through the stale grace period
+	ttl += c.staleTTL
+
+	// Store the tenant under both its name and its ID so either lookup is a single GET
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, ttl)
+		pipe.Set(ctx, c.tenantIDKey(tenant.ID), data, ttl)
+		return nil
+	})
+	return mapRedisError(err, tenant.Name)
+}
+
+// SetMany stores tenants with a single pipeline; it is not atomic, so a failure may leave
+// some of them stored
+func (c *TenantCache) SetMany(ctx context.Context, tenants []*core.Tenant, ttl time.Duration) error {
+	entries := make([][]byte, len(tenants))
+	for i, tenant := range tenants {
+		if tenant == nil {
+			return core.ErrTenantInvalid("", "tenant cannot be nil")
+		}
+		data, err := encodeEntry(c.codec, c.compress, tenant)
+		if err != nil {
+			return core.NewError(core.ErrCodeCacheOperation, "failed to encode tenant").WithCause(err)
+		}
+		entries[i] = data
+	}
+
+	if ttl <= 0 {
+		ttl = c.ttl
 	}
+	ttl += c.staleTTL
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tenant := range tenants {
+			pipe.Set(ctx, c.tenantKey(tenant.Name), entries[i], ttl)
+			pipe.Set(ctx, c.tenantIDKey(tenant.ID), entries[i], ttl)
+		}
+		return nil
+	})
+	return mapRedisError(err, "")
+}
 
+// SetNotFound remembers name as not found for ttl. The marker shares the tenant name
+// key, so storing or deleting a tenant with that name clears it.
+func (c *TenantCache) SetNotFound(ctx context.Context, name string, ttl time.Duration) error {
 	if ttl <= 0 {
 		ttl = c.ttl
 	}
 
-	return c.client.Set(ctx, key, data, ttl).Err()
+	return mapRedisError(c.client.Set(ctx, c.tenantKey(name), NOT_FOUND_MARKER, ttl).Err(), name)
 }
 
 func (c *TenantCache) Delete(ctx context.Context, name string) error {
-	key := c.tenantKey(name)
-	return c.client.Del(ctx, key).Err()
+	keys := []string{c.tenantKey(name)}
+
+	// Drop the ID-indexed copy too, when the cached entry tells us the ID
+	if tenant, err := c.get(ctx, c.tenantKey(name), name); err == nil {
+		keys = append(keys, c.tenantIDKey(tenant.ID))
+	} else if !core.IsErrorCode(err, core.ErrCodeTenantNotFound) {
+		return err
+	}
+
+	return mapRedisError(del(ctx, c.client, keys), name)
 }
 
-// DeleteAll removes all tenant keys from cache
+// DeleteAll removes all tenant keys from cache. On Redis Cluster every master is
+// scanned, since each one only holds the keys of its own slots.
 func (c *TenantCache) DeleteAll(ctx context.Context) error {
-	pattern := fmt.Sprintf("%s*", KEY_PREFIX)
+	var err error
+	if cluster, ok := c.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return c.deleteAll(ctx, shard)
+		})
+	} else {
+		err = c.deleteAll(ctx, c.client)
+	}
+
+	if err == nil {
+		c.logger.InfoContext(ctx, "cleared the tenant cache", "key_prefix", c.keyPrefix)
+	}
+	return err
+}
+
+// deleteAll removes the tenant keys held by a single node
+func (c *TenantCache) deleteAll(ctx context.Context, node redis.Cmdable) error {
+	for _, prefix := range []string{KEY_PREFIX, ID_KEY_PREFIX} {
+		if err := deletePattern(ctx, node, escapePattern(c.keyPrefix+prefix)+"*"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+// deletePattern removes every key of node matching pattern
+func deletePattern(ctx context.Context, node redis.Cmdable, pattern string) error {
 	var cursor uint64
 	var keys []string
 	var err error
 
 	for {
-		keys, cursor, err = c.client.Scan(ctx, cursor, pattern, 100).Result()
+		keys, cursor, err = node.Scan(ctx, cursor, pattern, 100).Result()
 		if err != nil {
-			return err
+			return mapRedisError(err, "")
 		}
 
-		if len(keys) > 0 {
-			if err := c.client.Del(ctx, keys...).Err(); err != nil {
-				return err
-			}
+		if err := del(ctx, node, keys); err != nil {
+			return mapRedisError(err, "")
 		}
 
 		if cursor == 0 {
@@ -124,3 +320,31 @@ func (c *TenantCache) DeleteAll(ctx context.Context) error {
 
 	return nil
 }
+
+// del removes keys with one DEL per key, since a multi-key DEL fails on Redis Cluster
+// when the keys hash to different slots
+func del(ctx context.Context, client redis.Cmdable, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// escapePattern escapes the glob characters of a literal key prefix for SCAN MATCH
+func escapePattern(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		if strings.ContainsRune(`*?[]\\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
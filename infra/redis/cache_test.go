@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -13,6 +14,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 
 	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/conformance"
 )
 
 func setupTestRedis(t *testing.T) (*TenantCache, func()) {
@@ -83,7 +85,7 @@ func TestTenantCache(t *testing.T) {
 	time.Sleep(2 * time.Second)
 	_, err = cache.Get(ctx, "test-tenant")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 
 	// Test cache invalidation
 	err = cache.Set(ctx, tenant, 10*time.Second)
@@ -96,6 +98,15 @@ func TestTenantCache(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestTenantCache_Conformance(t *testing.T) {
+	cache, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	conformance.RunCacheTests(t, func(t *testing.T) core.TenantCache {
+		return cache
+	})
+}
+
 func TestTenantCache_GetNotFound(t *testing.T) {
 	cache, cleanup := setupTestRedis(t)
 	defer cleanup()
@@ -105,7 +116,69 @@ func TestTenantCache_GetNotFound(t *testing.T) {
 	// Test getting non-existent tenant
 	_, err := cache.Get(ctx, "non-existent")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+}
+
+func TestTenantCache_NotFoundEntries(t *testing.T) {
+	cache, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetNotFound(ctx, "ghost", 10*time.Second))
+	_, err := cache.Get(ctx, "ghost")
+	assert.True(t, core.IsCachedNotFound(err))
+
+	// Storing a tenant under the name overwrites the marker
+	tenant := &core.Tenant{ID: "ghost-id", Name: "ghost", IsActive: true}
+	require.NoError(t, cache.Set(ctx, tenant, 10*time.Second))
+	retrieved, err := cache.Get(ctx, "ghost")
+	require.NoError(t, err)
+	assert.Equal(t, tenant.ID, retrieved.ID)
+
+	// Deleting a remembered name drops the marker
+	require.NoError(t, cache.SetNotFound(ctx, "phantom", 10*time.Second))
+	require.NoError(t, cache.Delete(ctx, "phantom"))
+	_, err = cache.Get(ctx, "phantom")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	assert.False(t, core.IsCachedNotFound(err))
+}
+
+func TestTenantCache_CodecsAndKeyPrefix(t *testing.T) {
+	cache, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	redisURL := "redis://" + cache.client.(*goredis.Client).Options().Addr
+
+	staging, err := NewTenantCache(ctx, Config{RedisURL: redisURL, Codec: MsgpackCodec, Compress: true, KeyPrefix: "staging"})
+	require.NoError(t, err)
+	stagingJSON, err := NewTenantCache(ctx, Config{RedisURL: redisURL, KeyPrefix: "staging"})
+	require.NoError(t, err)
+
+	// Prefixed caches share a Redis without seeing each other's tenants
+	tenant := &core.Tenant{ID: "acme-id", Name: "acme", IsActive: true}
+	require.NoError(t, staging.Set(ctx, tenant, 10*time.Second))
+	_, err = cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	// Readers configured with another codec decode the entry
+	retrieved, err := stagingJSON.Get(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, tenant.ID, retrieved.ID)
+
+	// Bare JSON entries written by older releases are still readable
+	require.NoError(t, cache.client.Set(ctx, cache.tenantKey("legacy"), `{"id":"legacy-id","name":"legacy","is_active":true}`, 10*time.Second).Err())
+	retrieved, err = cache.Get(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy-id", retrieved.ID)
+
+	// DeleteAll only clears its own namespace
+	require.NoError(t, staging.DeleteAll(ctx))
+	_, err = stagingJSON.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	_, err = cache.Get(ctx, "legacy")
+	assert.NoError(t, err)
 }
 
 func TestTenantCache_SetNilTenant(t *testing.T) {
@@ -158,11 +231,15 @@ func TestTenantCache_DeleteAll(t *testing.T) {
 	// Verify both tenants are gone
 	_, err = cache.Get(ctx, "test-tenant-1")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 
 	_, err = cache.Get(ctx, "test-tenant-2")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	// The ID-indexed copies are removed as well
+	_, err = cache.GetByID(ctx, "test-id-1")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantCache_CustomTTL(t *testing.T) {
@@ -192,7 +269,7 @@ func TestTenantCache_CustomTTL(t *testing.T) {
 	// Should be expired
 	_, err = cache.Get(ctx, "test-tenant")
 	assert.Error(t, err)
-	assert.IsType(t, core.TenantNotFoundError{}, err)
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 }
 
 func TestTenantCache_ConcurrentAccess(t *testing.T) {
@@ -230,7 +307,7 @@ func TestTenantCache_ConcurrentAccess(t *testing.T) {
 			_, err := cache.Get(ctx, tenantName)
 			// May or may not find the tenant depending on timing, but should not panic
 			if err != nil {
-				assert.IsType(t, core.TenantNotFoundError{}, err)
+				assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
 			}
 			done <- true
 		}(i)
@@ -1,10 +1,17 @@
 package redis
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/victorximenis/multitenant/core"
 )
 
 func TestTenantKey(t *testing.T) {
@@ -81,4 +88,25 @@ func TestConfig_DefaultTTL(t *testing.T) {
 func TestConstants(t *testing.T) {
 	assert.Equal(t, 5*time.Minute, DEFAULT_TTL)
 	assert.Equal(t, "multitenant:tenants:", KEY_PREFIX)
+	assert.Equal(t, "multitenant:tenant-ids:", ID_KEY_PREFIX)
+}
+
+func TestMapRedisError(t *testing.T) {
+	assert.NoError(t, mapRedisError(nil, "acme"))
+
+	notFound := mapRedisError(redis.Nil, "acme")
+	assert.True(t, core.IsErrorCode(notFound, core.ErrCodeTenantNotFound))
+	var legacy core.TenantNotFoundError
+	assert.True(t, errors.As(notFound, &legacy))
+	assert.Equal(t, "acme", legacy.Name)
+
+	timeout := mapRedisError(fmt.Errorf("get: %w", context.DeadlineExceeded), "acme")
+	assert.True(t, core.IsErrorCode(timeout, core.ErrCodeCacheTimeout))
+	assert.True(t, errors.Is(timeout, context.DeadlineExceeded))
+
+	refused := mapRedisError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}, "acme")
+	assert.True(t, core.IsErrorCode(refused, core.ErrCodeCacheConnection))
+
+	assert.True(t, core.IsErrorCode(mapRedisError(redis.ErrClosed, "acme"), core.ErrCodeCacheConnection))
+	assert.True(t, core.IsErrorCode(mapRedisError(assert.AnError, "acme"), core.ErrCodeCacheOperation))
 }
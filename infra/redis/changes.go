@@ -0,0 +1,197 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+const (
+	// CHANGES_CHANNEL is the pub/sub channel carrying tenant changes between instances
+	CHANGES_CHANNEL = "multitenant:tenant-changes"
+
+	DEFAULT_RECONNECT_DELAY     = time.Second
+	DEFAULT_MAX_RECONNECT_DELAY = 30 * time.Second
+	DEFAULT_HEALTH_CHECK        = 30 * time.Second
+)
+
+// ChangeBusConfig holds configuration for the tenant change bus
+type ChangeBusConfig struct {
+	RedisURL string
+	// Client selects Redis Cluster or Sentinel and sets credentials and TLS
+	Client ClientOptions
+	// Channel defaults to CHANGES_CHANNEL under KeyPrefix; every instance sharing a
+	// registry must use the same one
+	Channel string
+	// KeyPrefix namespaces the default channel like the keys of TenantCache
+	KeyPrefix string
+	// ReconnectDelay is the initial wait after a failure, doubled up to MaxReconnectDelay
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// HealthCheck is how long the subscription may stay quiet before it is pinged
+	HealthCheck time.Duration
+	// OnError is called for connection failures and malformed messages, which are also logged
+	OnError func(error)
+	// Logger receives the pub/sub logs; defaults to slog.Default()
+	Logger *slog.Logger
+}
+
+// ChangeBus publishes tenant changes on a Redis channel and delivers the changes
+// published by every instance, so each one can drop its stale caches and pools
+type ChangeBus struct {
+	client redis.UniversalClient
+	config ChangeBusConfig
+	logger *slog.Logger
+}
+
+// NewChangeBus connects the tenant change bus; call Run to receive changes
+func NewChangeBus(ctx context.Context, config ChangeBusConfig) (*ChangeBus, error) {
+	client, err := connect(ctx, config.RedisURL, config.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChangeBus(client, config), nil
+}
+
+// newChangeBus applies the defaults of config to a bus over client
+func newChangeBus(client redis.UniversalClient, config ChangeBusConfig) *ChangeBus {
+	if config.Channel == "" {
+		config.Channel = namespace(config.KeyPrefix) + CHANGES_CHANNEL
+	}
+	if config.ReconnectDelay <= 0 {
+		config.ReconnectDelay = DEFAULT_RECONNECT_DELAY
+	}
+	if config.MaxReconnectDelay < config.ReconnectDelay {
+		config.MaxReconnectDelay = DEFAULT_MAX_RECONNECT_DELAY
+		if config.MaxReconnectDelay < config.ReconnectDelay {
+			config.MaxReconnectDelay = config.ReconnectDelay
+		}
+	}
+	if config.HealthCheck <= 0 {
+		config.HealthCheck = DEFAULT_HEALTH_CHECK
+	}
+
+	return &ChangeBus{client: client, config: config, logger: core.ComponentLogger(config.Logger, "redis.changes")}
+}
+
+// Channel returns the pub/sub channel of the bus
+func (b *ChangeBus) Channel() string {
+	return b.config.Channel
+}
+
+// PublishChange sends change to every subscribed instance, including this one
+func (b *ChangeBus) PublishChange(ctx context.Context, change core.TenantChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return core.NewError(core.ErrCodeCacheOperation, "failed to encode tenant change").WithCause(err)
+	}
+
+	return mapRedisError(b.client.Publish(ctx, b.config.Channel, payload).Err(), change.Name)
+}
+
+// Run subscribes to the channel and calls handler for each change until ctx is done.
+// The subscription is re-established after Redis outages, and handler then receives a
+// core.ChangeResync because changes published meanwhile were lost.
+func (b *ChangeBus) Run(ctx context.Context, handler func(context.Context, core.TenantChange)) error {
+	pubsub := b.client.Subscribe(ctx, b.config.Channel)
+	defer pubsub.Close()
+
+	delay := b.config.ReconnectDelay
+	subscribed := false
+
+	for {
+		message, err := pubsub.ReceiveTimeout(ctx, b.config.HealthCheck)
+		if err != nil && isTimeout(err) && ctx.Err() == nil {
+			// A quiet channel: ping, so a dead connection is noticed and replaced
+			if err = pubsub.Ping(ctx); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			b.reportError(ctx, mapRedisError(err, ""))
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
+			}
+			delay *= 2
+			if delay > b.config.MaxReconnectDelay {
+				delay = b.config.MaxReconnectDelay
+			}
+			continue
+		}
+
+		switch message := message.(type) {
+		case *redis.Subscription:
+			if message.Kind != "subscribe" {
+				continue
+			}
+			if subscribed {
+				b.logger.InfoContext(ctx, "resubscribed to tenant changes, resyncing", "channel", b.config.Channel)
+				handler(ctx, core.TenantChange{Op: core.ChangeResync})
+			}
+			subscribed = true
+			delay = b.config.ReconnectDelay
+		case *redis.Message:
+			b.deliver(ctx, message.Payload, handler)
+		}
+	}
+}
+
+// deliver decodes a published change and passes it to handler
+func (b *ChangeBus) deliver(ctx context.Context, payload string, handler func(context.Context, core.TenantChange)) {
+	var change core.TenantChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil || change.Op == "" {
+		b.reportError(ctx, core.NewError(core.ErrCodeValidationFailed, "malformed tenant change message").
+			WithDetail("payload", payload).
+			WithCause(err))
+		return
+	}
+
+	handler(ctx, change)
+}
+
+// Close closes the Redis connections of the bus
+func (b *ChangeBus) Close() error {
+	return b.client.Close()
+}
+
+// reportError logs err and forwards it to the configured error callback
+func (b *ChangeBus) reportError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	b.logger.WarnContext(ctx, "tenant change subscription failed", "channel", b.config.Channel, core.LogKeyError, err)
+	if b.config.OnError != nil {
+		b.config.OnError(err)
+	}
+}
+
+// isTimeout reports whether err is a read timeout of a quiet subscription
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
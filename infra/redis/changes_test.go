@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func TestChangeBus(t *testing.T) {
+	cache, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	bus := newChangeBus(cache.client, ChangeBusConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan core.TenantChange, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Run(ctx, func(ctx context.Context, change core.TenantChange) {
+			changes <- change
+		})
+	}()
+
+	// Publish until the subscription is established
+	published := core.TenantChange{Op: core.ChangeDelete, ID: "t1", Name: "acme"}
+	require.Eventually(t, func() bool {
+		count, err := cache.client.PubSubNumSub(ctx, bus.Channel()).Result()
+		return err == nil && count[bus.Channel()] == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	require.NoError(t, bus.PublishChange(ctx, published))
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, published, change)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not delivered")
+	}
+
+	// A dropped connection is re-established and reported as a resync
+	require.NoError(t, cache.client.ClientKillByFilter(ctx, "TYPE", "pubsub").Err())
+	select {
+	case change := <-changes:
+		assert.Equal(t, core.ChangeResync, change.Op)
+	case <-time.After(10 * time.Second):
+		t.Fatal("resync was not delivered")
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+func TestNewChangeBus_Defaults(t *testing.T) {
+	bus := newChangeBus(nil, ChangeBusConfig{})
+	assert.Equal(t, CHANGES_CHANNEL, bus.Channel())
+	assert.Equal(t, DEFAULT_RECONNECT_DELAY, bus.config.ReconnectDelay)
+	assert.Equal(t, DEFAULT_MAX_RECONNECT_DELAY, bus.config.MaxReconnectDelay)
+	assert.Equal(t, DEFAULT_HEALTH_CHECK, bus.config.HealthCheck)
+
+	bus = newChangeBus(nil, ChangeBusConfig{Channel: "app:changes", ReconnectDelay: time.Minute})
+	assert.Equal(t, "app:changes", bus.Channel())
+	assert.Equal(t, time.Minute, bus.config.MaxReconnectDelay)
+
+	_, err := NewChangeBus(context.Background(), ChangeBusConfig{RedisURL: "not a url"})
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeConfigInvalid))
+}
+
+func TestChangeBus_Deliver(t *testing.T) {
+	var errs []error
+	bus := newChangeBus(nil, ChangeBusConfig{OnError: func(err error) { errs = append(errs, err) }})
+
+	var changes []core.TenantChange
+	handler := func(ctx context.Context, change core.TenantChange) {
+		changes = append(changes, change)
+	}
+
+	bus.deliver(context.Background(), `{"op":"UPDATE","id":"t1","name":"acme","old_name":"acme-old"}`, handler)
+	bus.deliver(context.Background(), `not json`, handler)
+	bus.deliver(context.Background(), `{"name":"acme"}`, handler)
+
+	require.Len(t, changes, 1)
+	assert.Equal(t, core.TenantChange{Op: core.ChangeUpdate, ID: "t1", Name: "acme", OldName: "acme-old"}, changes[0])
+	require.Len(t, errs, 2)
+	assert.True(t, core.IsErrorCode(errs[0], core.ErrCodeValidationFailed))
+}
@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// ClientOptions selects the Redis deployment and its credentials. Without Addrs the
+// Redis URL of the config is used; with Addrs, MasterName selects Sentinel and more
+// than one address (or Cluster) selects Redis Cluster.
+type ClientOptions struct {
+	// Addrs are the cluster seed nodes, the sentinels, or a single node
+	Addrs []string `json:"addrs,omitempty"`
+	// MasterName is the Sentinel master name
+	MasterName string `json:"master_name,omitempty"`
+	// Cluster forces Redis Cluster with a single seed address, such as a configuration endpoint
+	Cluster bool `json:"cluster,omitempty"`
+	// Username and Password authenticate against the data nodes, overriding the URL ones
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// SentinelUsername and SentinelPassword authenticate against the sentinels
+	SentinelUsername string `json:"sentinel_username,omitempty"`
+	SentinelPassword string `json:"sentinel_password,omitempty"`
+	// DB selects the database; Redis Cluster only has database 0
+	DB int `json:"db,omitempty"`
+	// TLS enables TLS with the default settings; TLSConfig customizes it
+	TLS       bool        `json:"tls,omitempty"`
+	TLSConfig *tls.Config `json:"-"`
+}
+
+// Validate checks that the options describe a single deployment
+func (o ClientOptions) Validate() error {
+	for _, addr := range o.Addrs {
+		if strings.TrimSpace(addr) == "" {
+			return core.ErrConfigInvalid("Addrs", "Redis addresses cannot be empty")
+		}
+	}
+
+	if o.MasterName != "" && len(o.Addrs) == 0 {
+		return core.ErrConfigInvalid("MasterName", "Sentinel requires the sentinel addresses")
+	}
+
+	if o.MasterName != "" && o.Cluster {
+		return core.ErrConfigInvalid("Cluster", "Redis Cluster and Sentinel cannot be combined")
+	}
+
+	if o.DB < 0 {
+		return core.ErrConfigInvalid("DB", "Redis database cannot be negative")
+	}
+
+	if o.DB != 0 && o.isCluster() {
+		return core.ErrConfigInvalid("DB", "Redis Cluster only supports database 0")
+	}
+
+	return nil
+}
+
+// isCluster reports whether the options select Redis Cluster
+func (o ClientOptions) isCluster() bool {
+	return o.MasterName == "" && (o.Cluster || len(o.Addrs) > 1)
+}
+
+// tlsConfig returns the TLS settings, or nil when TLS is disabled
+func (o ClientOptions) tlsConfig() *tls.Config {
+	if o.TLSConfig != nil {
+		return o.TLSConfig
+	}
+	if o.TLS {
+		return &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return nil
+}
+
+// newClient creates a client for redisURL or for the deployment selected by options,
+// and returns the address reported in connection errors
+func newClient(redisURL string, options ClientOptions) (redis.UniversalClient, string, error) {
+	if err := options.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	if len(options.Addrs) == 0 {
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, "", core.ErrConfigInvalid("RedisURL", err.Error())
+		}
+		if options.Username != "" {
+			opts.Username = options.Username
+		}
+		if options.Password != "" {
+			opts.Password = options.Password
+		}
+		if options.DB != 0 {
+			opts.DB = options.DB
+		}
+		if tlsConfig := options.tlsConfig(); tlsConfig != nil {
+			opts.TLSConfig = tlsConfig
+		}
+		return redis.NewClient(opts), opts.Addr, nil
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:            options.Addrs,
+		MasterName:       options.MasterName,
+		IsClusterMode:    options.Cluster,
+		Username:         options.Username,
+		Password:         options.Password,
+		SentinelUsername: options.SentinelUsername,
+		SentinelPassword: options.SentinelPassword,
+		DB:               options.DB,
+		TLSConfig:        options.tlsConfig(),
+	})
+	return client, strings.Join(options.Addrs, ","), nil
+}
+
+// connect creates a client with newClient and verifies the connection
+func connect(ctx context.Context, redisURL string, options ClientOptions) (redis.UniversalClient, error) {
+	client, addr, err := newClient(redisURL, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify connection
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, core.ErrCacheConnection(addr, err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,202 @@
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+const (
+	// ENVELOPE_MAGIC starts every enveloped entry. Legacy entries are bare JSON objects
+	// and start with '{', so both can be told apart.
+	ENVELOPE_MAGIC = "mt"
+	// ENVELOPE_VERSION is the envelope layout written by this release. Legacy bare JSON
+	// entries are read as version 0.
+	ENVELOPE_VERSION byte = 1
+
+	CODEC_JSON    byte = 1
+	CODEC_MSGPACK byte = 2
+
+	// FLAG_GZIP marks a gzip-compressed payload
+	FLAG_GZIP byte = 1 << 0
+
+	// COMPRESS_MIN_SIZE is the smallest payload compressed when compression is enabled
+	COMPRESS_MIN_SIZE = 512
+
+	// envelopeHeaderSize covers the magic, version, codec ID and flags
+	envelopeHeaderSize = len(ENVELOPE_MAGIC) + 3
+)
+
+// Codec serializes the tenants stored in the cache. Every entry records the ID of the
+// codec that wrote it, so instances configured with another codec can still read it.
+type Codec interface {
+	// ID identifies the codec in stored entries; IDs below 128 are reserved for the
+	// built-in codecs
+	ID() byte
+	Name() string
+	Marshal(tenant *core.Tenant) ([]byte, error)
+	Unmarshal(data []byte, tenant *core.Tenant) error
+}
+
+var (
+	// JSONCodec stores tenants as JSON; it is the default codec
+	JSONCodec Codec = jsonCodec{}
+	// MsgpackCodec stores tenants as MessagePack, which is smaller and faster to decode
+	MsgpackCodec Codec = newMsgpackCodec()
+
+	builtinCodecs = []Codec{JSONCodec, MsgpackCodec}
+)
+
+// CodecByName returns the built-in codec called name ("json" or "msgpack")
+func CodecByName(name string) (Codec, error) {
+	for _, c := range builtinCodecs {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, core.ErrConfigInvalid("Codec", fmt.Sprintf("unknown cache codec: %q (must be json or msgpack)", name))
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ID() byte     { return CODEC_JSON }
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(tenant *core.Tenant) ([]byte, error) {
+	return json.Marshal(tenant)
+}
+
+func (jsonCodec) Unmarshal(data []byte, tenant *core.Tenant) error {
+	return json.Unmarshal(data, tenant)
+}
+
+// msgpackCodec encodes tenants with their JSON field names, so both codecs tolerate the
+// same struct changes
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() msgpackCodec {
+	handle := &codec.MsgpackHandle{WriteExt: true}
+	handle.TypeInfos = codec.NewTypeInfos([]string{"json"})
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	handle.RawToString = true
+	handle.SignedInteger = true
+	return msgpackCodec{handle: handle}
+}
+
+func (msgpackCodec) ID() byte     { return CODEC_MSGPACK }
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (m msgpackCodec) Marshal(tenant *core.Tenant) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, m.handle).Encode(tenant)
+	return data, err
+}
+
+func (m msgpackCodec) Unmarshal(data []byte, tenant *core.Tenant) error {
+	return codec.NewDecoderBytes(data, m.handle).Decode(tenant)
+}
+
+// encodeEntry wraps the tenant encoded by c in a versioned envelope, compressing
+// payloads of at least COMPRESS_MIN_SIZE bytes when compress is set
+func encodeEntry(c Codec, compress bool, tenant *core.Tenant) ([]byte, error) {
+	payload, err := c.Marshal(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if compress && len(payload) >= COMPRESS_MIN_SIZE {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		payload = buf.Bytes()
+		flags |= FLAG_GZIP
+	}
+
+	entry := make([]byte, 0, envelopeHeaderSize+len(payload))
+	entry = append(entry, ENVELOPE_MAGIC...)
+	entry = append(entry, ENVELOPE_VERSION, c.ID(), flags)
+	return append(entry, payload...), nil
+}
+
+// errUnreadableEntry reports an entry written by a newer release or with an unknown
+// codec; it is treated as a cache miss
+type errUnreadableEntry struct {
+	reason string
+}
+
+func (e errUnreadableEntry) Error() string {
+	return "unreadable cache entry: " + e.reason
+}
+
+// decodeEntry parses an enveloped or legacy bare JSON entry. custom is consulted for
+// codec IDs that are not built in.
+func decodeEntry(data []byte, custom Codec) (*core.Tenant, error) {
+	var tenant core.Tenant
+
+	if !bytes.HasPrefix(data, []byte(ENVELOPE_MAGIC)) {
+		// Version 0: bare JSON written before envelopes were introduced
+		if err := json.Unmarshal(data, &tenant); err != nil {
+			return nil, err
+		}
+		return &tenant, nil
+	}
+
+	if len(data) < envelopeHeaderSize {
+		return nil, fmt.Errorf("truncated cache entry header")
+	}
+	header := data[len(ENVELOPE_MAGIC):envelopeHeaderSize]
+	version, codecID, flags := header[0], header[1], header[2]
+	payload := data[envelopeHeaderSize:]
+
+	if version > ENVELOPE_VERSION {
+		return nil, errUnreadableEntry{reason: fmt.Sprintf("envelope version %d is newer than %d", version, ENVELOPE_VERSION)}
+	}
+
+	c := findCodec(codecID, custom)
+	if c == nil {
+		return nil, errUnreadableEntry{reason: fmt.Sprintf("unknown codec %d", codecID)}
+	}
+
+	if flags&FLAG_GZIP != 0 {
+		reader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Unmarshal(payload, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// findCodec returns the built-in or custom codec with id, or nil
+func findCodec(id byte, custom Codec) Codec {
+	if custom != nil && custom.ID() == id {
+		return custom
+	}
+	for _, c := range builtinCodecs {
+		if c.ID() == id {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// mapRedisError maps Redis client errors to *core.MultitenantError. name identifies the
+// tenant for cache misses; errors that are already structured are returned unchanged.
+func mapRedisError(err error, name string) error {
+	if err == nil {
+		return nil
+	}
+
+	var mtErr *core.MultitenantError
+	if errors.As(err, &mtErr) {
+		return err
+	}
+
+	if errors.Is(err, redis.Nil) {
+		return core.ErrTenantNotFound(name)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return core.ErrCacheTimeout(err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return core.ErrCacheTimeout(err)
+		}
+		return connectionError(err)
+	}
+
+	if errors.Is(err, redis.ErrClosed) || errors.Is(err, redis.ErrPoolTimeout) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return connectionError(err)
+	}
+
+	return core.NewError(core.ErrCodeCacheOperation, "cache operation failed").WithCause(err)
+}
+
+// connectionError reports a lost or refused Redis connection
+func connectionError(cause error) error {
+	return core.NewError(core.ErrCodeCacheConnection, "cache connection failed").WithCause(cause)
+}
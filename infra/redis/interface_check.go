@@ -2,5 +2,10 @@ package redis
 
 import "github.com/victorximenis/multitenant/core"
 
-// Compile-time check to ensure TenantCache implements core.TenantCache interface
-var _ core.TenantCache = (*TenantCache)(nil)
+// Compile-time checks to ensure TenantCache implements the core cache interfaces
+var (
+	_ core.TenantCache   = (*TenantCache)(nil)
+	_ core.NegativeCache = (*TenantCache)(nil)
+	_ core.EntryCache    = (*TenantCache)(nil)
+	_ core.BatchCache    = (*TenantCache)(nil)
+)
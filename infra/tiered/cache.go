@@ -0,0 +1,308 @@
+// Package tiered layers a small in-process cache over a shared remote cache such as
+// Redis, so hot tenants are served without a network round trip.
+package tiered
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/infra/memory"
+)
+
+const (
+	DEFAULT_MAX_ENTRIES  = 1000
+	DEFAULT_TTL          = 30 * time.Second
+	DEFAULT_NEGATIVE_TTL = 10 * time.Second
+)
+
+// Config holds configuration for the tiered cache
+type Config struct {
+	// Remote is the shared L2 cache, usually the Redis cache
+	Remote core.TenantCache
+	// MaxEntries bounds the in-process L1 cache, which evicts the least recently used tenant
+	MaxEntries int
+	// TTL caps how long L1 serves a tenant; keep it short, as other processes only see
+	// changes in L2 once their L1 entry expires
+	TTL time.Duration
+	// NegativeTTL caps how long L1 keeps a "not found" result read from L2, whose
+	// remaining TTL is unknown; it never exceeds TTL
+	NegativeTTL time.Duration
+}
+
+// TierStats counts the lookups served by one tier
+type TierStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// NegativeHits counts lookups answered by a cached "not found" result
+	NegativeHits uint64 `json:"negative_hits"`
+	// Errors counts lookups that failed with anything other than TENANT_NOT_FOUND
+	Errors uint64 `json:"errors"`
+}
+
+// Stats reports the lookups of both tiers. L2 only sees the lookups L1 missed.
+type Stats struct {
+	L1 TierStats `json:"l1"`
+	L2 TierStats `json:"l2"`
+	// L1Evictions counts tenants evicted from L1 to respect MaxEntries
+	L1Evictions uint64 `json:"l1_evictions"`
+}
+
+// tierCounters holds the live counters of one tier
+type tierCounters struct {
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	negativeHits atomic.Uint64
+	errors       atomic.Uint64
+}
+
+// record counts the outcome of a lookup
+func (c *tierCounters) record(err error) {
+	switch {
+	case err == nil:
+		c.hits.Add(1)
+	case core.IsCachedNotFound(err):
+		c.negativeHits.Add(1)
+	case core.IsErrorCode(err, core.ErrCodeTenantNotFound):
+		c.misses.Add(1)
+	default:
+		c.errors.Add(1)
+	}
+}
+
+func (c *tierCounters) snapshot() TierStats {
+	return TierStats{
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Errors:       c.errors.Load(),
+	}
+}
+
+// Cache implements core.TenantCache with an in-process LRU (L1) in front of a remote
+// cache (L2). Lookups fill L1 from L2; writes and deletes go to both tiers.
+type Cache struct {
+	local       *memory.TenantCache
+	remote      core.TenantCache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	l1          tierCounters
+	l2          tierCounters
+}
+
+// NewCache creates a tiered cache over config.Remote
+func NewCache(config Config) (*Cache, error) {
+	if config.Remote == nil {
+		return nil, core.ErrConfigInvalid("Remote", "tiered cache requires a remote cache")
+	}
+
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DEFAULT_MAX_ENTRIES
+	}
+
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+
+	negativeTTL := config.NegativeTTL
+	if negativeTTL <= 0 {
+		negativeTTL = DEFAULT_NEGATIVE_TTL
+	}
+	if negativeTTL > ttl {
+		negativeTTL = ttl
+	}
+
+	return &Cache{
+		local:       memory.NewTenantCache(memory.CacheConfig{TTL: ttl, MaxEntries: maxEntries}),
+		remote:      config.Remote,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}, nil
+}
+
+// Close closes the remote cache
+func (c *Cache) Close(ctx context.Context) error {
+	return core.CloseResource(ctx, c.remote)
+}
+
+// Get returns a tenant from L1, or from L2 while filling L1
+func (c *Cache) Get(ctx context.Context, name string) (*core.Tenant, error) {
+	if _, ok := c.remote.(core.EntryCache); !ok {
+		return c.get(ctx, name, c.local.Get, c.remote.Get)
+	}
+
+	// Go through GetEntry so L1 learns how long the tenant stays fresh in L2
+	entry, err := c.GetEntry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.Fresh(time.Now()) {
+		return nil, core.ErrTenantNotFound(name)
+	}
+	return entry.Tenant, nil
+}
+
+// GetEntry returns a tenant with its freshness from L1, or from L2 while filling L1.
+// Stale L2 entries are returned without being copied to L1.
+func (c *Cache) GetEntry(ctx context.Context, name string) (*core.CacheEntry, error) {
+	entry, err := c.local.GetEntry(ctx, name)
+	c.l1.record(err)
+	if err == nil || core.IsCachedNotFound(err) {
+		return entry, err
+	}
+
+	remote, ok := c.remote.(core.EntryCache)
+	if !ok {
+		tenant, err := c.remote.Get(ctx, name)
+		c.l2.record(err)
+		if err != nil {
+			return nil, c.fillNotFound(ctx, name, err)
+		}
+		c.local.Set(ctx, tenant, c.ttl)
+		return &core.CacheEntry{Tenant: tenant}, nil
+	}
+
+	entry, err = remote.GetEntry(ctx, name)
+	c.l2.record(err)
+	if err != nil {
+		return nil, c.fillNotFound(ctx, name, err)
+	}
+
+	if entry.Fresh(time.Now()) {
+		c.local.SetEntry(ctx, *entry, c.ttl)
+	}
+	return entry, nil
+}
+
+// GetByID returns a tenant by ID from L1, or from L2 while filling L1
+func (c *Cache) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return c.get(ctx, id, c.local.GetByID, c.remote.GetByID)
+}
+
+// get looks key up in both tiers with the given lookups. Cached "not found" results
+// are served like hits, and those read from L2 are copied to L1.
+func (c *Cache) get(ctx context.Context, key string, local, remote func(context.Context, string) (*core.Tenant, error)) (*core.Tenant, error) {
+	tenant, err := local(ctx, key)
+	c.l1.record(err)
+	if err == nil || core.IsCachedNotFound(err) {
+		return tenant, err
+	}
+
+	tenant, err = remote(ctx, key)
+	c.l2.record(err)
+	if err != nil {
+		return nil, c.fillNotFound(ctx, key, err)
+	}
+
+	c.local.Set(ctx, tenant, c.ttl)
+	return tenant, nil
+}
+
+// fillNotFound copies a "not found" result cached by L2 to L1 and returns err
+func (c *Cache) fillNotFound(ctx context.Context, name string, err error) error {
+	if core.IsCachedNotFound(err) {
+		c.local.SetNotFound(ctx, name, c.negativeTTL)
+	}
+	return err
+}
+
+// Set stores a tenant in L2 for ttl and in L1 for at most the L1 TTL
+func (c *Cache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	if tenant == nil {
+		return core.ErrTenantInvalid("", "tenant cannot be nil")
+	}
+
+	if err := c.remote.Set(ctx, tenant, ttl); err != nil {
+		// Never keep serving a version L2 did not accept
+		c.local.Delete(ctx, tenant.Name)
+		return err
+	}
+
+	localTTL := c.ttl
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	return c.local.Set(ctx, tenant, localTTL)
+}
+
+// SetMany stores tenants in L2, in one batch when it supports batches, and then in L1
+// for at most the L1 TTL
+func (c *Cache) SetMany(ctx context.Context, tenants []*core.Tenant, ttl time.Duration) error {
+	if err := core.SetMany(ctx, c.remote, tenants, ttl); err != nil {
+		// Never keep serving versions L2 may not have accepted
+		for _, tenant := range tenants {
+			if tenant != nil {
+				c.local.Delete(ctx, tenant.Name)
+			}
+		}
+		return err
+	}
+
+	localTTL := c.ttl
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	return c.local.SetMany(ctx, tenants, localTTL)
+}
+
+// SetNotFound remembers name as not found in L2, when it supports negative entries,
+// and in L1 for at most the L1 TTL
+func (c *Cache) SetNotFound(ctx context.Context, name string, ttl time.Duration) error {
+	if remote, ok := c.remote.(core.NegativeCache); ok {
+		if err := remote.SetNotFound(ctx, name, ttl); err != nil {
+			return err
+		}
+	}
+
+	localTTL := c.ttl
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	return c.local.SetNotFound(ctx, name, localTTL)
+}
+
+// Delete removes a tenant from both tiers
+func (c *Cache) Delete(ctx context.Context, name string) error {
+	c.local.Delete(ctx, name)
+	return c.remote.Delete(ctx, name)
+}
+
+// DeleteAll clears both tiers; L2 is only cleared when it supports clearing
+func (c *Cache) DeleteAll(ctx context.Context) error {
+	c.local.DeleteAll(ctx)
+
+	remote, ok := c.remote.(interface {
+		DeleteAll(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+	return remote.DeleteAll(ctx)
+}
+
+// DeleteLocal clears L1 and leaves the shared L2 untouched
+func (c *Cache) DeleteLocal(ctx context.Context) error {
+	return c.local.DeleteAll(ctx)
+}
+
+// DeleteLocalName removes a tenant from L1 and leaves the shared L2 untouched
+func (c *Cache) DeleteLocalName(ctx context.Context, name string) error {
+	return c.local.Delete(ctx, name)
+}
+
+// Stats returns the hit and miss counters of both tiers
+func (c *Cache) Stats() Stats {
+	return Stats{
+		L1:          c.l1.snapshot(),
+		L2:          c.l2.snapshot(),
+		L1Evictions: c.local.Evictions(),
+	}
+}
+
+// Unwrap returns the remote cache
+func (c *Cache) Unwrap() core.TenantCache {
+	return c.remote
+}
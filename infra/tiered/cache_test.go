@@ -0,0 +1,258 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/conformance"
+	"github.com/victorximenis/multitenant/infra/memory"
+)
+
+// countingCache records the calls reaching the remote tier and can fail them
+type countingCache struct {
+	*memory.TenantCache
+	gets int
+	err  error
+}
+
+func newCountingCache() *countingCache {
+	return &countingCache{TenantCache: memory.NewTenantCache(memory.CacheConfig{})}
+}
+
+func (c *countingCache) Get(ctx context.Context, name string) (*core.Tenant, error) {
+	c.gets++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.TenantCache.Get(ctx, name)
+}
+
+func (c *countingCache) GetEntry(ctx context.Context, name string) (*core.CacheEntry, error) {
+	c.gets++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.TenantCache.GetEntry(ctx, name)
+}
+
+func (c *countingCache) Set(ctx context.Context, tenant *core.Tenant, ttl time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.TenantCache.Set(ctx, tenant, ttl)
+}
+
+func (c *countingCache) SetMany(ctx context.Context, tenants []*core.Tenant, ttl time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.TenantCache.SetMany(ctx, tenants, ttl)
+}
+
+func newTestCache(t *testing.T, remote core.TenantCache) *Cache {
+	cache, err := NewCache(Config{Remote: remote, MaxEntries: 2, TTL: time.Minute})
+	require.NoError(t, err)
+	return cache
+}
+
+func TestCache_ServesFromL1AfterFirstLookup(t *testing.T) {
+	remote := newCountingCache()
+	cache := newTestCache(t, remote)
+	ctx := context.Background()
+
+	tenant := core.NewTenant("acme")
+	require.NoError(t, remote.TenantCache.Set(ctx, tenant, 0))
+
+	for i := 0; i < 3; i++ {
+		found, err := cache.Get(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, tenant.ID, found.ID)
+	}
+	found, err := cache.GetByID(ctx, tenant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", found.Name)
+
+	_, err = cache.Get(ctx, "missing")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	assert.Equal(t, 2, remote.gets)
+	assert.Equal(t, Stats{
+		L1: TierStats{Hits: 3, Misses: 2},
+		L2: TierStats{Hits: 1, Misses: 1},
+	}, cache.Stats())
+}
+
+func TestCache_DeletePropagatesToBothTiers(t *testing.T) {
+	remote := newCountingCache()
+	cache := newTestCache(t, remote)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, core.NewTenant("acme"), 0))
+	require.NoError(t, cache.Set(ctx, core.NewTenant("globex"), 0))
+
+	require.NoError(t, cache.Delete(ctx, "acme"))
+	_, err := cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	_, err = remote.TenantCache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+
+	require.NoError(t, cache.DeleteAll(ctx))
+	assert.Equal(t, 0, remote.Len())
+	_, err = cache.Get(ctx, "globex")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+}
+
+func TestCache_DeleteLocalKeepsRemoteTier(t *testing.T) {
+	remote := newCountingCache()
+	cache := newTestCache(t, remote)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, core.NewTenant("acme"), 0))
+	require.NoError(t, cache.DeleteLocal(ctx))
+	assert.Equal(t, 1, remote.Len())
+
+	// The next lookup misses L1 and is served by L2
+	_, err := cache.Get(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, 1, remote.gets)
+
+	require.NoError(t, cache.DeleteLocalName(ctx, "acme"))
+	assert.Equal(t, 1, remote.Len())
+	_, err = cache.Get(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, 2, remote.gets)
+}
+
+func TestCache_RemoteFailures(t *testing.T) {
+	remote := newCountingCache()
+	cache := newTestCache(t, remote)
+	ctx := context.Background()
+
+	tenant := core.NewTenant("acme")
+	require.NoError(t, cache.Set(ctx, tenant, 0))
+
+	// A failed write must not leave the previous version in L1
+	remote.err = core.ErrCacheTimeout(errors.New("i/o timeout"))
+	updated := *tenant
+	updated.IsActive = false
+	assert.Error(t, cache.Set(ctx, &updated, 0))
+
+	_, err := cache.Get(ctx, "acme")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeCacheTimeout))
+	assert.Equal(t, uint64(1), cache.Stats().L2.Errors)
+}
+
+func TestCache_BoundedL1(t *testing.T) {
+	cache := newTestCache(t, newCountingCache())
+	ctx := context.Background()
+
+	for _, name := range []string{"acme", "globex", "initech"} {
+		require.NoError(t, cache.Set(ctx, core.NewTenant(name), 0))
+	}
+
+	assert.Equal(t, uint64(1), cache.Stats().L1Evictions)
+	_, err := cache.Get(ctx, "acme")
+	require.NoError(t, err, "evicted tenants are still served by L2")
+}
+
+func TestNewCache_RequiresRemote(t *testing.T) {
+	_, err := NewCache(Config{})
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeConfigInvalid))
+}
+
+func TestCache_Conformance(t *testing.T) {
+	conformance.RunCacheTests(t, func(t *testing.T) core.TenantCache {
+		return newTestCache(t, newCountingCache())
+	})
+}
+
+func TestCache_NotFoundEntries(t *testing.T) {
+	remote := newCountingCache()
+	cache := newTestCache(t, remote)
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetNotFound(ctx, "ghost", time.Minute))
+	_, err := remote.TenantCache.Get(ctx, "ghost")
+	assert.True(t, core.IsCachedNotFound(err))
+	_, err = cache.Get(ctx, "ghost")
+	assert.True(t, core.IsCachedNotFound(err))
+	assert.Equal(t, 0, remote.gets)
+
+	// Negative entries written by another process are copied from L2
+	require.NoError(t, remote.TenantCache.SetNotFound(ctx, "phantom", time.Minute))
+	for i := 0; i < 2; i++ {
+		_, err = cache.Get(ctx, "phantom")
+		assert.True(t, core.IsCachedNotFound(err))
+	}
+	assert.Equal(t, 1, remote.gets)
+	assert.Equal(t, Stats{
+		L1: TierStats{NegativeHits: 2, Misses: 1},
+		L2: TierStats{NegativeHits: 1},
+	}, cache.Stats())
+
+	// Creating the tenant clears the negative entry in both tiers
+	require.NoError(t, cache.Set(ctx, core.NewTenant("ghost"), 0))
+	_, err = cache.Get(ctx, "ghost")
+	require.NoError(t, err)
+	_, err = remote.TenantCache.Get(ctx, "ghost")
+	require.NoError(t, err)
+}
+
+func TestCache_EntriesKeepRemoteFreshness(t *testing.T) {
+	remote := &countingCache{TenantCache: memory.NewTenantCache(memory.CacheConfig{StaleTTL: time.Hour})}
+	cache := newTestCache(t, remote)
+	ctx := context.Background()
+
+	// L1 learns when the tenant stops being fresh in L2 instead of restarting its TTL
+	acme := core.NewTenant("acme")
+	require.NoError(t, remote.TenantCache.Set(ctx, acme, 10*time.Second))
+	entry, err := cache.GetEntry(ctx, "acme")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), entry.FreshUntil, time.Second)
+	entry, err = cache.GetEntry(ctx, "acme")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), entry.FreshUntil, time.Second)
+	assert.Equal(t, 1, remote.gets)
+
+	// Stale L2 entries are returned by GetEntry but not copied to L1 or served by Get
+	globex := core.NewTenant("globex")
+	require.NoError(t, remote.TenantCache.SetEntry(ctx, core.CacheEntry{Tenant: globex, FreshUntil: time.Now().Add(-time.Second)}, time.Hour))
+	entry, err = cache.GetEntry(ctx, "globex")
+	require.NoError(t, err)
+	assert.False(t, entry.Fresh(time.Now()))
+	_, err = cache.Get(ctx, "globex")
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeTenantNotFound))
+	assert.Equal(t, 3, remote.gets)
+}
+
+func TestCache_SetManyFillsBothTiers(t *testing.T) {
+	remote := newCountingCache()
+	cache, err := NewCache(Config{Remote: remote, MaxEntries: 10, TTL: time.Minute})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tenants := []*core.Tenant{core.NewTenant("acme"), core.NewTenant("globex")}
+	require.NoError(t, cache.SetMany(ctx, tenants, 0))
+
+	for _, tenant := range tenants {
+		_, err := remote.TenantCache.Get(ctx, tenant.Name)
+		require.NoError(t, err)
+		_, err = cache.Get(ctx, tenant.Name)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, remote.gets)
+
+	// A batch L2 rejects is not kept in L1
+	remote.err = errors.New("redis down")
+	require.Error(t, cache.SetMany(ctx, []*core.Tenant{tenants[0]}, 0))
+	remote.err = nil
+	_, err = cache.Get(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, 1, remote.gets)
+}
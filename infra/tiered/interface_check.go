@@ -0,0 +1,11 @@
+package tiered
+
+import "github.com/victorximenis/multitenant/core"
+
+// Compile-time checks to ensure Cache implements the core cache interfaces
+var (
+	_ core.TenantCache   = (*Cache)(nil)
+	_ core.NegativeCache = (*Cache)(nil)
+	_ core.EntryCache    = (*Cache)(nil)
+	_ core.BatchCache    = (*Cache)(nil)
+)
@@ -147,6 +147,13 @@ func (m *mockTenantRepository) GetByName(ctx context.Context, name string) (*cor
 	}, nil
 }
 
+func (m *mockTenantRepository) GetByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return &core.Tenant{
+		ID:   id,
+		Name: "test-tenant",
+	}, nil
+}
+
 func (m *mockTenantRepository) List(ctx context.Context) ([]core.Tenant, error) {
 	return []core.Tenant{}, nil
 }
@@ -177,6 +184,10 @@ func (m *mockTenantService) GetTenant(ctx context.Context, name string) (*core.T
 	}, nil
 }
 
+func (m *mockTenantService) GetTenantByID(ctx context.Context, id string) (*core.Tenant, error) {
+	return m.GetTenant(ctx, "test-tenant")
+}
+
 func (m *mockTenantService) CreateTenant(ctx context.Context, tenant *core.Tenant) error {
 	return nil
 }
@@ -34,6 +34,15 @@ func (m *MockTenantService) GetTenant(ctx context.Context, name string) (*core.T
 	return tenant, nil
 }
 
+func (m *MockTenantService) GetTenantByID(ctx context.Context, id string) (*core.Tenant, error) {
+	for _, tenant := range m.tenants {
+		if tenant.ID == id {
+			return tenant, nil
+		}
+	}
+	return nil, core.TenantNotFoundError{Name: id}
+}
+
 func (m *MockTenantService) ListTenants(ctx context.Context) ([]core.Tenant, error) {
 	tenants := make([]core.Tenant, 0, len(m.tenants))
 	for _, tenant := range m.tenants {
@@ -70,9 +70,36 @@ func (r *TenantResolver) WithTenant(ctx context.Context, tenantName string, fn f
 	return fn(ctx)
 }
 
+// ForEachOption narrows the tenants visited by ForEachTenant
+type ForEachOption func(*forEachOptions)
+
+type forEachOptions struct {
+	query    *core.MetadataQuery
+	selector *core.LabelSelector
+}
+
+// WithMetadataQuery only visits tenants whose metadata matches query
+func WithMetadataQuery(query core.MetadataQuery) ForEachOption {
+	return func(o *forEachOptions) {
+		o.query = &query
+	}
+}
+
+// WithLabelSelector only visits tenants whose labels match selector
+func WithLabelSelector(selector core.LabelSelector) ForEachOption {
+	return func(o *forEachOptions) {
+		o.selector = &selector
+	}
+}
+
 // ForEachTenant runs a function for each active tenant
-func (r *TenantResolver) ForEachTenant(ctx context.Context, fn func(context.Context) error) error {
-	tenants, err := r.tenantService.ListTenants(ctx)
+func (r *TenantResolver) ForEachTenant(ctx context.Context, fn func(context.Context) error, opts ...ForEachOption) error {
+	var options forEachOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	tenants, err := r.listTenants(ctx, options)
 	if err != nil {
 		return err
 	}
@@ -90,3 +117,53 @@ func (r *TenantResolver) ForEachTenant(ctx context.Context, fn func(context.Cont
 
 	return nil
 }
+
+// listTenants returns the tenants selected by options, querying the service by metadata
+// or labels when it supports it. With both options, labels are filtered in memory.
+func (r *TenantResolver) listTenants(ctx context.Context, options forEachOptions) ([]core.Tenant, error) {
+	if options.selector != nil {
+		if err := options.selector.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.query == nil {
+		if options.selector == nil {
+			return r.tenantService.ListTenants(ctx)
+		}
+		if lister, ok := r.tenantService.(core.LabelLister); ok {
+			return lister.ListByLabels(ctx, *options.selector)
+		}
+	}
+
+	tenants, err := r.queryTenants(ctx, options.query)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.selector != nil {
+		tenants = options.selector.Filter(tenants)
+	}
+	return tenants, nil
+}
+
+// queryTenants returns the tenants matching query, or all tenants for a nil query
+func (r *TenantResolver) queryTenants(ctx context.Context, query *core.MetadataQuery) ([]core.Tenant, error) {
+	if query == nil {
+		return r.tenantService.ListTenants(ctx)
+	}
+
+	if querier, ok := r.tenantService.(core.MetadataQuerier); ok {
+		return querier.FindByMetadata(ctx, *query)
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	tenants, err := r.tenantService.ListTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return query.Filter(tenants), nil
+}
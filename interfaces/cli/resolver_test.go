@@ -153,6 +153,70 @@ func TestTenantResolver_ForEachTenant(t *testing.T) {
 	assert.NotContains(t, processedTenants, "inactive-tenant")
 }
 
+func TestTenantResolver_ForEachTenantWithMetadataQuery(t *testing.T) {
+	mockService := NewMockTenantService()
+	mockService.AddTenant(&core.Tenant{ID: "eu-id", Name: "eu-tenant", IsActive: true,
+		Metadata: map[string]interface{}{"region": "eu", "plan": "pro"}})
+	mockService.AddTenant(&core.Tenant{ID: "us-id", Name: "us-tenant", IsActive: true,
+		Metadata: map[string]interface{}{"region": "us", "plan": "pro"}})
+	mockService.AddTenant(&core.Tenant{ID: "free-id", Name: "free-tenant", IsActive: true,
+		Metadata: map[string]interface{}{"region": "eu", "plan": "free"}})
+
+	resolver := NewTenantResolver(mockService, "TEST_TENANT")
+
+	var processedTenants []string
+	query := core.NewMetadataQuery(
+		core.MetadataEquals("region", "eu"),
+		core.MetadataIn("plan", "pro", "enterprise"),
+	)
+	err := resolver.ForEachTenant(context.Background(), func(ctx context.Context) error {
+		tenant, _ := tenantcontext.GetTenant(ctx)
+		processedTenants = append(processedTenants, tenant.Name)
+		return nil
+	}, WithMetadataQuery(query))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eu-tenant"}, processedTenants)
+
+	// Invalid queries fail before any tenant is processed
+	err = resolver.ForEachTenant(context.Background(), func(ctx context.Context) error {
+		t.Fatal("unexpected call")
+		return nil
+	}, WithMetadataQuery(core.NewMetadataQuery(core.MetadataEquals("", "eu"))))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeValidationFailed))
+}
+
+func TestTenantResolver_ForEachTenantWithLabelSelector(t *testing.T) {
+	mockService := NewMockTenantService()
+	mockService.AddTenant(&core.Tenant{ID: "gold-id", Name: "gold-tenant", IsActive: true,
+		Metadata: map[string]interface{}{"region": "eu"}, Labels: map[string]string{"tier": "gold"}})
+	mockService.AddTenant(&core.Tenant{ID: "silver-id", Name: "silver-tenant", IsActive: true,
+		Metadata: map[string]interface{}{"region": "eu"}, Labels: map[string]string{"tier": "silver"}})
+	mockService.AddTenant(&core.Tenant{ID: "us-id", Name: "us-tenant", IsActive: true,
+		Metadata: map[string]interface{}{"region": "us"}, Labels: map[string]string{"tier": "gold"}})
+
+	resolver := NewTenantResolver(mockService, "TEST_TENANT")
+
+	var processedTenants []string
+	err := resolver.ForEachTenant(context.Background(), func(ctx context.Context) error {
+		tenant, _ := tenantcontext.GetTenant(ctx)
+		processedTenants = append(processedTenants, tenant.Name)
+		return nil
+	}, WithLabelSelector(core.MustParseLabelSelector("tier in (gold)")),
+		WithMetadataQuery(core.NewMetadataQuery(core.MetadataEquals("region", "eu"))))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gold-tenant"}, processedTenants)
+
+	// Invalid selectors fail before any tenant is processed
+	invalid := core.LabelSelector{Requirements: []core.LabelRequirement{{Key: "tier", Operator: core.SelectorIn}}}
+	err = resolver.ForEachTenant(context.Background(), func(ctx context.Context) error {
+		t.Fatal("unexpected call")
+		return nil
+	}, WithLabelSelector(invalid))
+	assert.True(t, core.IsErrorCode(err, core.ErrCodeValidationFailed))
+}
+
 func TestTenantResolver_WithTenant(t *testing.T) {
 	mockService := NewMockTenantService()
 	mockService.AddTenant(&core.Tenant{
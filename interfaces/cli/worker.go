@@ -2,7 +2,7 @@ package cli
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,10 +14,12 @@ import (
 type Worker struct {
 	resolver     *TenantResolver
 	processAll   bool
+	forEachOpts  []ForEachOption
 	tenantName   string
 	pollInterval time.Duration
 	shutdownChan chan struct{}
 	shutdownDone chan struct{}
+	logger       *slog.Logger
 }
 
 type WorkerConfig struct {
@@ -26,6 +28,12 @@ type WorkerConfig struct {
 	TenantName    string
 	EnvVarName    string
 	PollInterval  time.Duration
+	// MetadataQuery restricts ProcessAll to the tenants whose metadata matches it
+	MetadataQuery *core.MetadataQuery
+	// LabelSelector restricts ProcessAll to the tenants whose labels match it
+	LabelSelector *core.LabelSelector
+	// Logger receives the processing failures; defaults to slog.Default()
+	Logger *slog.Logger
 }
 
 func NewWorker(config WorkerConfig) *Worker {
@@ -33,13 +41,23 @@ func NewWorker(config WorkerConfig) *Worker {
 		config.PollInterval = 1 * time.Minute
 	}
 
+	var forEachOpts []ForEachOption
+	if config.MetadataQuery != nil {
+		forEachOpts = append(forEachOpts, WithMetadataQuery(*config.MetadataQuery))
+	}
+	if config.LabelSelector != nil {
+		forEachOpts = append(forEachOpts, WithLabelSelector(*config.LabelSelector))
+	}
+
 	return &Worker{
 		resolver:     NewTenantResolver(config.TenantService, config.EnvVarName),
 		processAll:   config.ProcessAll,
+		forEachOpts:  forEachOpts,
 		tenantName:   config.TenantName,
 		pollInterval: config.PollInterval,
 		shutdownChan: make(chan struct{}),
 		shutdownDone: make(chan struct{}),
+		logger:       core.ComponentLogger(config.Logger, "worker"),
 	}
 }
 
@@ -81,9 +99,9 @@ func (w *Worker) run(ctx context.Context, processFn func(context.Context) error)
 func (w *Worker) process(ctx context.Context, processFn func(context.Context) error) {
 	if w.processAll {
 		// Process all tenants
-		err := w.resolver.ForEachTenant(ctx, processFn)
+		err := w.resolver.ForEachTenant(ctx, processFn, w.forEachOpts...)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing tenants: %v\n", err)
+			w.logger.ErrorContext(ctx, "failed to process tenants", core.LogKeyError, err)
 		}
 	} else {
 		// Process single tenant
@@ -94,13 +112,14 @@ func (w *Worker) process(ctx context.Context, processFn func(context.Context) er
 		}
 
 		if tenantName == "" {
-			fmt.Fprintf(os.Stderr, "No tenant specified\n")
+			w.logger.WarnContext(ctx, "no tenant specified", "env", w.resolver.envVarName)
 			return
 		}
 
 		err := w.resolver.WithTenant(ctx, tenantName, processFn)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing tenant %s: %v\n", tenantName, err)
+			w.logger.ErrorContext(ctx, "failed to process tenant",
+				core.LogKeyTenantName, tenantName, core.LogKeyError, err)
 		}
 	}
 }
@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"os"
 	"sync"
 	"testing"
@@ -198,11 +200,13 @@ func TestWorker_ProcessingError(t *testing.T) {
 	})
 
 	// Test worker with processing error
+	var logs bytes.Buffer
 	worker := NewWorker(WorkerConfig{
 		TenantService: mockService,
 		ProcessAll:    false,
 		TenantName:    "test-tenant",
 		PollInterval:  10 * time.Millisecond,
+		Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
 	})
 
 	ctx := context.Background()
@@ -225,6 +229,10 @@ func TestWorker_ProcessingError(t *testing.T) {
 	mu.Lock()
 	assert.True(t, processed) // Should still process, but handle the error
 	mu.Unlock()
+
+	assert.Contains(t, logs.String(), "failed to process tenant")
+	assert.Contains(t, logs.String(), "component=worker")
+	assert.Contains(t, logs.String(), "tenant.name=test-tenant")
 }
 
 func TestNewWorker(t *testing.T) {
@@ -3,6 +3,7 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -16,15 +17,18 @@ type ChiMiddlewareConfig struct {
 	HeaderName       string
 	ErrorHandler     func(http.ResponseWriter, *http.Request, error)
 	IgnoredEndpoints []string
+	// Logger receives the tenant resolution failures; defaults to slog.Default()
+	Logger *slog.Logger
 }
 
 // DefaultChiErrorHandler provides default error handling for Chi middleware
 func DefaultChiErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
 	statusCode := http.StatusInternalServerError
 
-	if _, ok := err.(core.TenantNotFoundError); ok {
+	switch core.GetErrorCode(err) {
+	case core.ErrCodeTenantNotFound:
 		statusCode = http.StatusNotFound
-	} else if _, ok := err.(core.TenantInactiveError); ok {
+	case core.ErrCodeTenantInactive:
 		statusCode = http.StatusForbidden
 	}
 
@@ -50,6 +54,8 @@ func ChiTenantMiddleware(config ChiMiddlewareConfig) func(http.Handler) http.Han
 		config.ErrorHandler = DefaultChiErrorHandler
 	}
 
+	logger := core.ComponentLogger(config.Logger, "http.chi")
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check if the current path should be ignored
@@ -64,23 +70,30 @@ func ChiTenantMiddleware(config ChiMiddlewareConfig) func(http.Handler) http.Han
 			// Get tenant from header
 			tenantName := r.Header.Get(config.HeaderName)
 			if tenantName == "" {
-				config.ErrorHandler(w, r, errors.New("tenant header not found"))
+				err := errors.New("tenant header not found")
+				logResolveFailure(r.Context(), logger, r.Method, path, tenantName, err)
+				config.ErrorHandler(w, r, err)
 				return
 			}
 
-			// Get tenant from service
-			tenant, err := config.TenantService.GetTenant(r.Context(), tenantName)
+			// Get tenant from service, letting it flag tenants served from a stale cache
+			ctx := tenantcontext.WithStaleTracking(r.Context())
+			tenant, err := config.TenantService.GetTenant(ctx, tenantName)
 			if err != nil {
+				logResolveFailure(ctx, logger, r.Method, path, tenantName, err)
 				config.ErrorHandler(w, r, err)
 				return
 			}
 
 			// Store tenant in context
-			ctx := tenantcontext.WithTenant(r.Context(), tenant)
+			ctx = tenantcontext.WithTenant(ctx, tenant)
 			r = r.WithContext(ctx)
 
 			// Add tenant to response headers for debugging
 			w.Header().Set("X-Tenant-Name", tenant.Name)
+			if tenantcontext.IsStale(ctx) {
+				w.Header().Set("X-Tenant-Stale", "true")
+			}
 
 			// Propagate tenant to tracing span if available
 			tenantcontext.PropagateToSpan(ctx)
@@ -169,3 +169,30 @@ func TestChiTenantMiddleware_CustomErrorHandler(t *testing.T) {
 		assert.Contains(t, string(body), "tenant header X-Tenant-Id not provided")
 	})
 }
+
+func TestChiTenantMiddleware_StaleTenant(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(ChiTenantMiddleware(ChiMiddlewareConfig{TenantService: NewMockTenantService()}))
+	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		if tenantcontext.IsStale(r.Context()) {
+			w.Write([]byte("stale"))
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Tenant-Id", "stale-tenant")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "stale", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("X-Tenant-Stale"))
+
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Tenant-Id", "test-tenant")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, w.Header().Get("X-Tenant-Stale"))
+}
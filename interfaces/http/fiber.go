@@ -2,6 +2,7 @@ package http
 
 import (
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,15 +17,18 @@ type FiberMiddlewareConfig struct {
 	HeaderName       string
 	ErrorHandler     func(*fiber.Ctx, error) error
 	IgnoredEndpoints []string
+	// Logger receives the tenant resolution failures; defaults to slog.Default()
+	Logger *slog.Logger
 }
 
 // DefaultFiberErrorHandler provides default error handling for Fiber middleware
 func DefaultFiberErrorHandler(c *fiber.Ctx, err error) error {
 	statusCode := fiber.StatusInternalServerError
 
-	if _, ok := err.(core.TenantNotFoundError); ok {
+	switch core.GetErrorCode(err) {
+	case core.ErrCodeTenantNotFound:
 		statusCode = fiber.StatusNotFound
-	} else if _, ok := err.(core.TenantInactiveError); ok {
+	case core.ErrCodeTenantInactive:
 		statusCode = fiber.StatusForbidden
 	}
 
@@ -45,6 +49,8 @@ func FiberTenantMiddleware(config FiberMiddlewareConfig) fiber.Handler {
 		config.ErrorHandler = DefaultFiberErrorHandler
 	}
 
+	logger := core.ComponentLogger(config.Logger, "http.fiber")
+
 	return func(c *fiber.Ctx) error {
 		// Check if the current path should be ignored
 		path := c.Path()
@@ -56,20 +62,28 @@ func FiberTenantMiddleware(config FiberMiddlewareConfig) fiber.Handler {
 
 		tenantName := c.Get(config.HeaderName)
 		if tenantName == "" {
-			return config.ErrorHandler(c, fmt.Errorf("tenant header %s not provided", config.HeaderName))
+			err := fmt.Errorf("tenant header %s not provided", config.HeaderName)
+			logResolveFailure(c.UserContext(), logger, c.Method(), path, tenantName, err)
+			return config.ErrorHandler(c, err)
 		}
 
-		tenant, err := config.TenantService.GetTenant(c.UserContext(), tenantName)
+		// Let the service flag tenants served from a stale cache
+		ctx := tenantcontext.WithStaleTracking(c.UserContext())
+		tenant, err := config.TenantService.GetTenant(ctx, tenantName)
 		if err != nil {
+			logResolveFailure(ctx, logger, c.Method(), path, tenantName, err)
 			return config.ErrorHandler(c, err)
 		}
 
 		// Store tenant in context
-		ctx := tenantcontext.WithTenant(c.UserContext(), tenant)
+		ctx = tenantcontext.WithTenant(ctx, tenant)
 		c.SetUserContext(ctx)
 
 		// Add tenant to response headers for debugging
 		c.Set("X-Tenant-Name", tenant.Name)
+		if tenantcontext.IsStale(ctx) {
+			c.Set("X-Tenant-Stale", "true")
+		}
 
 		// Propagate tenant to tracing span if available
 		tenantcontext.PropagateToSpan(ctx)
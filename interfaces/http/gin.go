@@ -2,6 +2,7 @@ package http
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -17,15 +18,18 @@ type GinMiddlewareConfig struct {
 	HeaderName       string
 	ErrorHandler     func(*gin.Context, error)
 	IgnoredEndpoints []string
+	// Logger receives the tenant resolution failures; defaults to slog.Default()
+	Logger *slog.Logger
 }
 
 // DefaultGinErrorHandler provides default error handling for Gin middleware
 func DefaultGinErrorHandler(c *gin.Context, err error) {
 	statusCode := http.StatusInternalServerError
 
-	if _, ok := err.(core.TenantNotFoundError); ok {
+	switch core.GetErrorCode(err) {
+	case core.ErrCodeTenantNotFound:
 		statusCode = http.StatusNotFound
-	} else if _, ok := err.(core.TenantInactiveError); ok {
+	case core.ErrCodeTenantInactive:
 		statusCode = http.StatusForbidden
 	}
 
@@ -47,6 +51,8 @@ func TenantMiddleware(config GinMiddlewareConfig) gin.HandlerFunc {
 		config.ErrorHandler = DefaultGinErrorHandler
 	}
 
+	logger := core.ComponentLogger(config.Logger, "http.gin")
+
 	return func(c *gin.Context) {
 		// Check if the current path should be ignored
 		path := c.Request.URL.Path
@@ -59,22 +65,30 @@ func TenantMiddleware(config GinMiddlewareConfig) gin.HandlerFunc {
 
 		tenantName := c.GetHeader(config.HeaderName)
 		if tenantName == "" {
-			config.ErrorHandler(c, fmt.Errorf("tenant header %s not provided", config.HeaderName))
+			err := fmt.Errorf("tenant header %s not provided", config.HeaderName)
+			logResolveFailure(c.Request.Context(), logger, c.Request.Method, path, tenantName, err)
+			config.ErrorHandler(c, err)
 			return
 		}
 
-		tenant, err := config.TenantService.GetTenant(c.Request.Context(), tenantName)
+		// Let the service flag tenants served from a stale cache
+		ctx := tenantcontext.WithStaleTracking(c.Request.Context())
+		tenant, err := config.TenantService.GetTenant(ctx, tenantName)
 		if err != nil {
+			logResolveFailure(ctx, logger, c.Request.Method, path, tenantName, err)
 			config.ErrorHandler(c, err)
 			return
 		}
 
 		// Store tenant in context
-		ctx := tenantcontext.WithTenant(c.Request.Context(), tenant)
+		ctx = tenantcontext.WithTenant(ctx, tenant)
 		c.Request = c.Request.WithContext(ctx)
 
 		// Add tenant to response headers for debugging
 		c.Header("X-Tenant-Name", tenant.Name)
+		if tenantcontext.IsStale(ctx) {
+			c.Header("X-Tenant-Stale", "true")
+		}
 
 		// Propagate tenant to tracing span if available
 		tenantcontext.PropagateToSpan(ctx)
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// logResolveFailure logs why a request got no tenant. Missing headers and unknown or
+// inactive tenants are caused by clients and logged at debug level; anything else means
+// the registry could not be reached and is logged as an error.
+func logResolveFailure(ctx context.Context, logger *slog.Logger, method, path, tenantName string, err error) {
+	level := slog.LevelError
+	switch core.GetErrorCode(err) {
+	case core.ErrCodeTenantNotFound, core.ErrCodeTenantInactive:
+		level = slog.LevelDebug
+	}
+	if tenantName == "" {
+		level = slog.LevelDebug
+	}
+
+	logger.Log(ctx, level, "failed to resolve tenant",
+		core.LogKeyTenantName, tenantName, "method", method, "path", path, core.LogKeyError, err)
+}
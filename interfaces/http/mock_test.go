@@ -4,11 +4,14 @@ import (
 	"context"
 
 	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/tenantcontext"
 )
 
 // MockTenantService is a mock implementation of core.TenantService for testing
 type MockTenantService struct {
 	tenants map[string]*core.Tenant
+	// stale lists the tenants served as if from a stale cache entry
+	stale map[string]bool
 }
 
 // NewMockTenantService creates a new mock tenant service with predefined tenants
@@ -25,7 +28,13 @@ func NewMockTenantService() *MockTenantService {
 				Name:     "inactive-tenant",
 				IsActive: false,
 			},
+			"stale-tenant": {
+				ID:       "stale-id",
+				Name:     "stale-tenant",
+				IsActive: true,
+			},
 		},
+		stale: map[string]bool{"stale-tenant": true},
 	}
 }
 
@@ -40,9 +49,22 @@ func (m *MockTenantService) GetTenant(ctx context.Context, name string) (*core.T
 		return nil, core.TenantInactiveError{Name: name}
 	}
 
+	if m.stale[name] {
+		tenantcontext.MarkStale(ctx)
+	}
 	return tenant, nil
 }
 
+// GetTenantByID implements core.TenantService
+func (m *MockTenantService) GetTenantByID(ctx context.Context, id string) (*core.Tenant, error) {
+	for _, tenant := range m.tenants {
+		if tenant.ID == id {
+			return tenant, nil
+		}
+	}
+	return nil, core.TenantNotFoundError{Name: id}
+}
+
 // ListTenants implements core.TenantService
 func (m *MockTenantService) ListTenants(ctx context.Context) ([]core.Tenant, error) {
 	var tenants []core.Tenant
@@ -0,0 +1,68 @@
+package multitenant
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/victorximenis/multitenant/infra/mongodb"
+	"github.com/victorximenis/multitenant/infra/postgres"
+	"github.com/victorximenis/multitenant/tenantcontext"
+)
+
+// logger returns the logger shared by the components of the client: the configured
+// Logger, or slog.Default() limited to LogLevel, adding the tenant of the context to
+// every record
+func (c *Config) logger() *slog.Logger {
+	if c.Logger != nil {
+		if _, ok := c.Logger.Handler().(*tenantcontext.LogHandler); ok {
+			return c.Logger
+		}
+		return slog.New(tenantcontext.NewLogHandler(c.Logger.Handler()))
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToLower(c.LogLevel))); err != nil {
+		level = slog.LevelInfo
+	}
+	return slog.New(tenantcontext.NewLogHandler(&levelHandler{
+		Handler: slog.Default().Handler(),
+		level:   level,
+	}))
+}
+
+// levelHandler drops the records below level before they reach the wrapped handler
+type levelHandler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// postgresOptions returns the PostgreSQL options logging through the client logger
+func (c *Config) postgresOptions() postgres.Options {
+	opts := c.Postgres
+	if opts.Logger == nil {
+		opts.Logger = c.logger()
+	}
+	return opts
+}
+
+// mongoOptions returns the MongoDB options logging through the client logger
+func (c *Config) mongoOptions() mongodb.Options {
+	opts := c.Mongo
+	if opts.Logger == nil {
+		opts.Logger = c.logger()
+	}
+	return opts
+}
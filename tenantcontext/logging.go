@@ -0,0 +1,71 @@
+package tenantcontext
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/victorximenis/multitenant/core"
+)
+
+// LogHandler is a slog.Handler that adds the tenant.id and tenant.name attributes of the
+// tenant in the context to every record, unless the record or logger already sets them.
+// The attributes are added to the record, so they are nested under the groups opened
+// with WithGroup.
+type LogHandler struct {
+	next slog.Handler
+	// hasTenant is set when WithAttrs already added a tenant attribute
+	hasTenant bool
+}
+
+// NewLogHandler wraps next so records logged with a tenant context carry the tenant
+func NewLogHandler(next slog.Handler) *LogHandler {
+	return &LogHandler{next: next}
+}
+
+// Enabled reports whether the wrapped handler handles records at level
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle adds the tenant attributes to r and passes it to the wrapped handler
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	tenant, ok := GetTenant(ctx)
+	if !ok || tenant == nil || h.hasTenant || hasTenantAttr(r) {
+		return h.next.Handle(ctx, r)
+	}
+
+	r = r.Clone()
+	r.AddAttrs(
+		slog.String(core.LogKeyTenantID, tenant.ID),
+		slog.String(core.LogKeyTenantName, tenant.Name),
+	)
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler whose records carry attrs
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hasTenant := h.hasTenant
+	for _, attr := range attrs {
+		hasTenant = hasTenant || isTenantKey(attr.Key)
+	}
+	return &LogHandler{next: h.next.WithAttrs(attrs), hasTenant: hasTenant}
+}
+
+// WithGroup returns a handler that nests the following attributes under name
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{next: h.next.WithGroup(name), hasTenant: h.hasTenant}
+}
+
+// hasTenantAttr reports whether r already sets a tenant attribute
+func hasTenantAttr(r slog.Record) bool {
+	found := false
+	r.Attrs(func(attr slog.Attr) bool {
+		found = isTenantKey(attr.Key)
+		return !found
+	})
+	return found
+}
+
+func isTenantKey(key string) bool {
+	return key == core.LogKeyTenantID || key == core.LogKeyTenantName
+}
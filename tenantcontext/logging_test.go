@@ -0,0 +1,63 @@
+package tenantcontext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var record map[string]interface{}
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil)))
+
+	tenant := NewTestTenant("acme")
+	ctx := WithTenant(context.Background(), tenant)
+
+	logger.InfoContext(ctx, "with tenant")
+	logger.InfoContext(context.Background(), "without tenant")
+	logger.InfoContext(ctx, "explicit tenant", "tenant.name", "globex")
+	logger.With("tenant.id", "42").InfoContext(ctx, "tenant set by logger")
+	logger.WithGroup("request").InfoContext(ctx, "grouped", "path", "/orders")
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 5)
+
+	assert.Equal(t, tenant.ID, records[0]["tenant.id"])
+	assert.Equal(t, "acme", records[0]["tenant.name"])
+
+	assert.NotContains(t, records[1], "tenant.id")
+	assert.NotContains(t, records[1], "tenant.name")
+
+	assert.Equal(t, "globex", records[2]["tenant.name"])
+	assert.NotContains(t, records[2], "tenant.id")
+
+	assert.Equal(t, "42", records[3]["tenant.id"])
+	assert.NotContains(t, records[3], "tenant.name")
+
+	group := records[4]["request"].(map[string]interface{})
+	assert.Equal(t, "/orders", group["path"])
+	assert.Equal(t, "acme", group["tenant.name"])
+}
+
+func TestLogHandler_Enabled(t *testing.T) {
+	handler := NewLogHandler(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	assert.False(t, handler.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelError))
+}
@@ -0,0 +1,42 @@
+package tenantcontext
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// staleContextKey is the key of the stale flag set by WithStaleTracking
+const staleContextKey contextKey = "tenant-stale"
+
+// WithStaleTracking returns a context in which the tenant service can report that it
+// served a tenant from a stale cache entry; check it with IsStale after resolving
+func WithStaleTracking(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Value(staleContextKey).(*atomic.Bool); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, staleContextKey, new(atomic.Bool))
+}
+
+// MarkStale flags the tenant resolved with ctx as stale; it is a no-op unless ctx
+// comes from WithStaleTracking
+func MarkStale(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if stale, ok := ctx.Value(staleContextKey).(*atomic.Bool); ok {
+		stale.Store(true)
+	}
+}
+
+// IsStale reports whether the tenant in ctx was served from a stale cache entry because
+// the registry could not be reached
+func IsStale(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	stale, ok := ctx.Value(staleContextKey).(*atomic.Bool)
+	return ok && stale.Load()
+}
@@ -0,0 +1,28 @@
+package tenantcontext
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleTracking(t *testing.T) {
+	ctx := context.Background()
+
+	// Without tracking, marking is a no-op
+	MarkStale(ctx)
+	assert.False(t, IsStale(ctx))
+
+	tracked := WithStaleTracking(ctx)
+	assert.False(t, IsStale(tracked))
+
+	// The flag is shared with contexts derived after tracking started
+	derived := WithTenant(tracked, NewTestTenant("acme"))
+	MarkStale(derived)
+	assert.True(t, IsStale(tracked))
+	assert.True(t, IsStale(derived))
+
+	// Tracking an already tracked context keeps the flag
+	assert.True(t, IsStale(WithStaleTracking(derived)))
+}
@@ -0,0 +1,118 @@
+package multitenant
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/victorximenis/multitenant/core"
+	"github.com/victorximenis/multitenant/core/service"
+)
+
+// defaultWarmUpTimeout bounds how long NewMultitenantClient waits for the warm-up
+const defaultWarmUpTimeout = 10 * time.Second
+
+// WarmUpProgress reports how far the startup warm-up got
+type WarmUpProgress struct {
+	// Total is the number of active tenants to cache, known once they were listed
+	Total  int `json:"total"`
+	Cached int `json:"cached"`
+	// Pools counts the pools and clients ready for the hot tenants
+	Pools int  `json:"pools"`
+	Done  bool `json:"done"`
+	// Err holds what failed during the warm-up, which never fails the client
+	Err error `json:"-"`
+}
+
+// warmUp loads the active tenants into the cache and creates the pools of the hot
+// tenants. It waits at most WarmUpTimeout; the rest continues in the background until
+// it finishes or the client is closed.
+func (c *MultitenantClient) warmUp(ctx context.Context, tenantService *service.TenantService) {
+	timeout := c.config.WarmUpTimeout
+	if timeout <= 0 {
+		timeout = defaultWarmUpTimeout
+	}
+
+	warmCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c.stopWarmUp = cancel
+	c.warmUpDone = make(chan struct{})
+
+	go func() {
+		defer close(c.warmUpDone)
+		c.runWarmUp(warmCtx, tenantService)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.warmUpDone:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// runWarmUp performs the warm-up steps, reporting progress after each of them
+func (c *MultitenantClient) runWarmUp(ctx context.Context, tenantService *service.TenantService) {
+	var errs []error
+
+	_, err := tenantService.WarmCache(ctx, service.DefaultWarmUpBatchSize, func(cached, total int) {
+		c.updateWarmUp(func(progress *WarmUpProgress) {
+			progress.Cached = cached
+			progress.Total = total
+		})
+	})
+	errs = append(errs, err)
+
+	for _, name := range c.config.WarmUpTenants {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+
+		ready, err := c.connectionManager.WarmTenant(ctx, name)
+		errs = append(errs, err)
+		c.updateWarmUp(func(progress *WarmUpProgress) {
+			progress.Pools += ready
+		})
+	}
+
+	err = errors.Join(errs...)
+	c.updateWarmUp(func(progress *WarmUpProgress) {
+		progress.Done = true
+		progress.Err = err
+	})
+
+	progress, _ := c.GetWarmUpProgress()
+	if err != nil {
+		c.logger.WarnContext(ctx, "tenant warm-up finished with errors",
+			"cached", progress.Cached, "total", progress.Total, "pools", progress.Pools, core.LogKeyError, err)
+		return
+	}
+	c.logger.InfoContext(ctx, "tenant warm-up finished",
+		"cached", progress.Cached, "total", progress.Total, "pools", progress.Pools)
+}
+
+// updateWarmUp applies update to the warm-up progress and reports the result
+func (c *MultitenantClient) updateWarmUp(update func(*WarmUpProgress)) {
+	c.warmUpMu.Lock()
+	update(&c.warmUpProgress)
+	progress := c.warmUpProgress
+	c.warmUpMu.Unlock()
+
+	if c.config.OnWarmUpProgress != nil {
+		c.config.OnWarmUpProgress(progress)
+	}
+}
+
+// GetWarmUpProgress returns the progress of the startup warm-up; ok is false when the
+// warm-up is disabled
+func (c *MultitenantClient) GetWarmUpProgress() (progress WarmUpProgress, ok bool) {
+	if c.warmUpDone == nil {
+		return WarmUpProgress{}, false
+	}
+
+	c.warmUpMu.Lock()
+	defer c.warmUpMu.Unlock()
+	return c.warmUpProgress, true
+}